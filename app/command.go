@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	bookModel "github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	loanBookModel "github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	mysqlgorm "github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/migration"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
+)
+
+// runCommand runs a maintenance subcommand instead of the HTTP server.
+func runCommand(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "migrate":
+		return runMigrate(ctx, args)
+	case "sequence":
+		return runSequence(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runMigrate applies or reverts schema migrations:
+//
+//	migrate up | down [-steps n] | redo | status
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [-steps n] | redo | status")
+	}
+
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	driver, _ := config.Config().Database()
+
+	files, err := migration.Files(driver)
+
+	if err != nil {
+		return err
+	}
+
+	migrator, err := migration.New(mysqlgorm.DBConnect.Connection, files)
+
+	if err != nil {
+		return err
+	}
+
+	var migrations []migration.Migration
+
+	switch args[0] {
+	case "up":
+		migrations, err = migrator.Up(ctx)
+	case "down":
+		migrations, err = migrator.Down(ctx, *steps)
+	case "redo":
+		migrations, err = migrator.Redo(ctx)
+	case "status":
+		return printStatus(ctx, migrator)
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	for _, m := range migrations {
+		fmt.Printf("%s %d_%s\n", args[0], m.Version, m.Name)
+	}
+
+	return err
+}
+
+func printStatus(ctx context.Context, migrator *migration.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Printf("%d_%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return nil
+}
+
+// runSequence checks existing book and loan IDs against the configured
+// formats and moves the ID counters past the highest IDs in use.
+func runSequence(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("sequence", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report, do not seed the counters")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := sequence.Migrate(ctx, mysqlgorm.DBConnect.Connection, pkg.sequence, *dryRun,
+		sequence.Source{Table: bookModel.Book{}.TableName(), Column: "book_id", Template: config.Config().BookIDTemplate()},
+		sequence.Source{Table: loanBookModel.LoanBook{}.TableName(), Column: "loan_id", Template: config.Config().LoanIDTemplate()},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if len(report.Invalid) > 0 {
+		return fmt.Errorf("found IDs that do not match their format")
+	}
+
+	return nil
+}
@@ -1,80 +1,208 @@
 package main
 
 import (
-	"fmt"
-	"log"
-	"net/http"
+	"context"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	apiKeyDomain "github.com/Zeroaril7/perpustakaan-go/modules/apikey/domain"
+	apiKeyHandler "github.com/Zeroaril7/perpustakaan-go/modules/apikey/handlers"
+	apiKeyRepository "github.com/Zeroaril7/perpustakaan-go/modules/apikey/repositories"
+	apiKeyUsecase "github.com/Zeroaril7/perpustakaan-go/modules/apikey/usecases"
 	authDomain "github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
 	authHandler "github.com/Zeroaril7/perpustakaan-go/modules/auth/handlers"
+	authRepository "github.com/Zeroaril7/perpustakaan-go/modules/auth/repositories"
 	authUsecase "github.com/Zeroaril7/perpustakaan-go/modules/auth/usecases"
 	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
 	bookHandler "github.com/Zeroaril7/perpustakaan-go/modules/book/handlers"
+	bookModel "github.com/Zeroaril7/perpustakaan-go/modules/book/models"
 	bookRepository "github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
 	bookUsecase "github.com/Zeroaril7/perpustakaan-go/modules/book/usecases"
+	fineDomain "github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	fineHandler "github.com/Zeroaril7/perpustakaan-go/modules/fine/handlers"
+	fineRepository "github.com/Zeroaril7/perpustakaan-go/modules/fine/repositories"
+	fineUsecase "github.com/Zeroaril7/perpustakaan-go/modules/fine/usecases"
+	healthHandler "github.com/Zeroaril7/perpustakaan-go/modules/health/handlers"
+	itemDomain "github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	itemHandler "github.com/Zeroaril7/perpustakaan-go/modules/item/handlers"
+	itemRepository "github.com/Zeroaril7/perpustakaan-go/modules/item/repositories"
+	itemUsecase "github.com/Zeroaril7/perpustakaan-go/modules/item/usecases"
 	loanBookDomain "github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
 	loanBookHandler "github.com/Zeroaril7/perpustakaan-go/modules/loan/handlers"
 	loanBookRepository "github.com/Zeroaril7/perpustakaan-go/modules/loan/repositories"
 	loanBookUsecase "github.com/Zeroaril7/perpustakaan-go/modules/loan/usecases"
+	reservationDomain "github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	reservationHandler "github.com/Zeroaril7/perpustakaan-go/modules/reservation/handlers"
+	reservationRepository "github.com/Zeroaril7/perpustakaan-go/modules/reservation/repositories"
+	reservationUsecase "github.com/Zeroaril7/perpustakaan-go/modules/reservation/usecases"
 	userDomain "github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	userHandler "github.com/Zeroaril7/perpustakaan-go/modules/user/handlers"
 	userRepository "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
 	userUsecase "github.com/Zeroaril7/perpustakaan-go/modules/user/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/apikey"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
 	mysqlgorm "github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/health"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/migration"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/password"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/rbac"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/oidc"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/search"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/server"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
 )
 
 type repositories struct {
-	bookRepository    bookDomain.BookRepository
-	userRepository    userDomain.UserRepository
-	loanBokRepository loanBookDomain.LoanBookRepository
+	bookRepository         bookDomain.BookRepository
+	itemRepository         itemDomain.ItemRepository
+	userRepository         userDomain.UserRepository
+	loanBokRepository      loanBookDomain.LoanBookRepository
+	reservationRepository  reservationDomain.ReservationRepository
+	fineRepository         fineDomain.FineRepository
+	refreshTokenRepository authDomain.RefreshTokenRepository
+	twoFactorRepository    authDomain.TwoFactorRepository
+	identityRepository     authDomain.IdentityRepository
+	apiKeyRepository       apiKeyDomain.APIKeyRepository
 }
 
 type usecase struct {
-	bookUsecase     bookDomain.BookUsecase
-	userUsecase     userDomain.UserUsecase
-	authUsecase     authDomain.AuthUsecase
-	loanBookUsecase loanBookDomain.LoanBookUsecase
+	bookUsecase        bookDomain.BookUsecase
+	itemUsecase        itemDomain.ItemUsecase
+	userUsecase        userDomain.UserUsecase
+	authUsecase        authDomain.AuthUsecase
+	loanBookUsecase    loanBookDomain.LoanBookUsecase
+	reservationUsecase reservationDomain.ReservationUsecase
+	fineUsecase        fineDomain.FineUsecase
+	apiKeyUsecase      apiKeyDomain.APIKeyUsecase
 }
 
 type packages struct {
 	repositories repositories
 	usecase      usecase
+	sequence     sequence.Sequence
+	bookIndex    search.Index
+	revocation   revocation.List
+	loginTracker lockout.Tracker
+	keyRing      *jwtrsa.Ring
+	health       *health.Health
 }
 
 var pkg packages
 
 func setPackages() {
+	pkg.sequence = sequence.New(mysqlgorm.DBConnect.Connection, config.Config().IDSequenceDigits())
+	pkg.bookIndex = search.NewMemoryIndex(bookModel.SearchWeights)
+	pkg.revocation = revocation.New(mysqlgorm.DBConnect.Connection)
+	revocation.SetDefault(pkg.revocation)
+	pkg.loginTracker = lockout.New(config.Config().LoginPolicy())
+	rbac.SetDefault(rbac.New(config.Config().RolePermissions()))
+	utils.SetPasswordCost(config.Config().PasswordCost())
+
+	passwordPolicy, err := config.Config().PasswordPolicy()
+
+	if err != nil {
+		mainLog().Fatal("Could not read the password policy", zap.Error(err))
+	}
+
+	password.SetDefault(passwordPolicy)
+
+	keyRing, err := jwtrsa.NewRing(config.Config().KeySource())
+
+	if err != nil {
+		mainLog().Fatal("Could not read the token signing keys", zap.Error(err))
+	}
+
+	pkg.keyRing = keyRing
+	jwtrsa.SetDefault(pkg.keyRing)
+
+	if oidcConfig, ok := config.Config().OIDC(); ok {
+		oidc.SetDefault(oidc.New(oidcConfig, nil))
+	}
+
 	// repository
 	pkg.repositories.bookRepository = bookRepository.NewBookRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.itemRepository = itemRepository.NewItemRepository(mysqlgorm.DBConnect.Connection)
 	pkg.repositories.userRepository = userRepository.NewUserRepository(mysqlgorm.DBConnect.Connection)
 	pkg.repositories.loanBokRepository = loanBookRepository.NewLoanBookRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.reservationRepository = reservationRepository.NewReservationRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.fineRepository = fineRepository.NewFineRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.refreshTokenRepository = authRepository.NewRefreshTokenRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.twoFactorRepository = authRepository.NewTwoFactorRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.identityRepository = authRepository.NewIdentityRepository(mysqlgorm.DBConnect.Connection)
+	pkg.repositories.apiKeyRepository = apiKeyRepository.NewAPIKeyRepository(mysqlgorm.DBConnect.Connection)
 
 	// usecase
-	pkg.usecase.bookUsecase = bookUsecase.NewBookUsecase(pkg.repositories.bookRepository)
-	pkg.usecase.userUsecase = userUsecase.NewUserUsecase(pkg.repositories.userRepository)
-	pkg.usecase.authUsecase = authUsecase.NewAuthUsecase(pkg.repositories.userRepository)
-	pkg.usecase.loanBookUsecase = loanBookUsecase.NewLoanBookUsecase(pkg.repositories.loanBokRepository, pkg.repositories.bookRepository)
+	pkg.usecase.bookUsecase = bookUsecase.NewTracedBookUsecase(bookUsecase.NewBookUsecase(pkg.repositories.bookRepository, pkg.sequence, pkg.bookIndex))
+	pkg.usecase.itemUsecase = itemUsecase.NewItemUsecase(pkg.repositories.itemRepository, pkg.repositories.bookRepository, mysqlgorm.NewUnitOfWork(mysqlgorm.DBConnect.Connection))
+	pkg.usecase.userUsecase = userUsecase.NewTracedUserUsecase(userUsecase.NewUserUsecase(pkg.repositories.userRepository))
+	pkg.usecase.authUsecase = authUsecase.NewTracedAuthUsecase(authUsecase.NewAuthUsecase(pkg.repositories.userRepository, pkg.repositories.refreshTokenRepository, pkg.repositories.twoFactorRepository, pkg.repositories.identityRepository, pkg.revocation, pkg.loginTracker, mysqlgorm.NewUnitOfWork(mysqlgorm.DBConnect.Connection)))
+	pkg.usecase.loanBookUsecase = loanBookUsecase.NewTracedLoanBookUsecase(loanBookUsecase.NewLoanBookUsecase(pkg.repositories.loanBokRepository, pkg.repositories.bookRepository, pkg.repositories.itemRepository, pkg.repositories.reservationRepository, pkg.repositories.fineRepository, pkg.repositories.userRepository, mysqlgorm.NewUnitOfWork(mysqlgorm.DBConnect.Connection), pkg.sequence))
+	pkg.usecase.reservationUsecase = reservationUsecase.NewReservationUsecase(pkg.repositories.reservationRepository, pkg.repositories.bookRepository, pkg.repositories.itemRepository, mysqlgorm.NewUnitOfWork(mysqlgorm.DBConnect.Connection))
+	pkg.usecase.fineUsecase = fineUsecase.NewFineUsecase(pkg.repositories.fineRepository, mysqlgorm.NewUnitOfWork(mysqlgorm.DBConnect.Connection))
+	pkg.usecase.apiKeyUsecase = apiKeyUsecase.NewAPIKeyUsecase(pkg.repositories.apiKeyRepository)
+
+	apikey.SetDefault(pkg.usecase.apiKeyUsecase)
 
+	setHealth()
+}
+
+// setHealth registers what /readyz checks before the service gets traffic.
+func setHealth() {
+	policy := config.Config().HealthPolicy()
+	pkg.health = health.New(policy.Timeout)
+
+	pkg.health.Register("database", health.Database(mysqlgorm.DBConnect.Connection))
+	pkg.health.Register("signing_key", health.SigningKey(pkg.keyRing))
+	pkg.health.Register("disk", health.DiskSpace(policy.LogDir, policy.MinFree))
+
+	driver, _ := config.Config().Database()
+	files, err := migration.Files(driver)
+
+	if err == nil {
+		var migrator *migration.Migrator
+
+		if migrator, err = migration.New(mysqlgorm.DBConnect.Connection, files); err == nil {
+			pkg.health.Register("migrations", health.Migrations(migrator))
+		}
+	}
+
+	if err != nil {
+		mainLog().Error("Could not read the migrations to check", zap.Error(err))
+	}
 }
 
 func setHttp(e *echo.Echo) {
-	e.GET("/v1/health-check", func(c echo.Context) error {
-		log.Default().Println("main", "This service is running properly")
-		return utils.Response(nil, "This service is running properly", 200, c)
-	})
+	// Health
+	healthHandler.NewHealthHandler(e, pkg.health)
+
+	// Metrics
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
 
 	// Book
 	bookHandler.NewBookHandler(e, pkg.usecase.bookUsecase)
 
+	// Item
+	itemHandler.NewItemHandler(e, pkg.usecase.itemUsecase)
+
 	// User
 	userHandler.NewUserHandler(e, pkg.usecase.userUsecase)
+	userHandler.NewMeHandler(e, pkg.usecase.userUsecase, pkg.usecase.loanBookUsecase, pkg.usecase.fineUsecase)
 
 	// Auth
 	authHandler.NewAuthHandler(e, pkg.usecase.authUsecase)
@@ -82,43 +210,192 @@ func setHttp(e *echo.Echo) {
 	// Loan
 	loanBookHandler.NewLoanBookHandler(e, pkg.usecase.loanBookUsecase)
 
+	// Reservation
+	reservationHandler.NewReservationHandler(e, pkg.usecase.reservationUsecase)
+
+	// Fine
+	fineHandler.NewFineHandler(e, pkg.usecase.fineUsecase)
+
+	// API key
+	apiKeyHandler.NewAPIKeyHandler(e, pkg.usecase.apiKeyUsecase)
+
+}
+
+// setSchedulers runs the periodic jobs until ctx is done. The returned
+// group is released once no job is running anymore.
+func setSchedulers(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+
+	// Reservation holds that were not picked up in time go to the next in queue
+	every(ctx, &wg, time.Minute, func() {
+		result := <-pkg.usecase.reservationUsecase.ExpireHolds(context.Background())
+
+		if result.Error != nil {
+			mainLog().Error("Could not expire reservation holds", zap.Any("error", result.Error))
+		}
+	})
+
+	// Overdue loans keep accruing fines until they are returned
+	every(ctx, &wg, time.Hour, func() {
+		result := <-pkg.usecase.loanBookUsecase.AccrueFines(context.Background())
+
+		if result.Error != nil {
+			mainLog().Error("Could not accrue overdue fines", zap.Any("error", result.Error))
+		}
+	})
+
+	// Expired refresh tokens and revocations are no longer needed
+	every(ctx, &wg, time.Hour, func() {
+		result := <-pkg.usecase.authUsecase.PurgeExpired(context.Background())
+
+		if result.Error != nil {
+			mainLog().Error("Could not purge expired tokens", zap.Any("error", result.Error))
+		}
+	})
+
+	return &wg
+}
+
+// every runs job each interval until ctx is done. A job that already runs
+// is not cut off, it finishes before wg is released.
+func every(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, job func()) {
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				job()
+			}
+		}
+	}()
+}
+
+// reloadOnHangup reads the token signing keys again on SIGHUP, so keys can
+// be rotated without a restart.
+func reloadOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		for range hangup {
+			if err := pkg.keyRing.Reload(); err != nil {
+				mainLog().Error("Could not reload the token signing keys", zap.Error(err))
+				continue
+			}
+
+			mainLog().Info("Reloaded the token signing keys")
+		}
+	}()
+}
+
+// flushTraces sends the spans that were not sent yet.
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), constant.TraceFlushTimeout)
+	defer cancel()
+
+	if err := shutdown(ctx); err != nil {
+		mainLog().Error("Could not send the last spans", zap.Error(err))
+	}
+}
+
+// mainLog returns the logger of the lifecycle of this service.
+func mainLog() *zap.Logger {
+	return logger.Default().Named("main")
 }
 
 func main() {
+	log, err := logger.New(config.Config().Logging(), os.Stdout)
+
+	if err != nil {
+		mainLog().Fatal("Could not read the log settings", zap.Error(err))
+	}
+
+	logger.SetDefault(log)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), config.Config().Tracing(), os.Stdout)
+
+	if err != nil {
+		mainLog().Fatal("Could not set up tracing", zap.Error(err))
+	}
 
 	path, _ := os.Getwd()
-	utils.LogDefault(path)
+	mainLog().Info("Starting", zap.String("dir", path))
+
+	mysqlgorm.InitConnection(config.Config().Database())
+
+	if len(os.Args) > 1 {
+		setPackages()
+
+		err := runCommand(context.Background(), os.Args[1], os.Args[2:])
+		mysqlgorm.DBConnect.Close()
+		flushTraces(shutdownTracing)
 
-	mysqlgorm.InitConnection(config.Config().MySQLDSN())
+		if err != nil {
+			mainLog().Fatal("Could not run the command", zap.String("command", os.Args[1]), zap.Error(err))
+		}
+
+		return
+	}
+
+	serverConfig, err := config.Config().Server()
+
+	if err != nil {
+		mainLog().Fatal("Could not read the server settings", zap.Error(err))
+	}
 
 	e := echo.New()
 
 	e.Validator = validator.NewCustomValidator()
+	e.HTTPErrorHandler = utils.HTTPErrorHandler
+	e.IPExtractor = serverConfig.IPExtractor()
 
-	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Skipper:          middleware.DefaultSkipper,
-		Format:           `[ROUTE] ${time_rfc3339} | ${status} | ${latency_human} ${latency} | ${method} | ${uri}` + "\n",
-		CustomTimeFormat: "2000-01-01 10:10:01.00000",
-	}))
-
+	e.Use(middlewares.RequestID())
+	e.Use(middlewares.Tracing())
+	e.Use(middlewares.AuditLog())
+	e.Use(middlewares.Metrics())
 	e.Use(middleware.Recover())
 	setPackages()
+
+	if result := <-pkg.usecase.bookUsecase.RebuildIndex(context.Background()); result.Error != nil {
+		mainLog().Error("Could not build the book search index", zap.Any("error", result.Error))
+	}
+
 	setHttp(e)
 
+	// SIGINT and SIGTERM start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	schedulers := setSchedulers(ctx)
+	reloadOnHangup()
+
 	e.Use(middleware.CORSWithConfig(middleware.DefaultCORSConfig))
 
-	listenerPort := fmt.Sprintf(":%s", config.Config().AppPort)
-	e.Logger.Fatal(e.Start(listenerPort))
+	mainLog().Info("Listening", zap.String("addr", serverConfig.Addr), zap.Bool("tls", serverConfig.TLS()))
+
+	serveErr := server.Run(ctx, serverConfig, e)
 
-	server := &http.Server{
-		Addr:         listenerPort,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  10 * time.Second,
+	// The server may also stop because it could not listen
+	stop()
+	schedulers.Wait()
+
+	if err := mysqlgorm.DBConnect.Close(); err != nil {
+		mainLog().Error("Could not close the database connections", zap.Error(err))
 	}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Default().Println("main", fmt.Sprintf("Could not listen on %s: %v\n", config.Config().AppPort, err))
+	flushTraces(shutdownTracing)
+
+	if serveErr != nil {
+		mainLog().Fatal("Could not serve", zap.String("addr", serverConfig.Addr), zap.Error(serveErr))
 	}
 
+	mainLog().Info("This service stopped")
 }
@@ -1,10 +1,24 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/password"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/oidc"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/server"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type envConfig struct {
@@ -12,12 +26,98 @@ type envConfig struct {
 	AppPort           string
 	BasicAuthUsername string
 	BasicAuthPassword string
+	BasicAuthRole     string
+	RBACPolicy        string
+	DBDriver          string
+	DBDSN             string
 	MySQLHost         string
 	MySQLUsername     string
 	MySQLPassword     string
 	MySQLDBName       string
 	PrivateKey        string
 	PublicKey         string
+	PrivateKeyFile    string
+	PublicKeyFile     string
+	JWTKeysDir        string
+	HoldPickupDays    string
+	FinePerDay        string
+	FineCap           string
+	FineGenreRates    string
+	FineRoleRates     string
+	RenewalDays       string
+	RenewalMaxCount   string
+	RenewalOverdueMax string
+	LoanLimits        string
+	BookIDFormat      string
+	LoanIDFormat      string
+	IDSequenceWidth   string
+	RefreshTokenHours string
+	LoginMaxAttempts  string
+	LoginIPAttempts   string
+	LoginLockoutMins  string
+	LoginBackoffMax   string
+	TwoFactorRoles    string
+	PasswordMinLength string
+	PasswordClasses   string
+	PasswordBreached  string
+	BcryptCost        string
+	OIDCIssuer        string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        string
+	OIDCUsernameClaim string
+	OIDCGroupsClaim   string
+	OIDCRoleMap       string
+	OIDCDefaultRole   string
+	HTTPReadTimeout   string
+	HTTPWriteTimeout  string
+	HTTPIdleTimeout   string
+	ShutdownTimeout   string
+	ShutdownDrain     string
+	TLSCertFile       string
+	TLSKeyFile        string
+	TrustedProxies    string
+	HealthTimeout     string
+	HealthMinFreeMB   string
+	LogDir            string
+	LogLevel          string
+	LogFormat         string
+	TracingExporter   string
+	TracingSample     string
+}
+
+// RenewalPolicy limits how loans are renewed. Loans more than OverdueMax
+// days late cannot be renewed.
+type RenewalPolicy struct {
+	Period     time.Duration
+	MaxCount   int
+	OverdueMax int64
+}
+
+// FinePolicy is the per-day fine charged for overdue loans. Rates are keyed
+// by upper-cased book genre and user role; a zero Cap means no cap.
+type FinePolicy struct {
+	PerDay     int64
+	Cap        int64
+	GenreRates map[string]int64
+	RoleRates  map[string]int64
+}
+
+// HealthPolicy bounds the health checks. LogDir needs at least MinFree
+// bytes available.
+type HealthPolicy struct {
+	Timeout time.Duration
+	LogDir  string
+	MinFree uint64
+}
+
+// BasicAccount is the shared basic auth account and the role requests
+// signed in with it act as.
+type BasicAccount struct {
+	Username string
+	Password string
+	Role     string
 }
 
 var envCfg envConfig
@@ -38,17 +138,565 @@ func LoadConfig() {
 		AppPort:           os.Getenv("APP_PORT"),
 		BasicAuthUsername: os.Getenv("BASIC_AUTH_USERNAME"),
 		BasicAuthPassword: os.Getenv("BASIC_AUTH_PASSWORD"),
+		BasicAuthRole:     os.Getenv("BASIC_AUTH_ROLE"),
+		RBACPolicy:        os.Getenv("RBAC_POLICY"),
+		DBDriver:          os.Getenv("DB_DRIVER"),
+		DBDSN:             os.Getenv("DB_DSN"),
 		MySQLHost:         os.Getenv("MYSQL_HOST"),
 		MySQLUsername:     os.Getenv("MYSQL_USERNAME"),
 		MySQLPassword:     os.Getenv("MYSQL_PASSWORD"),
 		MySQLDBName:       os.Getenv("MYSQL_DB_NAME"),
 		PrivateKey:        os.Getenv("PRIVATE_KEY"),
 		PublicKey:         os.Getenv("PUBLIC_KEY"),
+		PrivateKeyFile:    os.Getenv("PRIVATE_KEY_FILE"),
+		PublicKeyFile:     os.Getenv("PUBLIC_KEY_FILE"),
+		JWTKeysDir:        os.Getenv("JWT_KEYS_DIR"),
+		HoldPickupDays:    os.Getenv("HOLD_PICKUP_DAYS"),
+		FinePerDay:        os.Getenv("FINE_PER_DAY"),
+		FineCap:           os.Getenv("FINE_CAP"),
+		FineGenreRates:    os.Getenv("FINE_GENRE_RATES"),
+		FineRoleRates:     os.Getenv("FINE_ROLE_RATES"),
+		RenewalDays:       os.Getenv("RENEWAL_DAYS"),
+		RenewalMaxCount:   os.Getenv("RENEWAL_MAX_COUNT"),
+		RenewalOverdueMax: os.Getenv("RENEWAL_OVERDUE_MAX_DAYS"),
+		LoanLimits:        os.Getenv("LOAN_LIMITS"),
+		BookIDFormat:      os.Getenv("BOOK_ID_FORMAT"),
+		LoanIDFormat:      os.Getenv("LOAN_ID_FORMAT"),
+		IDSequenceWidth:   os.Getenv("ID_SEQUENCE_WIDTH"),
+		RefreshTokenHours: os.Getenv("REFRESH_TOKEN_TTL_HOURS"),
+		LoginMaxAttempts:  os.Getenv("LOGIN_MAX_ATTEMPTS"),
+		LoginIPAttempts:   os.Getenv("LOGIN_IP_MAX_ATTEMPTS"),
+		LoginLockoutMins:  os.Getenv("LOGIN_LOCKOUT_MINUTES"),
+		LoginBackoffMax:   os.Getenv("LOGIN_BACKOFF_MAX_SECONDS"),
+		TwoFactorRoles:    os.Getenv("TWO_FACTOR_ROLES"),
+		PasswordMinLength: os.Getenv("PASSWORD_MIN_LENGTH"),
+		PasswordClasses:   os.Getenv("PASSWORD_MIN_CLASSES"),
+		PasswordBreached:  os.Getenv("PASSWORD_BREACHED_FILE"),
+		BcryptCost:        os.Getenv("BCRYPT_COST"),
+		OIDCIssuer:        os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:        os.Getenv("OIDC_SCOPES"),
+		OIDCUsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+		OIDCGroupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+		OIDCRoleMap:       os.Getenv("OIDC_ROLE_MAP"),
+		OIDCDefaultRole:   os.Getenv("OIDC_DEFAULT_ROLE"),
+		HTTPReadTimeout:   os.Getenv("HTTP_READ_TIMEOUT_SECONDS"),
+		HTTPWriteTimeout:  os.Getenv("HTTP_WRITE_TIMEOUT_SECONDS"),
+		HTTPIdleTimeout:   os.Getenv("HTTP_IDLE_TIMEOUT_SECONDS"),
+		ShutdownTimeout:   os.Getenv("SHUTDOWN_TIMEOUT_SECONDS"),
+		ShutdownDrain:     os.Getenv("SHUTDOWN_DRAIN_SECONDS"),
+		TLSCertFile:       os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:        os.Getenv("TLS_KEY_FILE"),
+		TrustedProxies:    os.Getenv("TRUSTED_PROXIES"),
+		HealthTimeout:     os.Getenv("HEALTH_CHECK_TIMEOUT_SECONDS"),
+		HealthMinFreeMB:   os.Getenv("HEALTH_MIN_FREE_MB"),
+		LogDir:            os.Getenv("LOG_DIR"),
+		LogLevel:          os.Getenv("LOG_LEVEL"),
+		LogFormat:         os.Getenv("LOG_FORMAT"),
+		TracingExporter:   os.Getenv("TRACING_EXPORTER"),
+		TracingSample:     os.Getenv("TRACING_SAMPLE_RATIO"),
 	}
 }
 
 func (e envConfig) MySQLDSN() (string, string) {
-	return fmt.Sprintf("%s:%s@tcp(%s)/%s", envCfg.MySQLUsername, envCfg.MySQLPassword, envCfg.MySQLHost, envCfg.MySQLDBName), envCfg.MySQLDBName
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", envCfg.MySQLUsername, envCfg.MySQLPassword, envCfg.MySQLHost, envCfg.MySQLDBName), envCfg.MySQLDBName
+}
+
+// Database returns the driver and DSN to connect with. DB_DRIVER is one of
+// mysql (the default), postgres or sqlite. DB_DSN overrides the DSN; MySQL
+// falls back to the MYSQL_* settings and SQLite to a local file.
+func (e envConfig) Database() (string, string) {
+	driver := strings.ToLower(envCfg.DBDriver)
+
+	if driver == "" {
+		driver = constant.MySQLDriver
+	}
+
+	if envCfg.DBDSN != "" {
+		return driver, envCfg.DBDSN
+	}
+
+	switch driver {
+	case constant.MySQLDriver:
+		dsn, _ := e.MySQLDSN()
+		return driver, dsn
+	case constant.SQLiteDriver:
+		return driver, constant.DefaultSQLiteDSN
+	}
+
+	return driver, ""
+}
+
+func (e envConfig) HoldPickupPeriod() time.Duration {
+	days, _ := strconv.Atoi(envCfg.HoldPickupDays)
+
+	if days <= 0 {
+		days = constant.DefaultHoldPickupDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (e envConfig) RenewalPolicy() RenewalPolicy {
+	days, _ := strconv.Atoi(envCfg.RenewalDays)
+
+	if days <= 0 {
+		days = constant.DefaultRenewalDays
+	}
+
+	maxCount, err := strconv.Atoi(envCfg.RenewalMaxCount)
+
+	if err != nil || maxCount < 0 {
+		maxCount = constant.DefaultMaxRenewals
+	}
+
+	overdueMax, _ := strconv.ParseInt(envCfg.RenewalOverdueMax, 10, 64)
+
+	return RenewalPolicy{
+		Period:     time.Duration(days) * 24 * time.Hour,
+		MaxCount:   maxCount,
+		OverdueMax: overdueMax,
+	}
+}
+
+// LoanLimit returns how many active loans a role may hold. LOAN_LIMITS
+// overrides the defaults from constant.LoanLimits, e.g. "KARYAWAN=2".
+func (e envConfig) LoanLimit(role string) int {
+	if limit, ok := parseRates(envCfg.LoanLimits)[strings.ToUpper(role)]; ok {
+		return int(limit)
+	}
+
+	if limit, ok := constant.LoanLimits[role]; ok {
+		return limit
+	}
+
+	return constant.DefaultLoanLimit
+}
+
+// BookIDTemplate is the format of new book IDs. It may use {INSTITUTE},
+// {GENRE} and must contain {SEQ}.
+func (e envConfig) BookIDTemplate() string {
+	if envCfg.BookIDFormat == "" {
+		return constant.DefaultBookIDFormat
+	}
+
+	return envCfg.BookIDFormat
+}
+
+// LoanIDTemplate is the format of new loan IDs. It may use {USERNAME} and
+// must contain {SEQ}.
+func (e envConfig) LoanIDTemplate() string {
+	if envCfg.LoanIDFormat == "" {
+		return constant.DefaultLoanIDFormat
+	}
+
+	return envCfg.LoanIDFormat
+}
+
+// IDSequenceDigits is the minimum number of digits of the {SEQ} part of IDs.
+func (e envConfig) IDSequenceDigits() int {
+	width, _ := strconv.Atoi(envCfg.IDSequenceWidth)
+
+	if width <= 0 {
+		width = constant.DefaultSequenceWidth
+	}
+
+	return width
+}
+
+// BasicAccount returns the basic auth account. It acts as ADMIN unless
+// BASIC_AUTH_ROLE names another role, so managing users, sessions and API
+// keys with it takes SUPER ADMIN by name.
+func (e envConfig) BasicAccount() BasicAccount {
+	role := strings.ToUpper(strings.TrimSpace(envCfg.BasicAuthRole))
+
+	if role == "" {
+		role = constant.Admin
+	}
+
+	return BasicAccount{Username: envCfg.BasicAuthUsername, Password: envCfg.BasicAuthPassword, Role: role}
+}
+
+// RolePermissions returns the permissions of each role. RBAC_POLICY
+// replaces the defaults from constant.RolePermissions of the roles it
+// names, e.g. "KARYAWAN=loan:read|loan:write,ADMIN=book:*|loan:*".
+func (e envConfig) RolePermissions() map[string][]string {
+	result := make(map[string][]string, len(constant.RolePermissions))
+
+	for role, permissions := range constant.RolePermissions {
+		result[role] = permissions
+	}
+
+	for _, pair := range strings.Split(envCfg.RBACPolicy, ",") {
+		role, permissions, ok := strings.Cut(pair, "=")
+
+		if !ok {
+			continue
+		}
+
+		result[strings.ToUpper(strings.TrimSpace(role))] = strings.Split(permissions, "|")
+	}
+
+	return result
+}
+
+// KeySource is where the keys access tokens are signed and verified with
+// are read from: PRIVATE_KEY and PUBLIC_KEY hold PEM, PRIVATE_KEY_FILE and
+// PUBLIC_KEY_FILE name PEM files and JWT_KEYS_DIR a directory of *.pem
+// files. The files are read again on SIGHUP.
+func (e envConfig) KeySource() jwtrsa.Source {
+	return jwtrsa.Source{
+		PrivateKey:     envCfg.PrivateKey,
+		PublicKey:      envCfg.PublicKey,
+		PrivateKeyFile: envCfg.PrivateKeyFile,
+		PublicKeyFile:  envCfg.PublicKeyFile,
+		Dir:            envCfg.JWTKeysDir,
+	}
+}
+
+// RefreshTokenTTL is how long a refresh token can be used, i.e. how long a
+// session lasts without signing in again.
+func (e envConfig) RefreshTokenTTL() time.Duration {
+	hours, _ := strconv.Atoi(envCfg.RefreshTokenHours)
+
+	if hours <= 0 {
+		return constant.DefaultRefreshTokenTTL
+	}
+
+	return time.Duration(hours) * time.Hour
+}
+
+// LoginPolicy limits failed sign-ins. LOGIN_MAX_ATTEMPTS failures lock a
+// username and LOGIN_IP_MAX_ATTEMPTS failures throttle a client IP for
+// LOGIN_LOCKOUT_MINUTES; the wait between failures doubles up to
+// LOGIN_BACKOFF_MAX_SECONDS.
+func (e envConfig) LoginPolicy() lockout.Policy {
+	maxAttempts, _ := strconv.Atoi(envCfg.LoginMaxAttempts)
+
+	if maxAttempts <= 0 {
+		maxAttempts = constant.DefaultLoginMaxAttempts
+	}
+
+	maxIPAttempts, _ := strconv.Atoi(envCfg.LoginIPAttempts)
+
+	if maxIPAttempts <= 0 {
+		maxIPAttempts = constant.DefaultLoginIPMaxAttempts
+	}
+
+	policy := lockout.Policy{
+		MaxAttempts:   maxAttempts,
+		MaxIPAttempts: maxIPAttempts,
+		Lockout:       constant.DefaultLoginLockout,
+		BaseDelay:     constant.LoginBackoffBase,
+		MaxDelay:      constant.DefaultLoginBackoffMax,
+	}
+
+	if minutes, _ := strconv.Atoi(envCfg.LoginLockoutMins); minutes > 0 {
+		policy.Lockout = time.Duration(minutes) * time.Minute
+	}
+
+	if seconds, _ := strconv.Atoi(envCfg.LoginBackoffMax); seconds > 0 {
+		policy.MaxDelay = time.Duration(seconds) * time.Second
+	}
+
+	return policy
+}
+
+// TwoFactorRequired reports whether users of role must sign in with a
+// second factor. TWO_FACTOR_ROLES is a comma separated list of roles that
+// replaces constant.DefaultTwoFactorRoles; set it to NONE to leave two-factor
+// optional for every role.
+func (e envConfig) TwoFactorRequired(role string) bool {
+	roles := constant.DefaultTwoFactorRoles
+
+	if envCfg.TwoFactorRoles != "" {
+		roles = strings.Split(envCfg.TwoFactorRoles, ",")
+	}
+
+	for _, required := range roles {
+		if strings.EqualFold(strings.TrimSpace(required), role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TOTPIssuer is the name authenticator apps show next to the account.
+func (e envConfig) TOTPIssuer() string {
+	if envCfg.AppName != "" {
+		return envCfg.AppName
+	}
+
+	return constant.DefaultTOTPIssuer
+}
+
+// PasswordPolicy reads the policy new passwords must satisfy.
+// PASSWORD_BREACHED_FILE names a file of breached passwords, one per line,
+// that are refused; it is an error if the file can not be read.
+func (e envConfig) PasswordPolicy() (password.Policy, error) {
+	policy := password.Policy{
+		MinLength:  constant.DefaultPasswordMinLength,
+		MinClasses: constant.DefaultPasswordMinClasses,
+	}
+
+	if length, _ := strconv.Atoi(envCfg.PasswordMinLength); length > 0 {
+		policy.MinLength = length
+	}
+
+	if classes, err := strconv.Atoi(envCfg.PasswordClasses); err == nil && classes >= 0 {
+		policy.MinClasses = classes
+	}
+
+	if envCfg.PasswordBreached == "" {
+		return policy, nil
+	}
+
+	breached, err := password.LoadBreached(envCfg.PasswordBreached)
+	policy.Breached = breached
+
+	return policy, err
+}
+
+// PasswordCost is the bcrypt cost of new password hashes. Hashes made with
+// another cost are redone when their user signs in.
+func (e envConfig) PasswordCost() int {
+	cost, _ := strconv.Atoi(envCfg.BcryptCost)
+
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return constant.DefaultBcryptCost
+	}
+
+	return cost
+}
+
+// OIDC returns the OpenID Connect client, and whether sign-in through
+// OIDC_ISSUER is enabled at all. OIDC_ROLE_MAP maps groups to roles, e.g.
+// "library-staff=KARYAWAN,library-admins=ADMIN"; users in none of them get
+// OIDC_DEFAULT_ROLE, KARYAWAN unless set, or are refused when it is NONE.
+func (e envConfig) OIDC() (oidc.Config, bool) {
+	result := oidc.Config{
+		Issuer:        envCfg.OIDCIssuer,
+		ClientID:      envCfg.OIDCClientID,
+		ClientSecret:  envCfg.OIDCClientSecret,
+		RedirectURL:   envCfg.OIDCRedirectURL,
+		Scopes:        constant.DefaultOIDCScopes,
+		UsernameClaim: constant.DefaultOIDCUsernameClaim,
+		GroupsClaim:   constant.DefaultOIDCGroupsClaim,
+		RoleMap:       map[string]string{},
+		DefaultRole:   constant.Karyawan,
+	}
+
+	if envCfg.OIDCScopes != "" {
+		result.Scopes = strings.Fields(strings.ReplaceAll(envCfg.OIDCScopes, ",", " "))
+	}
+
+	if envCfg.OIDCUsernameClaim != "" {
+		result.UsernameClaim = envCfg.OIDCUsernameClaim
+	}
+
+	if envCfg.OIDCGroupsClaim != "" {
+		result.GroupsClaim = envCfg.OIDCGroupsClaim
+	}
+
+	for _, pair := range strings.Split(envCfg.OIDCRoleMap, ",") {
+		group, role, ok := strings.Cut(pair, "=")
+
+		if !ok {
+			continue
+		}
+
+		result.RoleMap[strings.TrimSpace(group)] = strings.ToUpper(strings.TrimSpace(role))
+	}
+
+	if role := strings.ToUpper(strings.TrimSpace(envCfg.OIDCDefaultRole)); role == "NONE" {
+		result.DefaultRole = ""
+	} else if role != "" {
+		result.DefaultRole = role
+	}
+
+	return result, envCfg.OIDCIssuer != "" && envCfg.OIDCClientID != ""
+}
+
+// FinePolicy reads the fine policy. FINE_GENRE_RATES and FINE_ROLE_RATES
+// are comma separated NAME=AMOUNT pairs, e.g. "Drama=500,Horror=750".
+func (e envConfig) FinePolicy() FinePolicy {
+	perDay, err := strconv.ParseInt(envCfg.FinePerDay, 10, 64)
+
+	if err != nil || perDay < 0 {
+		perDay = constant.DefaultFinePerDay
+	}
+
+	limit, _ := strconv.ParseInt(envCfg.FineCap, 10, 64)
+
+	return FinePolicy{
+		PerDay:     perDay,
+		Cap:        limit,
+		GenreRates: parseRates(envCfg.FineGenreRates),
+		RoleRates:  parseRates(envCfg.FineRoleRates),
+	}
+}
+
+// Rate returns the per-day fine for a book genre and borrower role. A role
+// rate wins over a genre rate, which wins over the default rate.
+func (p FinePolicy) Rate(genre, role string) int64 {
+	if rate, ok := p.RoleRates[strings.ToUpper(role)]; ok {
+		return rate
+	}
+
+	if rate, ok := p.GenreRates[strings.ToUpper(genre)]; ok {
+		return rate
+	}
+
+	return p.PerDay
+}
+
+func parseRates(value string) map[string]int64 {
+	rates := make(map[string]int64)
+
+	for _, pair := range strings.Split(value, ",") {
+		name, amount, found := strings.Cut(pair, "=")
+
+		if !found {
+			continue
+		}
+
+		rate, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+
+		if err != nil {
+			continue
+		}
+
+		rates[strings.ToUpper(strings.TrimSpace(name))] = rate
+	}
+
+	return rates
+}
+
+// Server is how the HTTP server listens on APP_PORT and shuts down. The
+// HTTP_*_TIMEOUT_SECONDS settings bound reading a request, writing its
+// response and keeping an idle connection open. On shutdown the service
+// reports itself not ready for SHUTDOWN_DRAIN_SECONDS before requests in
+// flight get SHUTDOWN_TIMEOUT_SECONDS to finish. TLS_CERT_FILE and
+// TLS_KEY_FILE serve HTTPS; it is an error to set only one of them.
+// TRUSTED_PROXIES is a comma separated list of the CIDRs of reverse proxies
+// whose X-Forwarded-For header is believed.
+func (e envConfig) Server() (server.Config, error) {
+	config := server.Config{
+		Addr:              fmt.Sprintf(":%s", envCfg.AppPort),
+		ReadTimeout:       seconds(envCfg.HTTPReadTimeout, constant.DefaultHTTPReadTimeout),
+		ReadHeaderTimeout: constant.DefaultHTTPReadHeaderTimeout,
+		WriteTimeout:      seconds(envCfg.HTTPWriteTimeout, constant.DefaultHTTPWriteTimeout),
+		IdleTimeout:       seconds(envCfg.HTTPIdleTimeout, constant.DefaultHTTPIdleTimeout),
+		ShutdownTimeout:   seconds(envCfg.ShutdownTimeout, constant.DefaultShutdownTimeout),
+		Drain:             constant.DefaultShutdownDrain,
+		CertFile:          envCfg.TLSCertFile,
+		KeyFile:           envCfg.TLSKeyFile,
+	}
+
+	// A drain of zero shuts down right away
+	if drain, err := strconv.Atoi(envCfg.ShutdownDrain); err == nil && drain >= 0 {
+		config.Drain = time.Duration(drain) * time.Second
+	}
+
+	if config.ReadHeaderTimeout > config.ReadTimeout {
+		config.ReadHeaderTimeout = config.ReadTimeout
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return config, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	for _, cidr := range strings.Split(envCfg.TrustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+
+		if err != nil {
+			return config, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+
+		config.TrustedProxies = append(config.TrustedProxies, network)
+	}
+
+	return config, nil
+}
+
+// HealthPolicy reads how long a health check may take,
+// HEALTH_CHECK_TIMEOUT_SECONDS, and how many megabytes, HEALTH_MIN_FREE_MB,
+// must be free in LOG_DIR.
+func (e envConfig) HealthPolicy() HealthPolicy {
+	policy := HealthPolicy{
+		Timeout: seconds(envCfg.HealthTimeout, constant.DefaultHealthCheckTimeout),
+		LogDir:  envCfg.LogDir,
+		MinFree: constant.DefaultHealthMinFreeMB << 20,
+	}
+
+	if policy.LogDir == "" {
+		policy.LogDir = constant.DefaultLogDir
+	}
+
+	if megabytes, err := strconv.ParseUint(envCfg.HealthMinFreeMB, 10, 64); err == nil {
+		policy.MinFree = megabytes << 20
+	}
+
+	return policy
+}
+
+// Logging is how the service logs. LOG_LEVEL is one of debug, info (the
+// default), warn or error and LOG_FORMAT one of json (the default) or
+// logfmt. SQL statements are logged at debug level.
+func (e envConfig) Logging() logger.Config {
+	config := logger.Config{Level: strings.ToLower(envCfg.LogLevel), Format: strings.ToLower(envCfg.LogFormat)}
+
+	if config.Level == "" {
+		config.Level = constant.DefaultLogLevel
+	}
+
+	if config.Format == "" {
+		config.Format = constant.DefaultLogFormat
+	}
+
+	return config
+}
+
+// Tracing is where spans go. TRACING_EXPORTER is one of none (the
+// default), stdout or otlp, which is set up with the standard
+// OTEL_EXPORTER_OTLP_* variables. TRACING_SAMPLE_RATIO is the share of new
+// traces kept, from 0 to 1 (the default). Spans name the service APP_NAME.
+func (e envConfig) Tracing() tracing.Config {
+	config := tracing.Config{
+		Exporter:    strings.ToLower(envCfg.TracingExporter),
+		ServiceName: envCfg.AppName,
+		SampleRatio: constant.DefaultTraceSampleRatio,
+	}
+
+	if config.Exporter == "" {
+		config.Exporter = tracing.ExporterNone
+	}
+
+	if config.ServiceName == "" {
+		config.ServiceName = constant.DefaultServiceName
+	}
+
+	if ratio, err := strconv.ParseFloat(envCfg.TracingSample, 64); err == nil && ratio >= 0 && ratio <= 1 {
+		config.SampleRatio = ratio
+	}
+
+	return config
+}
+
+// seconds parses a positive number of seconds, or returns fallback.
+func seconds(value string, fallback time.Duration) time.Duration {
+	if n, _ := strconv.Atoi(value); n > 0 {
+		return time.Duration(n) * time.Second
+	}
+
+	return fallback
 }
 
 func Config() *envConfig {
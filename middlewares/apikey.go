@@ -1,15 +1,43 @@
 package middlewares
 
 import (
+	"errors"
+	"net/http"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/apikey"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 )
 
-func VerifyAPIKey(keyAuth string) echo.MiddlewareFunc {
-	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
-		KeyLookup: "header:X-API-KEY",
-		Validator: func(auth string, c echo.Context) (bool, error) {
-			return auth == keyAuth, nil
-		},
-	})
+// VerifyAPIKey accepts keys from the X-API-Key header that apikey.Default
+// resolves. The caller is the principal of the key, granted its scopes
+// instead of the permissions of a role.
+func VerifyAPIKey() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			resolver := apikey.Default()
+			key := c.Request().Header.Get(constant.APIKeyHeader)
+
+			if resolver == nil || key == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, httperror.InvalidAPIKeyMsg)
+			}
+
+			principal, err := resolver.Resolve(c.Request().Context(), key)
+
+			if errors.Is(err, apikey.ErrInvalidKey) {
+				return echo.NewHTTPError(http.StatusUnauthorized, httperror.InvalidAPIKeyMsg)
+			}
+
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+
+			c.Set("username", principal.Username())
+			c.Set("role", "")
+			c.Set("scopes", principal.Scopes)
+
+			return next(c)
+		}
+	}
 }
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AuditLog logs every request once it is answered, with the status it got
+// and how long it took. Errors are handled here, like Metrics does, so the
+// status logged is the one sent. Server errors are logged as errors and
+// client errors as warnings.
+func AuditLog() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			req, res := c.Request(), c.Response()
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("route", c.Path()),
+				zap.String("uri", req.RequestURI),
+				zap.Int("status", res.Status),
+				zap.Duration("latency_ms", time.Since(start)),
+				zap.Int64("content_length", req.ContentLength),
+				zap.Int64("response_size", res.Size),
+				zap.String("ip", c.RealIP()),
+			}
+
+			if username, ok := c.Get("username").(string); ok {
+				fields = append(fields, zap.String("username", username))
+			}
+
+			log := logger.FromContext(req.Context()).Named("audit")
+
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				log.Error("Request failed", fields...)
+			case res.Status >= http.StatusBadRequest:
+				log.Warn("Request refused", fields...)
+			default:
+				log.Info("Request served", fields...)
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/labstack/echo/v4"
+)
+
+// Authenticate accepts a Bearer access token, the shared basic auth account
+// or an API key, and sets the username and role or scopes of the caller for
+// RequirePermission.
+func Authenticate(publicKey string, basic config.BasicAccount) echo.MiddlewareFunc {
+	bearer := VerifyJWTRSA(publicKey)
+	setCredential := EchoSetCredential()
+	basicAuth := VerifyBasicAuth(basic.Username, basic.Password)
+	apiKey := VerifyAPIKey()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withBearer := bearer(setCredential(next))
+		withAPIKey := apiKey(next)
+		withBasic := basicAuth(func(c echo.Context) error {
+			c.Set("username", basic.Username)
+			c.Set("role", basic.Role)
+
+			return next(c)
+		})
+
+		return func(c echo.Context) error {
+			if c.Request().Header.Get(constant.APIKeyHeader) != "" {
+				return withAPIKey(c)
+			}
+
+			scheme, _, _ := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+
+			if !strings.EqualFold(scheme, "basic") {
+				return withBearer(c)
+			}
+
+			if basic.Username == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Basic auth is not enabled")
+			}
+
+			return withBasic(c)
+		}
+	}
+}
@@ -1,22 +1,80 @@
 package middlewares
 
 import (
-	"log"
+	"net/http"
 
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 )
 
+// VerifyJWTRSA accepts access tokens whose jti is not on the
+// revocation.Default list. Tokens are verified with the key of the
+// jwtrsa.Default ring their kid names, or with publicKey when no ring is
+// set. Tokens without a jti predate revocation and are accepted until they
+// expire.
 func VerifyJWTRSA(publicKey string) echo.MiddlewareFunc {
-	verifyPublicKey, err := jwtrsa.GetPublicKey(publicKey)
+	fallback, err := jwtrsa.NewRing(jwtrsa.Source{PublicKey: publicKey})
 
 	if err != nil {
-		log.Default().Printf("%s", err.Error())
+		logger.Default().Warn("Could not read the public key", zap.Error(err))
 	}
 
-	return echojwt.WithConfig(echojwt.Config{
-		SigningKey:    verifyPublicKey,
-		SigningMethod: "RS256",
+	verify := echojwt.WithConfig(echojwt.Config{
+		KeyFunc: func(token *jwt.Token) (interface{}, error) {
+			ring := jwtrsa.Default()
+
+			if ring == nil {
+				ring = fallback
+			}
+
+			kid, _ := token.Header["kid"].(string)
+			key, err := ring.Verifier(kid)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if token.Method.Alg() != key.Method {
+				return nil, jwtrsa.ErrKeyAlgorithm
+			}
+
+			return key.Public, nil
+		},
 	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return verify(func(c echo.Context) error {
+			list := revocation.Default()
+			token, ok := c.Get("user").(*jwt.Token)
+
+			if list == nil || !ok {
+				return next(c)
+			}
+
+			claims, _ := token.Claims.(jwt.MapClaims)
+			jti, _ := claims["jti"].(string)
+
+			if jti == "" {
+				return next(c)
+			}
+
+			revoked, err := list.IsRevoked(c.Request().Context(), jti)
+
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, httperror.RevokedTokenMsg)
+			}
+
+			return next(c)
+		})
+	}
 }
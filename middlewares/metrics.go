@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths
+// do not each become a series.
+const unmatchedRoute = "unmatched"
+
+// Metrics counts requests and how long they took by route template and
+// status. Errors are handled here, like the logger middleware does, so
+// the status they get is the one counted.
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			done := metrics.StartRequest()
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			done(c.Request().Method, route, c.Response().Status)
+
+			return nil
+		}
+	}
+}
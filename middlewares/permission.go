@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/rbac"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// RequirePermission refuses callers that are not granted every one of
+// permissions, see Can. It runs after Authenticate.
+func RequirePermission(permissions ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !Can(c, permissions...) {
+				return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// Can reports whether the caller is granted every one of permissions: by
+// the scopes of their API key, or else by rbac.Default for their role.
+func Can(c echo.Context, permissions ...string) bool {
+	if scopes, ok := c.Get("scopes").([]string); ok {
+		return rbac.Allows(scopes, permissions...)
+	}
+
+	return rbac.Default().Can(utils.ConvertString(c.Get("role")), permissions...)
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestID takes the X-Request-ID of the client, or makes one when it is
+// missing or not usable, and sends it back. The context of the request
+// carries the ID and a logger adding it to every line.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+
+			if !validRequestID(id) {
+				var err error
+
+				if id, err = utils.GenerateToken(constant.RequestIDBytes); err != nil {
+					return err
+				}
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(logger.WithRequestID(req.Context(), id)))
+
+			return next(c)
+		}
+	}
+}
+
+// validRequestID accepts IDs such as UUIDs and tokens, which can be logged
+// as they are.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > constant.MaxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		isAlphanumeric := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+
+		if !isAlphanumeric && r != '-' && r != '_' && r != '.' && r != ':' {
+			return false
+		}
+	}
+
+	return true
+}
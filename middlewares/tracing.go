@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Tracing starts a server span for every request named by its method and
+// route template, continuing the trace of the traceparent header of the
+// caller. Log lines of the request carry the trace ID. Errors are handled
+// here, like Metrics does, so the span has the status that was sent.
+func Tracing() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route := c.Path()
+
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			attributes := append(httpconv.ServerRequest("", req), semconv.HTTPRoute(route))
+
+			if id := logger.RequestID(ctx); id != "" {
+				attributes = append(attributes, attribute.String(logger.RequestIDKey, id))
+			}
+
+			ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, req.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+			defer span.End()
+
+			if span.SpanContext().IsValid() {
+				ctx = logger.NewContext(ctx, logger.FromContext(ctx).With(zap.String("trace_id", span.SpanContext().TraceID().String())))
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			span.SetStatus(httpconv.ServerStatus(status))
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/apikey"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+type APIKeyRepository interface {
+	Add(ctx context.Context, data models.APIKey) (models.APIKey, error)
+	Get(ctx context.Context, filter models.APIKeyFilter) ([]models.APIKey, int64, error)
+	GetByKeyHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	Touch(ctx context.Context, id int64, at time.Time) error
+	Update(ctx context.Context, data models.APIKey) (models.APIKey, error)
+}
+
+// APIKeyUsecase manages API keys and, as an apikey.Resolver, signs in the
+// requests made with them.
+type APIKeyUsecase interface {
+	apikey.Resolver
+	Add(ctx context.Context, data models.APIKeyAdd, createdBy string) <-chan utils.Result
+	Get(ctx context.Context, filter models.APIKeyFilter) <-chan utils.Result
+	GetByPrefix(ctx context.Context, prefix string) <-chan utils.Result
+	Revoke(ctx context.Context, prefix string) <-chan utils.Result
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/rbac"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type APIKeyHandler interface {
+	Add(c echo.Context) error
+	Get(c echo.Context) error
+	GetByPrefix(c echo.Context) error
+	Revoke(c echo.Context) error
+}
+
+type apiKeyHandler struct {
+	apiKeyUsecase domain.APIKeyUsecase
+}
+
+func NewAPIKeyHandler(e *echo.Echo, apiKeyUsecase domain.APIKeyUsecase) APIKeyHandler {
+	handler := &apiKeyHandler{
+		apiKeyUsecase: apiKeyUsecase,
+	}
+
+	group := e.Group("/api-keys", middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()), middlewares.RequirePermission(constant.APIKeyManage))
+	group.DELETE("/:prefix", handler.Revoke)
+	group.GET("", handler.Get)
+	group.GET("/:prefix", handler.GetByPrefix)
+	group.POST("", handler.Add)
+
+	return handler
+}
+
+// Add implements APIKeyHandler. Callers can only grant scopes they have
+// themselves.
+func (h *apiKeyHandler) Add(c echo.Context) error {
+	data := new(models.APIKeyAdd)
+
+	if err := c.Bind(data); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	for i, scope := range data.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		data.Scopes[i] = scope
+
+		if !rbac.Valid(scope) {
+			return utils.ResponseError(httperror.BadRequest(fmt.Sprintf("%s: %s", httperror.InvalidScopeMsg, scope)), c)
+		}
+
+		if !middlewares.Can(c, scope) {
+			return utils.ResponseError(httperror.Forbidden(httperror.ScopeNotGrantedMsg), c)
+		}
+	}
+
+	result := <-h.apiKeyUsecase.Add(c.Request().Context(), *data, utils.ConvertString(c.Get("username")))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Add api key success", http.StatusOK, c)
+}
+
+// Get implements APIKeyHandler.
+func (h *apiKeyHandler) Get(c echo.Context) error {
+	filter := new(models.APIKeyFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	result := <-h.apiKeyUsecase.Get(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get api key success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// GetByPrefix implements APIKeyHandler.
+func (h *apiKeyHandler) GetByPrefix(c echo.Context) error {
+	result := <-h.apiKeyUsecase.GetByPrefix(c.Request().Context(), utils.ConvertString(c.Param("prefix")))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Get api key success", http.StatusOK, c)
+}
+
+// Revoke implements APIKeyHandler.
+func (h *apiKeyHandler) Revoke(c echo.Context) error {
+	result := <-h.apiKeyUsecase.Revoke(c.Request().Context(), utils.ConvertString(c.Param("prefix")))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Revoke api key success", http.StatusOK, c)
+}
@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	apiKeyEndpoint              = "/api-keys"
+	apiKeyBodyFilePath          = "test_data/api_key_body_req.json"
+	apiKeyBodyInvalidFilePath   = "test_data/api_key_body_invalid_req.json"
+	apiKeyBodyEmptyFilePath     = "test_data/api_key_body_empty_req.json"
+	apiKeyBodyUnknownScopePath  = "test_data/api_key_body_unknown_scope_req.json"
+	apiKeyBodyAllScopesFilePath = "test_data/api_key_body_all_scopes_req.json"
+	apiKeyRows                  = []string{"id", "name", "prefix", "key_hash", "scopes", "created_by", "expires_at", "last_used_at", "revoked_at", "created_at"}
+	apiKeyResult                = []driver.Value{1, "kiosk-1", testPrefix, "hash", `["loan:write","loan:manage"]`, "admin", nil, nil, nil, time.Now()}
+	testPrefix                  = "0123456789ab"
+)
+
+type Suite struct {
+	suite.Suite
+	e                *echo.Echo
+	DB               *gorm.DB
+	mock             sqlmock.Sqlmock
+	apiKeyRepository domain.APIKeyRepository
+	apiKeyUsecase    domain.APIKeyUsecase
+	apiKeyHandler    handlers.APIKeyHandler
+}
+
+func (s *Suite) SetupSuite() {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	s.e = echo.New()
+	s.e.Validator = validator.NewCustomValidator()
+	db, s.mock, err = sqlmock.New()
+	s.Require().NoError(err)
+
+	dialector := mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	})
+
+	s.DB, err = gorm.Open(dialector, &gorm.Config{})
+	s.Require().NoError(err)
+
+	s.apiKeyRepository = repositories.NewAPIKeyRepository(s.DB)
+	s.apiKeyUsecase = usecases.NewAPIKeyUsecase(s.apiKeyRepository)
+	s.apiKeyHandler = handlers.NewAPIKeyHandler(s.e, s.apiKeyUsecase)
+}
+
+func (s *Suite) TearDownSuite() {
+	db, err := s.DB.DB()
+	s.Require().NoError(err)
+	db.Close()
+}
+
+func (s *Suite) TestAddAPIKey() {
+	tests := []struct {
+		name           string
+		role           string
+		bodyFilepath   string
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", role: constant.Admin, bodyFilepath: apiKeyBodyFilePath, expectedStatus: http.StatusOK},
+		{name: "all scopes", role: constant.SuperAdmin, bodyFilepath: apiKeyBodyAllScopesFilePath, expectedStatus: http.StatusOK},
+		{name: "scope not granted to caller", role: constant.Admin, bodyFilepath: apiKeyBodyAllScopesFilePath, expectedStatus: http.StatusForbidden},
+		{name: "unknown scope", role: constant.SuperAdmin, bodyFilepath: apiKeyBodyUnknownScopePath, expectedStatus: http.StatusBadRequest},
+		{name: "bind error", role: constant.Admin, bodyFilepath: apiKeyBodyInvalidFilePath, expectedStatus: http.StatusBadRequest},
+		{name: "validator error", role: constant.Admin, bodyFilepath: apiKeyBodyEmptyFilePath, expectedStatus: http.StatusBadRequest},
+		{name: "sql error", role: constant.Admin, bodyFilepath: apiKeyBodyFilePath, sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		jsonFile, err := os.Open(tt.bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, apiKeyEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(apiKeyEndpoint)
+		c.Set("username", "admin")
+		c.Set("role", tt.role)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else if tt.expectedStatus == http.StatusOK {
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err = s.apiKeyHandler.Add(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().Contains(rec.Body.String(), `"key":"`+constant.APIKeyScheme+"_", tt.name)
+			s.Require().NotContains(rec.Body.String(), "key_hash", tt.name)
+		}
+	}
+}
+
+func (s *Suite) TestGetAPIKey() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		totalErr       bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "total error", totalErr: true, sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("name", "kiosk-1")
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, apiKeyEndpoint+"?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(apiKeyEndpoint)
+
+		if tt.sqlErr != nil && !tt.bindErr && tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.sqlErr != nil && !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(apiKeyRows).AddRow(apiKeyResult...))
+		}
+
+		err := s.apiKeyHandler.Get(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().Contains(rec.Body.String(), `"scopes":["loan:write","loan:manage"]`, tt.name)
+			s.Require().NotContains(rec.Body.String(), "hash", tt.name)
+		}
+	}
+}
+
+func (s *Suite) TestGetAPIKeyByPrefix() {
+	tests := []struct {
+		name           string
+		notFound       bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, apiKeyEndpoint+"/"+testPrefix, nil)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(apiKeyEndpoint + "/:prefix")
+		c.SetParamNames("prefix")
+		c.SetParamValues(testPrefix)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnError(tt.sqlErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(apiKeyRows))
+		} else {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(apiKeyRows).AddRow(apiKeyResult...))
+		}
+
+		err := s.apiKeyHandler.GetByPrefix(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+	}
+}
+
+func (s *Suite) TestRevokeAPIKey() {
+	revokedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name           string
+		notFound       bool
+		revoked        bool
+		sqlGetErr      error
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "already revoked", revoked: true, expectedStatus: http.StatusOK},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "sql get error", sqlGetErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, apiKeyEndpoint+"/"+testPrefix, nil)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(apiKeyEndpoint + "/:prefix")
+		c.SetParamNames("prefix")
+		c.SetParamValues(testPrefix)
+
+		if tt.sqlGetErr != nil {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnError(tt.sqlGetErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(apiKeyRows))
+		} else if tt.revoked {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(apiKeyRows).AddRow(1, "kiosk-1", testPrefix, "hash", `["loan:write"]`, "admin", nil, nil, revokedAt, time.Now()))
+		} else {
+			s.mock.ExpectQuery("").WithArgs(testPrefix).WillReturnRows(sqlmock.NewRows(apiKeyRows).AddRow(apiKeyResult...))
+			s.mock.ExpectBegin()
+
+			if tt.sqlErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+				s.mock.ExpectRollback()
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+				s.mock.ExpectCommit()
+			}
+		}
+
+		err := s.apiKeyHandler.Revoke(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().NotContains(rec.Body.String(), `"revoked_at":null`, tt.name)
+		}
+	}
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
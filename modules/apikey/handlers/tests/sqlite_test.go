@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/apikey"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases/dbtest"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeysOnSQLite(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+
+	defer config.LoadConfig()
+	config.Config().BasicAuthUsername = "admin"
+	config.Config().BasicAuthPassword = "admin"
+	config.Config().BasicAuthRole = constant.SuperAdmin
+
+	apiKeyUsecase := usecases.NewAPIKeyUsecase(repositories.NewAPIKeyRepository(db))
+	apikey.SetDefault(apiKeyUsecase)
+	t.Cleanup(func() { apikey.SetDefault(nil) })
+
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	handlers.NewAPIKeyHandler(e, apiKeyUsecase)
+
+	auth := middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount())
+	e.POST("/loans", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get("username").(string))
+	}, auth, middlewares.RequirePermission(constant.LoanWrite))
+	e.POST("/books", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, auth, middlewares.RequirePermission(constant.BookWrite))
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	admin := map[string]string{echo.HeaderAuthorization: "Basic YWRtaW46YWRtaW4="}
+
+	rec := do(http.MethodPost, "/api-keys", `{"name":"kiosk-1","scopes":["loan:write"],"expires_in_days":30}`, admin)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var created struct {
+		Data models.APIKeyCreated `json:"data"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, "admin", created.Data.CreatedBy)
+	require.Equal(t, created.Data.Prefix, apikey.PrefixOf(created.Data.Key))
+
+	key := map[string]string{constant.APIKeyHeader: created.Data.Key}
+
+	rec = do(http.MethodPost, "/loans", "", key)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, constant.APIKeyUsernamePrefix+created.Data.Prefix, rec.Body.String())
+
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/books", "", key).Code)
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api-keys", "", key).Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/loans", "", map[string]string{constant.APIKeyHeader: created.Data.Key + "x"}).Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/loans", "", map[string]string{constant.APIKeyHeader: "garbage"}).Code)
+
+	var stored models.APIKey
+	require.NoError(t, db.Where("prefix = ?", created.Data.Prefix).First(&stored).Error)
+	require.NotNil(t, stored.LastUsedAt)
+	require.Equal(t, []string{constant.LoanWrite}, stored.Scopes)
+	require.NotEqual(t, created.Data.Key, stored.KeyHash)
+
+	rec = do(http.MethodDelete, "/api-keys/"+created.Data.Prefix, "", admin)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/loans", "", key).Code)
+
+	rec = do(http.MethodPost, "/api-keys", `{"name":"kiosk-2","scopes":["loan:*"]}`, admin)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Nil(t, created.Data.ExpiresAt)
+
+	key = map[string]string{constant.APIKeyHeader: created.Data.Key}
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/loans", "", key).Code)
+
+	require.NoError(t, db.Model(&models.APIKey{}).Where("prefix = ?", created.Data.Prefix).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/loans", "", key).Code)
+}
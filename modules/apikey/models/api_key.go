@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// APIKey lets a machine client call the API without a user password. Only
+// the hash of the key is stored; the prefix is kept to tell keys apart.
+// Requests made with the key are granted its scopes.
+type APIKey struct {
+	ID         int64      `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_key"
+}
+
+// Active reports whether the key can still be used at now.
+func (m APIKey) Active(now time.Time) bool {
+	return m.RevokedAt == nil && (m.ExpiresAt == nil || m.ExpiresAt.After(now))
+}
@@ -0,0 +1,15 @@
+package models
+
+// APIKeyAdd creates a key. Scopes are permissions, wildcards included; a
+// key expires after ExpiresInDays, or never when it is zero.
+type APIKeyAdd struct {
+	Name          string   `json:"name" validate:"required"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"gte=0"`
+}
+
+// APIKeyCreated is a new key. Key is only ever returned here.
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
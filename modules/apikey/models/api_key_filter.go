@@ -0,0 +1,9 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+
+type APIKeyFilter struct {
+	Name      string `json:"name" query:"name"`
+	CreatedBy string `json:"created_by" query:"created_by"`
+	utils.PaginationRequest
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+func (m *APIKeyAdd) ToAPIKey(e APIKey, now time.Time) APIKey {
+	e.Name = strings.TrimSpace(m.Name)
+	e.Scopes = m.Scopes
+	e.CreatedAt = now
+
+	if m.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, m.ExpiresInDays)
+		e.ExpiresAt = &expiresAt
+	}
+
+	return e
+}
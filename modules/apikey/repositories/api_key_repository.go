@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// Add implements domain.APIKeyRepository.
+func (r *apiKeyRepository) Add(ctx context.Context, data models.APIKey) (result models.APIKey, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// Get implements domain.APIKeyRepository.
+func (r *apiKeyRepository) Get(ctx context.Context, filter models.APIKeyFilter) (result []models.APIKey, total int64, err error) {
+	db := databases.Conn(ctx, r.db)
+	db = buildFilterQuery(db, filter)
+
+	if err = db.Model(&models.APIKey{}).Count(&total).Error; err != nil {
+		return
+	}
+
+	if !filter.DisablePagination {
+		db = db.Offset(int(filter.GetOffset())).Limit(int(filter.GetLimit()))
+	}
+
+	if err = db.Order("id").Find(&result).Error; err != nil {
+		return
+	}
+
+	return
+}
+
+// GetByKeyHash implements domain.APIKeyRepository.
+func (r *apiKeyRepository) GetByKeyHash(ctx context.Context, keyHash string) (result models.APIKey, err error) {
+	err = databases.Conn(ctx, r.db).Where("key_hash = ?", keyHash).First(&result).Error
+	return
+}
+
+// GetByPrefix implements domain.APIKeyRepository.
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (result models.APIKey, err error) {
+	err = databases.Conn(ctx, r.db).Where("prefix = ?", prefix).First(&result).Error
+	return
+}
+
+// Touch implements domain.APIKeyRepository.
+func (r *apiKeyRepository) Touch(ctx context.Context, id int64, at time.Time) error {
+	return databases.Conn(ctx, r.db).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+// Update implements domain.APIKeyRepository.
+func (r *apiKeyRepository) Update(ctx context.Context, data models.APIKey) (result models.APIKey, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+func NewAPIKeyRepository(db *gorm.DB) domain.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
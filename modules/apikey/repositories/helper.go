@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/models"
+	"gorm.io/gorm"
+)
+
+func buildFilterQuery(db *gorm.DB, f models.APIKeyFilter) *gorm.DB {
+	if f.Name != "" {
+		db = db.Where("name = ?", f.Name)
+	}
+
+	if f.CreatedBy != "" {
+		db = db.Where("created_by = ?", f.CreatedBy)
+	}
+
+	return db
+}
@@ -0,0 +1,169 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/apikey/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/apikey"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type apiKeyUsecase struct {
+	apiKeyRepository domain.APIKeyRepository
+}
+
+// Add implements domain.APIKeyUsecase.
+func (u *apiKeyUsecase) Add(ctx context.Context, data models.APIKeyAdd, createdBy string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		expend := data.ToAPIKey(models.APIKey{CreatedBy: createdBy}, time.Now())
+
+		key, prefix, err := apikey.Generate()
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		expend.Prefix = prefix
+		expend.KeyHash = apikey.Hash(key)
+
+		result, err := u.apiKeyRepository.Add(ctx, expend)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: models.APIKeyCreated{APIKey: result, Key: key}}
+	}()
+
+	return output
+}
+
+// Get implements domain.APIKeyUsecase.
+func (u *apiKeyUsecase) Get(ctx context.Context, filter models.APIKeyFilter) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, total, err := u.apiKeyRepository.Get(ctx, filter)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result, Total: total}
+	}()
+
+	return output
+}
+
+// GetByPrefix implements domain.APIKeyUsecase.
+func (u *apiKeyUsecase) GetByPrefix(ctx context.Context, prefix string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, err := u.apiKeyRepository.GetByPrefix(ctx, prefix)
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			output <- utils.Result{Error: httperror.NotFound(httperror.NotFoundErrorMessage)}
+			return
+		}
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// Revoke implements domain.APIKeyUsecase. The key is kept, so it still
+// shows who made it and when it was last used.
+func (u *apiKeyUsecase) Revoke(ctx context.Context, prefix string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, err := u.apiKeyRepository.GetByPrefix(ctx, prefix)
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			output <- utils.Result{Error: httperror.NotFound(httperror.NotFoundErrorMessage)}
+			return
+		}
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if result.RevokedAt == nil {
+			now := time.Now()
+			result.RevokedAt = &now
+
+			if result, err = u.apiKeyRepository.Update(ctx, result); err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// Resolve implements apikey.Resolver. When the key was last used is only
+// written once per constant.APIKeyTouchInterval, so busy clients do not
+// write on every request.
+func (u *apiKeyUsecase) Resolve(ctx context.Context, key string) (apikey.Principal, error) {
+	if apikey.PrefixOf(key) == "" {
+		return apikey.Principal{}, apikey.ErrInvalidKey
+	}
+
+	result, err := u.apiKeyRepository.GetByKeyHash(ctx, apikey.Hash(key))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apikey.Principal{}, apikey.ErrInvalidKey
+	}
+
+	if err != nil {
+		return apikey.Principal{}, err
+	}
+
+	now := time.Now()
+
+	if !result.Active(now) {
+		return apikey.Principal{}, apikey.ErrInvalidKey
+	}
+
+	if result.LastUsedAt == nil || now.Sub(*result.LastUsedAt) >= constant.APIKeyTouchInterval {
+		if err = u.apiKeyRepository.Touch(ctx, result.ID, now); err != nil {
+			return apikey.Principal{}, err
+		}
+	}
+
+	return apikey.Principal{Name: result.Name, Prefix: result.Prefix, Scopes: result.Scopes}, nil
+}
+
+func NewAPIKeyUsecase(apiKeyRepository domain.APIKeyRepository) domain.APIKeyUsecase {
+	return &apiKeyUsecase{apiKeyRepository: apiKeyRepository}
+}
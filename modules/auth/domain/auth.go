@@ -2,11 +2,50 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 )
 
+type IdentityRepository interface {
+	Add(ctx context.Context, data models.UserIdentity) (models.UserIdentity, error)
+	Delete(ctx context.Context, id int64) error
+	GetBySubject(ctx context.Context, issuer, subject string) (models.UserIdentity, error)
+}
+
+type RefreshTokenRepository interface {
+	Add(ctx context.Context, data models.RefreshToken) (models.RefreshToken, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	GetLive(ctx context.Context, filter models.RefreshTokenFilter, now time.Time) ([]models.RefreshToken, error)
+	Revoke(ctx context.Context, filter models.RefreshTokenFilter, at time.Time) error
+	Update(ctx context.Context, data models.RefreshToken) (models.RefreshToken, error)
+}
+
+type TwoFactorRepository interface {
+	AddChallenge(ctx context.Context, data models.LoginChallenge) (models.LoginChallenge, error)
+	DeleteChallenge(ctx context.Context, id int64) error
+	DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error)
+	GetChallenge(ctx context.Context, tokenHash string) (models.LoginChallenge, error)
+	GetTOTP(ctx context.Context, username string) (models.UserTOTP, error)
+	ReplaceRecoveryCodes(ctx context.Context, username string, codes []models.RecoveryCode) error
+	SaveTOTP(ctx context.Context, data models.UserTOTP) (models.UserTOTP, error)
+	UpdateChallenge(ctx context.Context, data models.LoginChallenge) (models.LoginChallenge, error)
+	UseRecoveryCode(ctx context.Context, username, codeHash string, at time.Time) (bool, error)
+}
+
 type AuthUsecase interface {
+	AuthWithOIDC(ctx context.Context, authReq models.LoginOIDC) <-chan utils.Result
 	AuthWithPassword(ctx context.Context, authReq models.LoginAuth) <-chan utils.Result
+	AuthWithTwoFactor(ctx context.Context, authReq models.LoginTwoFactor) <-chan utils.Result
+	ConfirmTwoFactor(ctx context.Context, username string, code models.TwoFactorCode) <-chan utils.Result
+	EnrollTwoFactor(ctx context.Context, username string) <-chan utils.Result
+	EnrollTwoFactorWithChallenge(ctx context.Context, challenge models.LoginChallengeAuth) <-chan utils.Result
+	LinkOIDC(ctx context.Context, username string, link models.OIDCLink) <-chan utils.Result
+	Logout(ctx context.Context, logout models.LogoutAuth) <-chan utils.Result
+	PurgeExpired(ctx context.Context) <-chan utils.Result
+	Refresh(ctx context.Context, refreshReq models.RefreshAuth) <-chan utils.Result
+	RevokeSessions(ctx context.Context, username string) <-chan utils.Result
+	Unlock(ctx context.Context, username string) <-chan utils.Result
 }
@@ -1,17 +1,36 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"net/http"
 
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/oidc"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
 type AuthHandler interface {
+	ConfirmTwoFactor(c echo.Context) error
+	EnrollTwoFactor(c echo.Context) error
+	JWKS(c echo.Context) error
+	LinkOIDC(c echo.Context) error
 	Login(c echo.Context) error
+	LoginEnrollTwoFactor(c echo.Context) error
+	LoginTwoFactor(c echo.Context) error
+	Logout(c echo.Context) error
+	OIDCCallback(c echo.Context) error
+	OIDCLogin(c echo.Context) error
+	Refresh(c echo.Context) error
+	RevokeSessions(c echo.Context) error
+	Unlock(c echo.Context) error
 }
 
 type authHandler struct {
@@ -27,18 +46,283 @@ func (h *authHandler) Login(c echo.Context) error {
 	}
 
 	if err := c.Validate(authRequest); err != nil {
-		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		return utils.ResponseError(err, c)
 	}
 
+	authRequest.IP = c.RealIP()
+
 	result := <-h.authUsecase.AuthWithPassword(c.Request().Context(), *authRequest)
 
 	if result.Error != nil {
 		return utils.ResponseError(result.Error, c)
 	}
 
+	if _, ok := result.Data.(models.LoginChallengeResponse); ok {
+		return utils.Response(result.Data, "Two-factor code required", http.StatusOK, c)
+	}
+
+	return utils.Response(result.Data, "Login success", http.StatusOK, c)
+}
+
+// LoginTwoFactor implements AuthHandler.
+func (h *authHandler) LoginTwoFactor(c echo.Context) error {
+	authRequest := new(models.LoginTwoFactor)
+
+	if err := c.Bind(authRequest); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(authRequest); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	authRequest.IP = c.RealIP()
+
+	result := <-h.authUsecase.AuthWithTwoFactor(c.Request().Context(), *authRequest)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
 	return utils.Response(result.Data, "Login success", http.StatusOK, c)
 }
 
+// LoginEnrollTwoFactor implements AuthHandler.
+func (h *authHandler) LoginEnrollTwoFactor(c echo.Context) error {
+	challengeRequest := new(models.LoginChallengeAuth)
+
+	if err := c.Bind(challengeRequest); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(challengeRequest); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	result := <-h.authUsecase.EnrollTwoFactorWithChallenge(c.Request().Context(), *challengeRequest)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Enroll two-factor success", http.StatusOK, c)
+}
+
+// EnrollTwoFactor implements AuthHandler.
+func (h *authHandler) EnrollTwoFactor(c echo.Context) error {
+	result := <-h.authUsecase.EnrollTwoFactor(c.Request().Context(), utils.ConvertString(c.Get("username")))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Enroll two-factor success", http.StatusOK, c)
+}
+
+// ConfirmTwoFactor implements AuthHandler.
+func (h *authHandler) ConfirmTwoFactor(c echo.Context) error {
+	codeRequest := new(models.TwoFactorCode)
+
+	if err := c.Bind(codeRequest); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(codeRequest); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	result := <-h.authUsecase.ConfirmTwoFactor(c.Request().Context(), utils.ConvertString(c.Get("username")), *codeRequest)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Confirm two-factor success", http.StatusOK, c)
+}
+
+// Logout implements AuthHandler.
+func (h *authHandler) Logout(c echo.Context) error {
+	token, ok := c.Get("user").(*jwt.Token)
+
+	if !ok {
+		return utils.ResponseError(httperror.Unauthorized(httperror.UnauthorizedErrorMessage), c)
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	jti, _ := claims["jti"].(string)
+	expiresAt, err := claims.GetExpirationTime()
+
+	if jti == "" || err != nil || expiresAt == nil {
+		return utils.ResponseError(httperror.BadRequest("token can not be revoked, it has no jti or exp claim"), c)
+	}
+
+	result := <-h.authUsecase.Logout(c.Request().Context(), models.LogoutAuth{JTI: jti, ExpiresAt: expiresAt.Time})
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(nil, "Logout success", http.StatusOK, c)
+}
+
+// Refresh implements AuthHandler.
+func (h *authHandler) Refresh(c echo.Context) error {
+	refreshRequest := new(models.RefreshAuth)
+
+	if err := c.Bind(refreshRequest); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(refreshRequest); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	result := <-h.authUsecase.Refresh(c.Request().Context(), *refreshRequest)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Refresh token success", http.StatusOK, c)
+}
+
+// RevokeSessions implements AuthHandler.
+func (h *authHandler) RevokeSessions(c echo.Context) error {
+	result := <-h.authUsecase.RevokeSessions(c.Request().Context(), c.Param("username"))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(nil, "Revoke sessions success", http.StatusOK, c)
+}
+
+// JWKS implements AuthHandler. It serves the public keys access tokens are
+// verified with, so other services can verify them too. Keys stay listed
+// while tokens signed with them may still be in use.
+func (h *authHandler) JWKS(c echo.Context) error {
+	set := jwtrsa.JWKSet{Keys: []jwtrsa.JWK{}}
+
+	if ring := jwtrsa.Default(); ring != nil {
+		set = ring.JWKS()
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, constant.JWKSCacheControl)
+
+	return c.JSON(http.StatusOK, set)
+}
+
+// OIDCLogin implements AuthHandler. The browser is sent to the identity
+// provider, with what the callback needs to finish signing in kept in a
+// cookie.
+func (h *authHandler) OIDCLogin(c echo.Context) error {
+	client := oidc.Default()
+
+	if client == nil {
+		return utils.ResponseError(httperror.NotFound(httperror.OIDCDisabledMsg), c)
+	}
+
+	state, err := oidc.NewState()
+
+	if err != nil {
+		return utils.ResponseError(httperror.FromError(err), c)
+	}
+
+	redirectURL, err := client.AuthCodeURL(c.Request().Context(), state)
+
+	if err != nil {
+		return utils.ResponseError(httperror.FromError(err), c)
+	}
+
+	c.SetCookie(oidcStateCookie(c, state.String(), int(constant.OIDCStateTTL.Seconds())))
+
+	return c.Redirect(http.StatusFound, redirectURL)
+}
+
+// OIDCCallback implements AuthHandler.
+func (h *authHandler) OIDCCallback(c echo.Context) error {
+	callback := new(models.OIDCCallback)
+
+	if err := c.Bind(callback); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	cookie, err := c.Cookie(constant.OIDCStateCookie)
+
+	// The state is only good for one callback
+	c.SetCookie(oidcStateCookie(c, "", -1))
+
+	if callback.Error != "" {
+		return utils.ResponseError(httperror.Unauthorized(httperror.OIDCLoginFailedMsg), c)
+	}
+
+	if err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.InvalidOIDCStateMsg), c)
+	}
+
+	state, ok := oidc.ParseState(cookie.Value)
+
+	if !ok || callback.Code == "" || subtle.ConstantTimeCompare([]byte(state.State), []byte(callback.State)) != 1 {
+		return utils.ResponseError(httperror.BadRequest(httperror.InvalidOIDCStateMsg), c)
+	}
+
+	result := <-h.authUsecase.AuthWithOIDC(c.Request().Context(), models.LoginOIDC{Code: callback.Code, State: state, IP: c.RealIP()})
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	if _, ok := result.Data.(models.LoginChallengeResponse); ok {
+		return utils.Response(result.Data, "Two-factor code required", http.StatusOK, c)
+	}
+
+	return utils.Response(result.Data, "Login success", http.StatusOK, c)
+}
+
+// LinkOIDC implements AuthHandler.
+func (h *authHandler) LinkOIDC(c echo.Context) error {
+	link := new(models.OIDCLink)
+
+	if err := c.Bind(link); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(link); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	result := <-h.authUsecase.LinkOIDC(c.Request().Context(), c.Param("username"), *link)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Link identity provider success", http.StatusOK, c)
+}
+
+func oidcStateCookie(c echo.Context, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     constant.OIDCStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Unlock implements AuthHandler.
+func (h *authHandler) Unlock(c echo.Context) error {
+	result := <-h.authUsecase.Unlock(c.Request().Context(), c.Param("username"))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Unlock user success", http.StatusOK, c)
+}
+
 func NewAuthHandler(e *echo.Echo, authUsecase domain.AuthUsecase) AuthHandler {
 	handler := &authHandler{
 		authUsecase: authUsecase,
@@ -46,6 +330,19 @@ func NewAuthHandler(e *echo.Echo, authUsecase domain.AuthUsecase) AuthHandler {
 
 	group := e.Group("/auth")
 	group.POST("/login", handler.Login)
+	group.POST("/login/2fa", handler.LoginTwoFactor)
+	group.POST("/login/2fa/enroll", handler.LoginEnrollTwoFactor)
+	group.POST("/2fa/enroll", handler.EnrollTwoFactor, middlewares.VerifyJWTRSA(config.Config().PublicKey), middlewares.EchoSetCredential())
+	group.POST("/2fa/confirm", handler.ConfirmTwoFactor, middlewares.VerifyJWTRSA(config.Config().PublicKey), middlewares.EchoSetCredential())
+	group.GET("/oidc/login", handler.OIDCLogin)
+	group.GET("/oidc/callback", handler.OIDCCallback)
+	group.POST("/refresh", handler.Refresh)
+	group.POST("/logout", handler.Logout, middlewares.VerifyJWTRSA(config.Config().PublicKey))
+	group.DELETE("/sessions/:username", handler.RevokeSessions, middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()), middlewares.RequirePermission(constant.UserManage))
+
+	e.GET("/.well-known/jwks.json", handler.JWKS)
+	e.POST("/user/:username/oidc", handler.LinkOIDC, middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()), middlewares.RequirePermission(constant.UserManage))
+	e.POST("/user/:username/unlock", handler.Unlock, middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()), middlewares.RequirePermission(constant.UserManage))
 
 	return handler
 }
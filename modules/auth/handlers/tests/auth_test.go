@@ -3,23 +3,31 @@ package tests
 import (
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Zeroaril7/perpustakaan-go/config"
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/usecases"
 	userDomain "github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	userRepo "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -32,10 +40,17 @@ var (
 	authBodyFilePath                = "test_data/auth_login_body_req.json"
 	authBodyInvalidFilePath         = "test_data/auth_login_body_invalid_req.json"
 	authBodyEmptyFilePath           = "test_data/auth_login_body_empty_req.json"
+	refreshEndpoint                 = "/refresh"
+	logoutEndpoint                  = "/logout"
+	refreshBodyFilePath             = "test_data/auth_refresh_body_req.json"
+	refreshBodyInvalidFilePath      = "test_data/auth_refresh_body_invalid_req.json"
+	refreshBodyEmptyFilePath        = "test_data/auth_refresh_body_empty_req.json"
 	privateKeyPath                  = "test_data/private.pem"
 	publicKeyPath                   = "test_data/public.pem"
 	userRows                        = []string{"username", "password", "role"}
 	userResult                      = []driver.Value{"test", utils.HashPassword("test"), "ADMIN"}
+	userTOTPRows                    = []string{"username", "secret", "last_step", "confirmed_at", "created_at"}
+	refreshTokenRows                = []string{"id", "token_hash", "family", "username", "access_jti", "access_expires_at", "expires_at", "revoked_at", "created_at"}
 )
 
 type Suite struct {
@@ -46,6 +61,7 @@ type Suite struct {
 	authUsecase    domain.AuthUsecase
 	userRepository userDomain.UserRepository
 	authHandler    handlers.AuthHandler
+	loginTracker   lockout.Tracker
 }
 
 func (s *Suite) SetupSuite() {
@@ -69,7 +85,8 @@ func (s *Suite) SetupSuite() {
 
 	s.userRepository = userRepo.NewUserRepository(s.DB)
 
-	s.authUsecase = usecases.NewAuthUsecase(s.userRepository)
+	s.loginTracker = lockout.New(lockout.Policy{MaxAttempts: 3, Lockout: time.Minute})
+	s.authUsecase = usecases.NewAuthUsecase(s.userRepository, repositories.NewRefreshTokenRepository(s.DB), repositories.NewTwoFactorRepository(s.DB), repositories.NewIdentityRepository(s.DB), revocation.New(s.DB), s.loginTracker, databases.NewUnitOfWork(s.DB))
 	s.authHandler = handlers.NewAuthHandler(s.e, s.authUsecase)
 
 	config.LoadConfig()
@@ -89,11 +106,13 @@ func (s *Suite) TestLogin() {
 		authUsernameErr bool
 		authPasswordErr bool
 		jwtErr          bool
+		outdatedHash    bool
 		sqlErr          error
 		expectedStatus  int
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
+		{name: "rehash outdated password", outdatedHash: true, expectedStatus: http.StatusOK},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: gorm.ErrRecordNotFound, expectedStatus: http.StatusUnauthorized},
 		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
@@ -103,6 +122,9 @@ func (s *Suite) TestLogin() {
 		{name: "jwt error", jwtErr: true, expectedStatus: http.StatusInternalServerError},
 	}
 
+	config.Config().TwoFactorRoles = "NONE"
+	defer config.LoadConfig()
+
 	for _, tt := range tests {
 		if !tt.jwtErr {
 			privateKeyFile, _ := os.Open(privateKeyPath)
@@ -144,10 +166,28 @@ func (s *Suite) TestLogin() {
 
 		if tt.sqlErr != nil && !tt.bindErr && !tt.validatorErr && !tt.authPasswordErr && !tt.authUsernameErr && !tt.jwtErr {
 			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.outdatedHash {
+			hash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+			s.Require().NoError(err)
+
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow("test", string(hash), "ADMIN"))
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+			s.mock.ExpectCommit()
 		} else if !tt.bindErr && !tt.validatorErr {
 			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
 		}
 
+		if tt.sqlErr == nil && !tt.bindErr && !tt.validatorErr && !tt.authPasswordErr && !tt.authUsernameErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userTOTPRows))
+		}
+
+		if tt.sqlErr == nil && !tt.bindErr && !tt.validatorErr && !tt.authPasswordErr && !tt.authUsernameErr && !tt.jwtErr {
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
 		err = s.authHandler.Login(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
@@ -155,6 +195,325 @@ func (s *Suite) TestLogin() {
 	}
 }
 
+func (s *Suite) TestLoginLockout() {
+	s.setKeys()
+	s.loginTracker.Unlock("test")
+
+	config.Config().TwoFactorRoles = "NONE"
+	defer config.LoadConfig()
+
+	var tests = []struct {
+		name           string
+		wrongPassword  bool
+		locked         bool
+		unlock         bool
+		expectedStatus int
+	}{
+		{name: "first failure", wrongPassword: true, expectedStatus: http.StatusUnauthorized},
+		{name: "second failure", wrongPassword: true, expectedStatus: http.StatusUnauthorized},
+		{name: "failure locks", wrongPassword: true, expectedStatus: http.StatusLocked},
+		{name: "locked", locked: true, expectedStatus: http.StatusLocked},
+		{name: "unlock", unlock: true, expectedStatus: http.StatusOK},
+		{name: "success after unlock", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		bodyFilepath := authBodyFilePath
+		if tt.wrongPassword {
+			bodyFilepath = invalidPasswordAuthBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, authEndpoint+loginEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+
+		if tt.unlock {
+			c.SetPath("/user/:username/unlock")
+			c.SetParamNames("username")
+			c.SetParamValues("test")
+
+			err = s.authHandler.Unlock(c)
+			s.Require().NoError(err)
+			s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+			continue
+		}
+
+		c.SetPath(authEndpoint + loginEndpoint)
+
+		if !tt.locked {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+		}
+
+		if tt.expectedStatus == http.StatusOK {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userTOTPRows))
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err = s.authHandler.Login(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+
+		if tt.expectedStatus == http.StatusLocked {
+			s.Require().Equal("60", rec.Header().Get("Retry-After"), tt.name)
+		}
+	}
+}
+
+func (s *Suite) TestLoginBackoff() {
+	s.setKeys()
+
+	handler := handlers.NewAuthHandler(echo.New(), usecases.NewAuthUsecase(s.userRepository, repositories.NewRefreshTokenRepository(s.DB), repositories.NewTwoFactorRepository(s.DB), repositories.NewIdentityRepository(s.DB), revocation.New(s.DB), lockout.New(lockout.Policy{
+		MaxAttempts: 5,
+		Lockout:     time.Hour,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+	}), databases.NewUnitOfWork(s.DB)))
+
+	for _, expectedStatus := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		jsonFile, err := os.Open(invalidPasswordAuthBodyFilePath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, authEndpoint+loginEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+
+		c.SetPath(authEndpoint + loginEndpoint)
+
+		if expectedStatus == http.StatusUnauthorized {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+		}
+
+		err = handler.Login(c)
+		s.Require().NoError(err)
+		s.Require().Equal(expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestLoginIPThrottleIgnoresForwardedFor() {
+	s.setKeys()
+
+	serverConfig, err := config.Config().Server()
+	s.Require().NoError(err)
+
+	e := echo.New()
+	e.IPExtractor = serverConfig.IPExtractor()
+	e.Validator = validator.NewCustomValidator()
+
+	handler := handlers.NewAuthHandler(e, usecases.NewAuthUsecase(s.userRepository, repositories.NewRefreshTokenRepository(s.DB), repositories.NewTwoFactorRepository(s.DB), repositories.NewIdentityRepository(s.DB), revocation.New(s.DB), lockout.New(lockout.Policy{
+		MaxAttempts:   100,
+		MaxIPAttempts: 2,
+		Lockout:       time.Hour,
+	}), databases.NewUnitOfWork(s.DB)))
+
+	// Every attempt claims another client IP but comes from the same peer
+	for i, expectedStatus := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		jsonFile, err := os.Open(invalidPasswordAuthBodyFilePath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, authEndpoint+loginEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderXForwardedFor, fmt.Sprintf("198.51.100.%d", i+1))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		c.SetPath(authEndpoint + loginEndpoint)
+
+		if expectedStatus == http.StatusUnauthorized {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+		}
+
+		err = handler.Login(c)
+		s.Require().NoError(err)
+		s.Require().Equal(expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) setKeys() {
+	privateKey, err := os.ReadFile(privateKeyPath)
+	s.Require().NoError(err)
+
+	publicKey, err := os.ReadFile(publicKeyPath)
+	s.Require().NoError(err)
+
+	config.Config().PrivateKey = string(privateKey)
+	config.Config().PublicKey = string(publicKey)
+}
+
+func refreshTokenResult(expiresAt time.Time, revokedAt *time.Time) []driver.Value {
+	return []driver.Value{1, utils.HashToken("test"), "family", "test", "jti", time.Now().Add(time.Hour), expiresAt, revokedAt, time.Now()}
+}
+
+func (s *Suite) TestRefresh() {
+	revokedAt := time.Now().Add(-time.Minute)
+
+	var tests = []struct {
+		name           string
+		bindErr        bool
+		validatorErr   bool
+		notFound       bool
+		expired        bool
+		reused         bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "unknown token", notFound: true, expectedStatus: http.StatusUnauthorized},
+		{name: "expired token", expired: true, expectedStatus: http.StatusUnauthorized},
+		{name: "reused token", reused: true, expectedStatus: http.StatusUnauthorized},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		s.setKeys()
+
+		var bodyFilepath string
+		if tt.bindErr {
+			bodyFilepath = refreshBodyInvalidFilePath
+		} else if tt.validatorErr {
+			bodyFilepath = refreshBodyEmptyFilePath
+		} else {
+			bodyFilepath = refreshBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, authEndpoint+refreshEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+
+		c.SetPath(authEndpoint + refreshEndpoint)
+
+		if !tt.bindErr && !tt.validatorErr {
+			s.mock.ExpectBegin()
+
+			if tt.sqlErr != nil {
+				s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+				s.mock.ExpectRollback()
+			} else if tt.notFound {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows))
+				s.mock.ExpectRollback()
+			} else if tt.expired {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(-time.Minute), nil)...))
+				s.mock.ExpectRollback()
+			} else if tt.reused {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(time.Hour), &revokedAt)...))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(time.Hour), nil)...))
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+				s.mock.ExpectCommit()
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(time.Hour), nil)...))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(2, 1))
+				s.mock.ExpectCommit()
+			}
+		}
+
+		err = s.authHandler.Refresh(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestLogout() {
+	var tests = []struct {
+		name           string
+		noJTI          bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "token without jti", noJTI: true, expectedStatus: http.StatusBadRequest},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		claims := jwt.MapClaims{"username": "test", "exp": float64(time.Now().Add(time.Hour).Unix())}
+
+		if !tt.noJTI {
+			claims["jti"] = "jti"
+		}
+
+		req := httptest.NewRequest(http.MethodPost, authEndpoint+logoutEndpoint, nil)
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+
+		c.SetPath(authEndpoint + logoutEndpoint)
+		c.Set("user", &jwt.Token{Claims: claims})
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else if !tt.noJTI {
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(time.Hour), nil)...))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(time.Hour), nil)...))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err := s.authHandler.Logout(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestRevokeSessions() {
+	var tests = []struct {
+		name           string
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, authEndpoint+"/sessions/test", nil)
+		rec := httptest.NewRecorder()
+		c := s.e.NewContext(req, rec)
+
+		c.SetPath(authEndpoint + "/sessions/:username")
+		c.SetParamNames("username")
+		c.SetParamValues("test")
+
+		s.mock.ExpectBegin()
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(refreshTokenRows).AddRow(refreshTokenResult(time.Now().Add(time.Hour), nil)...))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err := s.authHandler.RevokeSessions(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(Suite))
 }
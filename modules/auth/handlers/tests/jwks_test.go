@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSAndKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2024-01.pem"), privateKey, 0o600))
+
+	ring, err := jwtrsa.NewRing(jwtrsa.Source{Dir: dir})
+	require.NoError(t, err)
+	jwtrsa.SetDefault(ring)
+	t.Cleanup(func() { jwtrsa.SetDefault(nil) })
+
+	e := echo.New()
+	handlers.NewAuthHandler(e, nil)
+	e.GET("/protected", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, middlewares.VerifyJWTRSA(""))
+
+	sign := func(ring *jwtrsa.Ring) string {
+		token, _, err := ring.GenerateJWT(jwtrsa.GenerateInputJWT{Claims: map[string]interface{}{"username": "test"}, TimeExpire: time.Minute})
+		require.NoError(t, err)
+
+		return token
+	}
+
+	protected := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	jwks := func() jwtrsa.JWKSet {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotEmpty(t, rec.Header().Get(echo.HeaderCacheControl))
+		require.NotContains(t, rec.Body.String(), `"d"`)
+
+		var set jwtrsa.JWKSet
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+
+		return set
+	}
+
+	oldToken := sign(ring)
+	require.Equal(t, http.StatusOK, protected(oldToken))
+
+	set := jwks()
+	require.Len(t, set.Keys, 1)
+	require.Equal(t, "RSA", set.Keys[0].Kty)
+	require.Equal(t, "RS256", set.Keys[0].Alg)
+
+	// An ECDSA key with a later name takes over signing after a reload
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2024-06.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	require.NoError(t, ring.Reload())
+
+	newToken := sign(ring)
+	require.Equal(t, http.StatusOK, protected(newToken))
+	require.Equal(t, http.StatusOK, protected(oldToken))
+
+	set = jwks()
+	require.Len(t, set.Keys, 2)
+	require.Equal(t, "ES256", set.Keys[0].Alg)
+
+	// Tokens of a retired key and of unknown keys are refused
+	require.NoError(t, os.Remove(filepath.Join(dir, "2024-01.pem")))
+	require.NoError(t, ring.Reload())
+	require.Equal(t, http.StatusUnauthorized, protected(oldToken))
+	require.Equal(t, http.StatusOK, protected(newToken))
+
+	other, err := jwtrsa.NewRing(jwtrsa.Source{PrivateKey: string(privateKey)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, protected(sign(other)))
+
+	// Without a ring there are no keys to publish or verify with
+	jwtrsa.SetDefault(nil)
+	require.Empty(t, jwks().Keys)
+	require.Equal(t, http.StatusUnauthorized, protected(newToken))
+}
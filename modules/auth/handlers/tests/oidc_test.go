@@ -0,0 +1,445 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/usecases"
+	userModel "github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	userRepo "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases/dbtest"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/oidc"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	stubClientID     = "perpustakaan"
+	stubClientSecret = "s3cret"
+	stubRedirectURL  = "http://library.test/auth/oidc/callback"
+)
+
+type stubGrant struct {
+	nonce     string
+	challenge string
+}
+
+// stubIdP is a local OpenID Connect provider. Claims are added to the ID
+// tokens it issues; the other fields break them in the ways a provider or
+// an attacker could.
+type stubIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	ring   *jwtrsa.Ring
+
+	mu       sync.Mutex
+	grants   map[string]stubGrant
+	claims   map[string]interface{}
+	signWith *jwtrsa.Ring
+	audience string
+	nonce    string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.NoError(t, err)
+
+	ring, err := jwtrsa.NewRing(jwtrsa.Source{PrivateKey: string(privateKey)})
+	require.NoError(t, err)
+
+	idp := &stubIdP{t: t, ring: ring, grants: map[string]stubGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidc.Provider{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(idp.ring.JWKS())
+	})
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *stubIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	require.Equal(idp.t, stubClientID, query.Get("client_id"))
+	require.Equal(idp.t, "code", query.Get("response_type"))
+	require.Equal(idp.t, "S256", query.Get("code_challenge_method"))
+	require.Contains(idp.t, query.Get("scope"), "openid")
+
+	code, err := utils.GenerateToken(16)
+	require.NoError(idp.t, err)
+
+	idp.mu.Lock()
+	idp.grants[code] = stubGrant{nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+	idp.mu.Unlock()
+
+	http.Redirect(w, r, query.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {query.Get("state")}}.Encode(), http.StatusFound)
+}
+
+func (idp *stubIdP) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, _ := r.BasicAuth()
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	grant, ok := idp.grants[r.PostFormValue("code")]
+	delete(idp.grants, r.PostFormValue("code"))
+
+	if !ok || clientID != stubClientID || clientSecret != stubClientSecret || r.PostFormValue("redirect_uri") != stubRedirectURL || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]interface{}{"iss": idp.server.URL, "aud": stubClientID, "sub": "stub-subject", "nonce": grant.nonce}
+
+	for key, value := range idp.claims {
+		claims[key] = value
+	}
+
+	if idp.audience != "" {
+		claims["aud"] = idp.audience
+	}
+
+	if idp.nonce != "" {
+		claims["nonce"] = idp.nonce
+	}
+
+	ring := idp.ring
+
+	if idp.signWith != nil {
+		ring = idp.signWith
+	}
+
+	idToken, _, err := ring.GenerateJWT(jwtrsa.GenerateInputJWT{Claims: claims, TimeExpire: time.Minute})
+	require.NoError(idp.t, err)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+// signIn runs the authorization code flow through the browser redirects
+// and returns the response of the callback.
+func (s sessionServer) signIn(mutate func(callback url.Values, cookie *http.Cookie)) *httptest.ResponseRecorder {
+	rec := s.do(http.MethodGet, "/auth/oidc/login", "", nil)
+	require.Equal(s.t, http.StatusFound, rec.Code, rec.Body.String())
+
+	cookies := rec.Result().Cookies()
+	require.Len(s.t, cookies, 1)
+	require.True(s.t, cookies[0].HttpOnly)
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := browser.Get(rec.Header().Get(echo.HeaderLocation))
+	require.NoError(s.t, err)
+	res.Body.Close()
+	require.Equal(s.t, http.StatusFound, res.StatusCode)
+
+	location, err := url.Parse(res.Header.Get(echo.HeaderLocation))
+	require.NoError(s.t, err)
+
+	callback := location.Query()
+	cookie := cookies[0]
+
+	if mutate != nil {
+		mutate(callback, cookie)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, location.Path+"?"+callback.Encode(), nil)
+
+	if cookie.Value != "" {
+		req.AddCookie(cookie)
+	}
+
+	rec = httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func generatePrivateKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func accessClaims(t *testing.T, accessToken string) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(accessToken, claims)
+	require.NoError(t, err)
+
+	return claims
+}
+
+func TestOIDCLoginWithStubIdP(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+	idp := newStubIdP(t)
+
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.NoError(t, err)
+
+	publicKey, err := os.ReadFile(publicKeyPath)
+	require.NoError(t, err)
+
+	defer config.LoadConfig()
+	config.Config().PrivateKey = string(privateKey)
+	config.Config().PublicKey = string(publicKey)
+
+	// Roles requiring two-factor are covered on their own below
+	config.Config().TwoFactorRoles = "NONE"
+
+	client := oidc.New(oidc.Config{
+		Issuer:        idp.server.URL + "/",
+		ClientID:      stubClientID,
+		ClientSecret:  stubClientSecret,
+		RedirectURL:   stubRedirectURL,
+		Scopes:        constant.DefaultOIDCScopes,
+		UsernameClaim: "email",
+		GroupsClaim:   "roles",
+		RoleMap:       map[string]string{"library-staff": constant.Karyawan, "library-admins": constant.Admin, "library-root": constant.SuperAdmin},
+	}, idp.server.Client())
+	oidc.SetDefault(client)
+	t.Cleanup(func() { oidc.SetDefault(nil) })
+
+	list := revocation.New(db)
+	userRepository := userRepo.NewUserRepository(db)
+	_, err = userRepository.Add(context.Background(), userModel.User{Username: "test@library.test", Password: utils.HashPassword("test"), Role: constant.Karyawan})
+	require.NoError(t, err)
+
+	tracker := lockout.New(lockout.Policy{MaxAttempts: 3, Lockout: time.Minute})
+
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	e.GET("/protected", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, middlewares.VerifyJWTRSA(config.Config().PublicKey))
+	handlers.NewAuthHandler(e, usecases.NewAuthUsecase(userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewTwoFactorRepository(db), repositories.NewIdentityRepository(db), list, tracker, databases.NewUnitOfWork(db)))
+
+	server := sessionServer{t: t, e: e}
+
+	// A new user is added with the most privileged role of their groups
+	idp.claims = map[string]interface{}{"sub": "new-subject", "email": "new@library.test", "roles": []string{"library-staff", "library-admins", "other"}}
+	tokens := server.tokens(server.signIn(nil))
+	require.Equal(t, http.StatusOK, server.protected(tokens.AccessToken))
+	require.Equal(t, constant.Admin, accessClaims(t, tokens.AccessToken)["role"])
+
+	user, err := userRepository.GetByUsername(context.Background(), "new@library.test")
+	require.NoError(t, err)
+	require.Equal(t, constant.Admin, user.Role)
+	require.Empty(t, user.Password)
+
+	// Refresh tokens of the sign-in work as usual
+	server.tokens(server.refresh(tokens.RefreshToken))
+
+	// An existing user is not taken over by a provider account of the same name
+	idp.claims = map[string]interface{}{"sub": "test-subject", "email": "test@library.test", "roles": []string{"library-admins"}}
+	require.Equal(t, http.StatusConflict, server.signIn(nil).Code)
+
+	// A user manager links it to the account at the provider
+	idp.claims = map[string]interface{}{"sub": "root-subject", "email": "root@library.test", "roles": "library-root"}
+	root := server.tokens(server.signIn(nil))
+
+	link := func(username, subject string) int {
+		body := fmt.Sprintf(`{"subject":%q}`, subject)
+		return server.do(http.MethodPost, "/user/"+username+"/oidc", body, map[string]string{echo.HeaderAuthorization: "Bearer " + root.AccessToken}).Code
+	}
+
+	require.Equal(t, http.StatusOK, link("test@library.test", "test-subject"))
+	require.Equal(t, http.StatusConflict, link("test@library.test", "test-subject"))
+	require.Equal(t, http.StatusNotFound, link("nobody@library.test", "nobody-subject"))
+	require.Equal(t, http.StatusBadRequest, link("test@library.test", ""))
+
+	// Once linked it signs in, keeping its role and its password
+	idp.claims = map[string]interface{}{"sub": "test-subject", "email": "test@library.test", "roles": "library-admins"}
+	tokens = server.tokens(server.signIn(nil))
+	require.Equal(t, constant.Karyawan, accessClaims(t, tokens.AccessToken)["role"])
+	server.tokens(server.do(http.MethodPost, "/auth/login", `{"username":"test@library.test","password":"test"}`, nil))
+
+	// The account at the provider picks the user, not the username claim
+	idp.claims["email"] = "renamed@library.test"
+	tokens = server.tokens(server.signIn(nil))
+	require.Equal(t, "test@library.test", accessClaims(t, tokens.AccessToken)["username"])
+
+	// A user locked by failed password sign-ins is not signed in either
+	for i := 0; i < 3; i++ {
+		server.do(http.MethodPost, "/auth/login", `{"username":"test@library.test","password":"wrong"}`, nil)
+	}
+
+	require.Equal(t, http.StatusLocked, server.signIn(nil).Code)
+	require.True(t, tracker.Unlock("test@library.test"))
+	server.tokens(server.signIn(nil))
+
+	// An enrolled TOTP secret is asked for
+	confirmedAt := time.Now()
+	_, err = repositories.NewTwoFactorRepository(db).SaveTOTP(context.Background(), models.UserTOTP{Username: "test@library.test", Secret: "JBSWY3DPEHPK3PXP", ConfirmedAt: &confirmedAt})
+	require.NoError(t, err)
+
+	rec := server.signIn(nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var challenge struct {
+		Data models.LoginChallengeResponse `json:"data"`
+	}
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &challenge))
+	require.True(t, challenge.Data.TwoFactorRequired)
+	require.NotEmpty(t, challenge.Data.ChallengeToken)
+	require.NotContains(t, rec.Body.String(), "access_token")
+
+	// Roles that require two-factor have to enroll while signing in
+	config.Config().TwoFactorRoles = ""
+	idp.claims = map[string]interface{}{"sub": "root-subject", "email": "root@library.test", "roles": "library-root"}
+	rec = server.signIn(nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	challenge.Data = models.LoginChallengeResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &challenge))
+	require.True(t, challenge.Data.EnrollmentRequired)
+	require.NotContains(t, rec.Body.String(), "access_token")
+	config.Config().TwoFactorRoles = "NONE"
+
+	// The role of users the sign-in added follows the groups on every sign-in
+	idp.claims = map[string]interface{}{"sub": "new-subject", "email": "new@library.test", "roles": "library-staff"}
+	tokens = server.tokens(server.signIn(nil))
+	require.Equal(t, constant.Karyawan, accessClaims(t, tokens.AccessToken)["role"])
+
+	// A user that was removed is not signed in through its old link
+	require.NoError(t, userRepository.Delete(context.Background(), "new@library.test"))
+	_, err = userRepository.Add(context.Background(), userModel.User{Username: "new@library.test", Password: utils.HashPassword("other"), Role: constant.Karyawan})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, server.signIn(nil).Code)
+
+	// Users in no mapped group are refused without a default role
+	idp.claims = map[string]interface{}{"sub": "guest-subject", "email": "guest@library.test", "roles": []string{"other"}}
+	require.Equal(t, http.StatusForbidden, server.signIn(nil).Code)
+
+	_, err = userRepository.GetByUsername(context.Background(), "guest@library.test")
+	require.Error(t, err)
+
+	// Names of API key principals can not be taken
+	idp.claims = map[string]interface{}{"email": constant.APIKeyUsernamePrefix + "0123456789ab", "roles": "library-admins"}
+	require.Equal(t, http.StatusUnauthorized, server.signIn(nil).Code)
+
+	idp.claims = map[string]interface{}{"roles": "library-staff"}
+	require.Equal(t, http.StatusUnauthorized, server.signIn(nil).Code)
+
+	idp.claims = map[string]interface{}{"sub": "other-subject", "email": "other@library.test", "roles": "library-staff"}
+
+	tests := []struct {
+		name           string
+		setup          func()
+		mutate         func(callback url.Values, cookie *http.Cookie)
+		expectedStatus int
+	}{
+		{
+			name:           "forged state",
+			mutate:         func(callback url.Values, cookie *http.Cookie) { callback.Set("state", "forged") },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no state cookie",
+			mutate:         func(callback url.Values, cookie *http.Cookie) { cookie.Value = "" },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong code verifier",
+			mutate: func(callback url.Values, cookie *http.Cookie) {
+				state, ok := oidc.ParseState(cookie.Value)
+				require.True(t, ok)
+				state.Verifier = "wrong"
+				cookie.Value = state.String()
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown code",
+			mutate:         func(callback url.Values, cookie *http.Cookie) { callback.Set("code", "unknown") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "sign-in refused by the provider",
+			mutate:         func(callback url.Values, cookie *http.Cookie) { callback.Set("error", "access_denied") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "replayed nonce",
+			setup:          func() { idp.nonce = "replayed" },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token for another client",
+			setup:          func() { idp.audience = "another-client" },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token signed by an unknown key",
+			setup: func() {
+				idp.signWith, err = jwtrsa.NewRing(jwtrsa.Source{PrivateKey: string(generatePrivateKey(t))})
+				require.NoError(t, err)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		idp.nonce, idp.audience, idp.signWith = "", "", nil
+
+		if tt.setup != nil {
+			tt.setup()
+		}
+
+		rec := server.signIn(tt.mutate)
+		require.Equal(t, tt.expectedStatus, rec.Code, tt.name+": "+rec.Body.String())
+	}
+
+	// A code can only be exchanged once
+	idp.nonce, idp.audience, idp.signWith = "", "", nil
+
+	var replayed url.Values
+	require.Equal(t, http.StatusOK, server.signIn(func(callback url.Values, cookie *http.Cookie) { replayed = callback }).Code)
+
+	// The callback needs the state of a sign-in, so replay with a fresh one
+	rec = server.signIn(func(callback url.Values, cookie *http.Cookie) { callback.Set("code", replayed.Get("code")) })
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Without a client the endpoints are not there
+	oidc.SetDefault(nil)
+	require.Equal(t, http.StatusNotFound, server.do(http.MethodGet, "/auth/oidc/login", "", nil).Code)
+
+}
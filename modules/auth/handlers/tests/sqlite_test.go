@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/usecases"
+	userModel "github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	userRepo "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases/dbtest"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+type sessionServer struct {
+	t *testing.T
+	e *echo.Echo
+}
+
+func (s sessionServer) do(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s sessionServer) tokens(rec *httptest.ResponseRecorder) models.AuthResponse {
+	require.Equal(s.t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var body struct {
+		Data models.AuthResponse `json:"data"`
+	}
+
+	require.NoError(s.t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.NotEmpty(s.t, body.Data.RefreshToken)
+
+	return body.Data
+}
+
+func (s sessionServer) login() models.AuthResponse {
+	return s.tokens(s.do(http.MethodPost, "/auth/login", `{"username":"test","password":"test"}`, nil))
+}
+
+func (s sessionServer) refresh(token string) *httptest.ResponseRecorder {
+	return s.do(http.MethodPost, "/auth/refresh", fmt.Sprintf(`{"refresh_token":%q}`, token), nil)
+}
+
+func (s sessionServer) protected(accessToken string) int {
+	return s.do(http.MethodGet, "/protected", "", map[string]string{echo.HeaderAuthorization: "Bearer " + accessToken}).Code
+}
+
+// loginFailures returns the failed sign-ins counted for reason.
+func loginFailures(t *testing.T, reason string) float64 {
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "perpustakaan_auth_login_failures_total" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "reason" && label.GetValue() == reason {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestSessionsOnSQLite(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.NoError(t, err)
+
+	publicKey, err := os.ReadFile(publicKeyPath)
+	require.NoError(t, err)
+
+	config.Config().PrivateKey = string(privateKey)
+	config.Config().PublicKey = string(publicKey)
+	config.Config().BasicAuthUsername = "admin"
+	config.Config().BasicAuthPassword = "admin"
+	config.Config().BasicAuthRole = constant.SuperAdmin
+
+	list := revocation.New(db)
+	revocation.SetDefault(list)
+	t.Cleanup(func() { revocation.SetDefault(nil) })
+
+	userRepository := userRepo.NewUserRepository(db)
+	_, err = userRepository.Add(context.Background(), userModel.User{Username: "test", Password: utils.HashPassword("test"), Role: constant.Karyawan})
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	e.GET("/protected", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, middlewares.VerifyJWTRSA(config.Config().PublicKey))
+	handlers.NewAuthHandler(e, usecases.NewAuthUsecase(userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewTwoFactorRepository(db), repositories.NewIdentityRepository(db), list, lockout.New(config.Config().LoginPolicy()), databases.NewUnitOfWork(db)))
+
+	server := sessionServer{t: t, e: e}
+
+	// Refreshing rotates the refresh token
+	first := server.login()
+	require.Equal(t, http.StatusOK, server.protected(first.AccessToken))
+
+	second := server.tokens(server.refresh(first.RefreshToken))
+	require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	require.Equal(t, http.StatusOK, server.protected(second.AccessToken))
+
+	// Using a rotated token again revokes the whole sign-in
+	require.Equal(t, http.StatusUnauthorized, server.refresh(first.RefreshToken).Code)
+	require.Equal(t, http.StatusUnauthorized, server.refresh(second.RefreshToken).Code)
+	require.Equal(t, http.StatusUnauthorized, server.protected(first.AccessToken))
+	require.Equal(t, http.StatusUnauthorized, server.protected(second.AccessToken))
+
+	// Logging out revokes the access and refresh token
+	third := server.login()
+	other := server.login()
+	rec := server.do(http.MethodPost, "/auth/logout", "", map[string]string{echo.HeaderAuthorization: "Bearer " + third.AccessToken})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, http.StatusUnauthorized, server.protected(third.AccessToken))
+	require.Equal(t, http.StatusUnauthorized, server.refresh(third.RefreshToken).Code)
+	require.Equal(t, http.StatusOK, server.protected(other.AccessToken))
+
+	// An admin can end every session of a user
+	rec = server.do(http.MethodDelete, "/auth/sessions/test", "", map[string]string{echo.HeaderAuthorization: "Basic YWRtaW46YWRtaW4="})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, http.StatusUnauthorized, server.protected(other.AccessToken))
+	require.Equal(t, http.StatusUnauthorized, server.refresh(other.RefreshToken).Code)
+
+	// Failed sign-ins are counted by reason, a quick retry is throttled
+	invalid, throttled := loginFailures(t, "invalid_login"), loginFailures(t, "throttled")
+	require.Equal(t, http.StatusUnauthorized, server.do(http.MethodPost, "/auth/login", `{"username":"test","password":"wrong"}`, nil).Code)
+	require.Equal(t, http.StatusTooManyRequests, server.do(http.MethodPost, "/auth/login", `{"username":"test","password":"wrong"}`, nil).Code)
+	require.Equal(t, invalid+1, loginFailures(t, "invalid_login"))
+	require.Equal(t, throttled+1, loginFailures(t, "throttled"))
+
+	// Expired tokens are purged
+	result := <-usecases.NewAuthUsecase(userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewTwoFactorRepository(db), repositories.NewIdentityRepository(db), list, lockout.New(config.Config().LoginPolicy()), databases.NewUnitOfWork(db)).PurgeExpired(context.Background())
+	require.Nil(t, result.Error)
+}
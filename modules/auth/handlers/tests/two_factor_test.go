@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/usecases"
+	userModel "github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	userRepo "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases/dbtest"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/totp"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, data interface{}) {
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &struct {
+		Data interface{} `json:"data"`
+	}{Data: data}))
+}
+
+func (s sessionServer) challenge(username string) models.LoginChallengeResponse {
+	var challenge models.LoginChallengeResponse
+	decodeData(s.t, s.do(http.MethodPost, "/auth/login", fmt.Sprintf(`{"username":%q,"password":"test"}`, username), nil), &challenge)
+	require.True(s.t, challenge.TwoFactorRequired)
+	require.NotEmpty(s.t, challenge.ChallengeToken)
+
+	return challenge
+}
+
+func (s sessionServer) secondFactor(challengeToken, field, code string) *httptest.ResponseRecorder {
+	return s.do(http.MethodPost, "/auth/login/2fa", fmt.Sprintf(`{"challenge_token":%q,%q:%q}`, challengeToken, field, code), nil)
+}
+
+func code(t *testing.T, secret string, at time.Time) string {
+	value, err := totp.Code(secret, at)
+	require.NoError(t, err)
+
+	return value
+}
+
+func TestTwoFactorOnSQLite(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.NoError(t, err)
+
+	publicKey, err := os.ReadFile(publicKeyPath)
+	require.NoError(t, err)
+
+	config.Config().PrivateKey = string(privateKey)
+	config.Config().PublicKey = string(publicKey)
+	config.Config().TwoFactorRoles = ""
+
+	userRepository := userRepo.NewUserRepository(db)
+
+	for username, role := range map[string]string{"admin": constant.Admin, "staff": constant.Karyawan} {
+		_, err = userRepository.Add(context.Background(), userModel.User{Username: username, Password: utils.HashPassword("test"), Role: role})
+		require.NoError(t, err)
+	}
+
+	// Wrong codes count as failed sign-ins; the flow below stays clear of
+	// the lockout, which TestWrongSecondFactorCodesLock covers
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	handlers.NewAuthHandler(e, usecases.NewAuthUsecase(userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewTwoFactorRepository(db), repositories.NewIdentityRepository(db), revocation.New(db), lockout.New(lockout.Policy{MaxAttempts: 100, Lockout: time.Minute}), databases.NewUnitOfWork(db)))
+
+	server := sessionServer{t: t, e: e}
+	now := time.Now()
+
+	// A role that requires two-factor has to enroll while signing in
+	challenge := server.challenge("admin")
+	require.True(t, challenge.EnrollmentRequired)
+
+	var enrollment models.TwoFactorEnrollment
+	decodeData(t, server.do(http.MethodPost, "/auth/login/2fa/enroll", fmt.Sprintf(`{"challenge_token":%q}`, challenge.ChallengeToken), nil), &enrollment)
+	require.Contains(t, enrollment.URI, "otpauth://totp/")
+
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "code", "000000").Code)
+
+	first := server.tokens(server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now)))
+	require.Len(t, first.RecoveryCodes, constant.RecoveryCodeCount)
+
+	// The challenge is used up and a code is only accepted once
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now)).Code)
+
+	challenge = server.challenge("admin")
+	require.False(t, challenge.EnrollmentRequired)
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now)).Code)
+
+	second := server.tokens(server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now.Add(totp.Period))))
+	require.Empty(t, second.RecoveryCodes)
+
+	// Recovery codes work once each
+	challenge = server.challenge("admin")
+	server.tokens(server.secondFactor(challenge.ChallengeToken, "recovery_code", first.RecoveryCodes[0]))
+
+	challenge = server.challenge("admin")
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "recovery_code", first.RecoveryCodes[0]).Code)
+
+	// Too many wrong codes drop the challenge
+	for i := 1; i < constant.MaxChallengeAttempts; i++ {
+		require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "code", "000000").Code)
+	}
+
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "recovery_code", first.RecoveryCodes[1]).Code)
+
+	// Other roles sign in with a password until they enroll themselves
+	staff := server.tokens(server.do(http.MethodPost, "/auth/login", `{"username":"staff","password":"test"}`, nil))
+	bearer := map[string]string{echo.HeaderAuthorization: "Bearer " + staff.AccessToken}
+
+	decodeData(t, server.do(http.MethodPost, "/auth/2fa/enroll", "", bearer), &enrollment)
+	require.Equal(t, http.StatusBadRequest, server.do(http.MethodPost, "/auth/2fa/confirm", `{"code":"000000"}`, bearer).Code)
+
+	var recoveryCodes models.TwoFactorRecoveryCodes
+	decodeData(t, server.do(http.MethodPost, "/auth/2fa/confirm", fmt.Sprintf(`{"code":%q}`, code(t, enrollment.Secret, now)), bearer), &recoveryCodes)
+	require.Len(t, recoveryCodes.RecoveryCodes, constant.RecoveryCodeCount)
+	require.Equal(t, http.StatusConflict, server.do(http.MethodPost, "/auth/2fa/enroll", "", bearer).Code)
+
+	challenge = server.challenge("staff")
+	require.False(t, challenge.EnrollmentRequired)
+	server.tokens(server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now.Add(totp.Period))))
+}
+
+func TestWrongSecondFactorCodesLock(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+
+	privateKey, err := os.ReadFile(privateKeyPath)
+	require.NoError(t, err)
+
+	publicKey, err := os.ReadFile(publicKeyPath)
+	require.NoError(t, err)
+
+	config.Config().PrivateKey = string(privateKey)
+	config.Config().PublicKey = string(publicKey)
+	config.Config().TwoFactorRoles = ""
+
+	userRepository := userRepo.NewUserRepository(db)
+
+	_, err = userRepository.Add(context.Background(), userModel.User{Username: "admin", Password: utils.HashPassword("test"), Role: constant.Admin})
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	handlers.NewAuthHandler(e, usecases.NewAuthUsecase(userRepository, repositories.NewRefreshTokenRepository(db), repositories.NewTwoFactorRepository(db), repositories.NewIdentityRepository(db), revocation.New(db), lockout.New(lockout.Policy{MaxAttempts: 3, Lockout: time.Minute}), databases.NewUnitOfWork(db)))
+
+	server := sessionServer{t: t, e: e}
+	now := time.Now()
+
+	challenge := server.challenge("admin")
+
+	var enrollment models.TwoFactorEnrollment
+	decodeData(t, server.do(http.MethodPost, "/auth/login/2fa/enroll", fmt.Sprintf(`{"challenge_token":%q}`, challenge.ChallengeToken), nil), &enrollment)
+	server.tokens(server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now)))
+
+	// The right password alone does not forget the failures before it
+	require.Equal(t, http.StatusUnauthorized, server.do(http.MethodPost, "/auth/login", `{"username":"admin","password":"wrong"}`, nil).Code)
+
+	challenge = server.challenge("admin")
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "code", "000000").Code)
+	require.Equal(t, http.StatusLocked, server.secondFactor(challenge.ChallengeToken, "code", "000000").Code)
+
+	// The lock drops the challenge and refuses the password
+	require.Equal(t, http.StatusUnauthorized, server.secondFactor(challenge.ChallengeToken, "code", code(t, enrollment.Secret, now.Add(totp.Period))).Code)
+	require.Equal(t, http.StatusLocked, server.do(http.MethodPost, "/auth/login", `{"username":"admin","password":"test"}`, nil).Code)
+}
@@ -2,6 +2,7 @@ package models
 
 type AccessTokenClaims struct {
 	Aud      string `claim:"aud"`
+	Jti      string `claim:"jti"`
 	Username string `claim:"username"`
 	Role     string `claim:"role"`
 	Exp      int    `claim:"exp"`
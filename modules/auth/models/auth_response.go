@@ -1,7 +1,10 @@
 package models
 
 type AuthResponse struct {
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
-	AccessToken string `json:"access_token"`
+	TokenType        string   `json:"token_type"`
+	ExpiresIn        int      `json:"expires_in"`
+	AccessToken      string   `json:"access_token"`
+	RefreshExpiresIn int      `json:"refresh_expires_in"`
+	RefreshToken     string   `json:"refresh_token"`
+	RecoveryCodes    []string `json:"recovery_codes,omitempty"`
 }
@@ -1,6 +1,9 @@
 package models
 
+// LoginAuth is a sign-in with a password. IP is the client address the
+// failed attempts are also counted for.
 type LoginAuth struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	IP       string `json:"-"`
 }
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// LogoutAuth is the access token being signed out, read from its claims.
+type LogoutAuth struct {
+	JTI       string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/sdk/oidc"
+
+// OIDCCallback is what the identity provider sends the browser back with.
+type OIDCCallback struct {
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
+
+// LoginOIDC is a sign-in the identity provider vouches for with Code. State
+// is what the sign-in was started with. IP is the client address the
+// sign-in is throttled by.
+type LoginOIDC struct {
+	Code  string
+	State oidc.State
+	IP    string
+}
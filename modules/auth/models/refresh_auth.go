@@ -0,0 +1,5 @@
+package models
+
+type RefreshAuth struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
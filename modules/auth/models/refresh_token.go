@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RefreshToken is a refresh token handed out with an access token. Only the
+// hash of the token is stored. Tokens rotate on use: refreshing revokes the
+// token and issues a new one in the same family, so a revoked token coming
+// back means it was stolen and the whole family is revoked.
+type RefreshToken struct {
+	ID              int64      `json:"id" gorm:"primaryKey"`
+	TokenHash       string     `json:"-"`
+	Family          string     `json:"family"`
+	Username        string     `json:"username"`
+	AccessJTI       string     `json:"access_jti" gorm:"column:access_jti"`
+	AccessExpiresAt time.Time  `json:"access_expires_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+type RefreshTokenFilter struct {
+	Family    string
+	Username  string
+	AccessJTI string
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// UserTOTP is the TOTP secret of a user. It is pending until confirmed with
+// a code, and only a confirmed secret is asked for at sign-in. LastStep is
+// the last time step a code was accepted for, so codes can not be replayed.
+type UserTOTP struct {
+	Username    string     `json:"username" gorm:"primaryKey"`
+	Secret      string     `json:"-"`
+	LastStep    int64      `json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode is a one-time code that signs in instead of a TOTP code.
+// Only the hash of the code is stored.
+type RecoveryCode struct {
+	ID        int64      `json:"id" gorm:"primaryKey"`
+	Username  string     `json:"username"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_code"
+}
+
+// LoginChallenge is a sign-in whose password was right and which waits for
+// a second factor. Only the hash of the challenge token is stored.
+type LoginChallenge struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	TokenHash string    `json:"-"`
+	Username  string    `json:"username"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (LoginChallenge) TableName() string {
+	return "login_challenge"
+}
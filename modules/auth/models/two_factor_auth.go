@@ -0,0 +1,37 @@
+package models
+
+// LoginTwoFactor completes a sign-in with either a TOTP code or a recovery
+// code. IP is the client address wrong codes are also counted for.
+type LoginTwoFactor struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code"`
+	IP             string `json:"-"`
+}
+
+type LoginChallengeAuth struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// LoginChallengeResponse is returned by a sign-in that needs a second
+// factor. With EnrollmentRequired the user has to enroll a TOTP secret
+// first, which the challenge token allows.
+type LoginChallengeResponse struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserIdentity links the account an OpenID Connect issuer knows by Subject
+// to a user. Provisioned is set on users the sign-in added, only their
+// role follows the groups at the provider.
+type UserIdentity struct {
+	ID          int64     `json:"id" gorm:"primaryKey"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	UserID      int64     `json:"user_id"`
+	Username    string    `json:"username"`
+	Provisioned bool      `json:"provisioned"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+// OIDCLink is the account at the identity provider to link a user to.
+type OIDCLink struct {
+	Subject string `json:"subject" validate:"required"`
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"gorm.io/gorm"
+)
+
+func buildFilterQuery(db *gorm.DB, filter models.RefreshTokenFilter) *gorm.DB {
+	if filter.Family != "" {
+		db = db.Where("family = ?", filter.Family)
+	}
+
+	if filter.Username != "" {
+		db = db.Where("username = ?", filter.Username)
+	}
+
+	if filter.AccessJTI != "" {
+		db = db.Where("access_jti = ?", filter.AccessJTI)
+	}
+
+	return db
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+// Add implements domain.IdentityRepository.
+func (r *identityRepository) Add(ctx context.Context, data models.UserIdentity) (result models.UserIdentity, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// Delete implements domain.IdentityRepository.
+func (r *identityRepository) Delete(ctx context.Context, id int64) error {
+	return databases.Conn(ctx, r.db).Where("id = ?", id).Delete(&models.UserIdentity{}).Error
+}
+
+// GetBySubject implements domain.IdentityRepository.
+func (r *identityRepository) GetBySubject(ctx context.Context, issuer, subject string) (result models.UserIdentity, err error) {
+	err = databases.Conn(ctx, r.db).Where("issuer = ? AND subject = ?", issuer, subject).First(&result).Error
+	return
+}
+
+func NewIdentityRepository(db *gorm.DB) domain.IdentityRepository {
+	return &identityRepository{db: db}
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// Add implements domain.RefreshTokenRepository.
+func (r *refreshTokenRepository) Add(ctx context.Context, data models.RefreshToken) (result models.RefreshToken, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// DeleteExpired implements domain.RefreshTokenRepository. Tokens are kept
+// until the access token issued with them has expired too.
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := databases.Conn(ctx, r.db).Where("expires_at < ? AND access_expires_at < ?", before, before).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+// GetByTokenHash implements domain.RefreshTokenRepository. The token stays
+// locked until the surrounding transaction ends, so it is rotated once.
+func (r *refreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (result models.RefreshToken, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("token_hash = ?", tokenHash).First(&result).Error
+	return
+}
+
+// GetLive implements domain.RefreshTokenRepository. A token is live while
+// it can be refreshed or the access token issued with it is still valid.
+func (r *refreshTokenRepository) GetLive(ctx context.Context, filter models.RefreshTokenFilter, now time.Time) (result []models.RefreshToken, err error) {
+	db := buildFilterQuery(databases.Conn(ctx, r.db), filter)
+	err = db.Where("((revoked_at IS NULL AND expires_at > ?) OR access_expires_at > ?)", now, now).Find(&result).Error
+	return
+}
+
+// Revoke implements domain.RefreshTokenRepository.
+func (r *refreshTokenRepository) Revoke(ctx context.Context, filter models.RefreshTokenFilter, at time.Time) error {
+	db := buildFilterQuery(databases.Conn(ctx, r.db), filter)
+	return db.Model(&models.RefreshToken{}).Where("revoked_at IS NULL").Update("revoked_at", at).Error
+}
+
+// Update implements domain.RefreshTokenRepository.
+func (r *refreshTokenRepository) Update(ctx context.Context, data models.RefreshToken) (result models.RefreshToken, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) domain.RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
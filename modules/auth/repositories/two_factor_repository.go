@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+// AddChallenge implements domain.TwoFactorRepository.
+func (r *twoFactorRepository) AddChallenge(ctx context.Context, data models.LoginChallenge) (result models.LoginChallenge, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// DeleteChallenge implements domain.TwoFactorRepository.
+func (r *twoFactorRepository) DeleteChallenge(ctx context.Context, id int64) error {
+	return databases.Conn(ctx, r.db).Where("id = ?", id).Delete(&models.LoginChallenge{}).Error
+}
+
+// DeleteExpiredChallenges implements domain.TwoFactorRepository.
+func (r *twoFactorRepository) DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error) {
+	result := databases.Conn(ctx, r.db).Where("expires_at < ?", before).Delete(&models.LoginChallenge{})
+	return result.RowsAffected, result.Error
+}
+
+// GetChallenge implements domain.TwoFactorRepository. The challenge stays
+// locked until the surrounding transaction ends, so its attempts are counted
+// one at a time.
+func (r *twoFactorRepository) GetChallenge(ctx context.Context, tokenHash string) (result models.LoginChallenge, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("token_hash = ?", tokenHash).First(&result).Error
+	return
+}
+
+// UpdateChallenge implements domain.TwoFactorRepository.
+func (r *twoFactorRepository) UpdateChallenge(ctx context.Context, data models.LoginChallenge) (result models.LoginChallenge, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+// GetTOTP implements domain.TwoFactorRepository. The secret stays locked
+// until the surrounding transaction ends, so a code is accepted once.
+func (r *twoFactorRepository) GetTOTP(ctx context.Context, username string) (result models.UserTOTP, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("username = ?", username).First(&result).Error
+	return
+}
+
+// SaveTOTP implements domain.TwoFactorRepository. It adds the secret of a
+// user or replaces it.
+func (r *twoFactorRepository) SaveTOTP(ctx context.Context, data models.UserTOTP) (result models.UserTOTP, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+// ReplaceRecoveryCodes implements domain.TwoFactorRepository. Codes handed
+// out before are dropped.
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, username string, codes []models.RecoveryCode) error {
+	db := databases.Conn(ctx, r.db)
+
+	if err := db.Where("username = ?", username).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	return db.Create(&codes).Error
+}
+
+// UseRecoveryCode implements domain.TwoFactorRepository. It reports whether
+// an unused code matched, which is used up.
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, username, codeHash string, at time.Time) (bool, error) {
+	result := databases.Conn(ctx, r.db).Model(&models.RecoveryCode{}).
+		Where("username = ? AND code_hash = ? AND used_at IS NULL", username, codeHash).
+		Update("used_at", at)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func NewTwoFactorRepository(db *gorm.DB) domain.TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
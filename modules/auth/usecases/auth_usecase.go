@@ -12,67 +12,348 @@ import (
 	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
 	userDomain "github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	userModel "github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/lockout"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/revocation"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 	"gorm.io/gorm"
 )
 
 type authUsecase struct {
-	userRepository userDomain.UserRepository
+	userRepository         userDomain.UserRepository
+	refreshTokenRepository domain.RefreshTokenRepository
+	revocationList         revocation.List
+	twoFactorRepository    domain.TwoFactorRepository
+	identityRepository     domain.IdentityRepository
+	loginTracker           lockout.Tracker
+	unitOfWork             databases.UnitOfWork
 }
 
-// AuthWithPassword implements domain.AuthUsecase.
+// AuthWithPassword implements domain.AuthUsecase. Users with two-factor
+// get a models.LoginChallengeResponse to finish signing in with instead of
+// an access token.
 func (u *authUsecase) AuthWithPassword(ctx context.Context, authReq models.LoginAuth) <-chan utils.Result {
 	output := make(chan utils.Result)
 
 	go func() {
 		defer close(output)
 
+		if decision := u.loginTracker.Check(authReq.Username, authReq.IP, time.Now()); !decision.Allowed() {
+			output <- utils.Result{Error: throttleError(decision)}
+			return
+		}
+
 		user, err := u.userRepository.GetByUsername(ctx, authReq.Username)
 
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				output <- utils.Result{Error: httperror.NewUnauthorized(httperror.InvalidLoginMsg)}
-				return
-			} else {
-				output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if err != nil || user.Username != authReq.Username || !u.verifyPassword(authReq.Password, user.Password) {
+			if decision := u.loginTracker.Fail(authReq.Username, authReq.IP, time.Now()); decision.Locked {
+				output <- utils.Result{Error: throttleError(decision)}
 				return
 			}
-		} else if user.Username != authReq.Username {
+
+			loginFailures.WithLabelValues(failureInvalidLogin).Inc()
 			output <- utils.Result{Error: httperror.NewUnauthorized(httperror.InvalidLoginMsg)}
 			return
 		}
 
-		if !u.verifyPassword(authReq.Password, user.Password) {
-			output <- utils.Result{Error: httperror.NewUnauthorized(httperror.InvalidLoginMsg)}
+		// Hashes made before the bcrypt cost changed are redone while the
+		// password is at hand
+		if utils.PasswordNeedsRehash(user.Password) {
+			user.Password = utils.HashPassword(authReq.Password)
+
+			if _, err = u.userRepository.Update(ctx, user); err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+		}
+
+		challenge, err := u.challenge(ctx, user, config.Config().TwoFactorRequired(user.Role))
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		authResponse, err := u.createAuthResponse(ctx, user)
+		if challenge != nil {
+			output <- utils.Result{Data: *challenge}
+			return
+		}
+
+		authResponse, err := u.createAuthResponse(ctx, user, "")
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		u.loginTracker.Succeed(authReq.Username)
+
+		output <- utils.Result{Data: authResponse}
+
+	}()
+
+	return output
+}
+
+// Refresh implements domain.AuthUsecase. The refresh token is exchanged
+// for a new access and refresh token and can not be used again. Using it
+// again revokes every token issued since the sign-in it came from.
+func (u *authUsecase) Refresh(ctx context.Context, refreshReq models.RefreshAuth) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var (
+			authResponse models.AuthResponse
+			reused       bool
+		)
+
+		// A reused token ends the transaction without an error, so that the
+		// revocation of its family is committed before the caller is refused.
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			token, err := u.refreshTokenRepository.GetByTokenHash(ctx, utils.HashToken(refreshReq.RefreshToken))
+
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return httperror.Unauthorized(httperror.InvalidRefreshTokenMsg)
+				}
+
+				return httperror.FromError(err)
+			}
+
+			now := time.Now()
+
+			if token.RevokedAt != nil {
+				if err := u.revokeSessions(ctx, models.RefreshTokenFilter{Family: token.Family}, now); err != nil {
+					return httperror.FromError(err)
+				}
+
+				reused = true
+				return nil
+			}
+
+			if !now.Before(token.ExpiresAt) {
+				return httperror.Unauthorized(httperror.InvalidRefreshTokenMsg)
+			}
+
+			user, err := u.userRepository.GetByUsername(ctx, token.Username)
+
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return httperror.Unauthorized(httperror.InvalidRefreshTokenMsg)
+				}
+
+				return httperror.FromError(err)
+			}
+
+			token.RevokedAt = &now
+
+			if _, err := u.refreshTokenRepository.Update(ctx, token); err != nil {
+				return httperror.FromError(err)
+			}
+
+			authResponse, err = u.createAuthResponse(ctx, user, token.Family)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			return nil
+		})
+
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if reused {
+			output <- utils.Result{Error: httperror.Unauthorized(httperror.RefreshTokenReusedMsg)}
 			return
 		}
 
 		output <- utils.Result{Data: authResponse}
+	}()
 
+	return output
+}
+
+// Logout implements domain.AuthUsecase. The access token and the refresh
+// tokens of its sign-in are revoked.
+func (u *authUsecase) Logout(ctx context.Context, logout models.LogoutAuth) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			now := time.Now()
+
+			if err := u.revocationList.Revoke(ctx, logout.JTI, logout.ExpiresAt); err != nil {
+				return err
+			}
+
+			tokens, err := u.refreshTokenRepository.GetLive(ctx, models.RefreshTokenFilter{AccessJTI: logout.JTI}, now)
+
+			if err != nil {
+				return err
+			}
+
+			for _, token := range tokens {
+				if err := u.revokeSessions(ctx, models.RefreshTokenFilter{Family: token.Family}, now); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{}
 	}()
 
 	return output
 }
 
+// RevokeSessions implements domain.AuthUsecase. Every access and refresh
+// token of username stops working, e.g. when a member of staff leaves.
+func (u *authUsecase) RevokeSessions(ctx context.Context, username string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			return u.revokeSessions(ctx, models.RefreshTokenFilter{Username: username}, time.Now())
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{}
+	}()
+
+	return output
+}
+
+// PurgeExpired implements domain.AuthUsecase. It drops expired refresh
+// tokens, revocations and sign-in challenges, which are refused without
+// being looked up, and failed sign-ins that no longer count.
+func (u *authUsecase) PurgeExpired(ctx context.Context) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		tokens, err := u.refreshTokenRepository.DeleteExpired(ctx, time.Now())
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		revoked, err := u.revocationList.Purge(ctx)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		challenges, err := u.twoFactorRepository.DeleteExpiredChallenges(ctx, time.Now())
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		attempts := u.loginTracker.Purge(time.Now())
+
+		output <- utils.Result{Total: tokens + revoked + challenges + int64(attempts)}
+	}()
+
+	return output
+}
+
+// Unlock implements domain.AuthUsecase. It forgets the failed sign-ins of
+// username, lifting its lockout.
+func (u *authUsecase) Unlock(ctx context.Context, username string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		u.loginTracker.Unlock(username)
+
+		output <- utils.Result{Data: username}
+	}()
+
+	return output
+}
+
+// throttleError counts and returns the error refusing a sign-in the
+// tracker does not allow.
+func throttleError(decision lockout.Decision) error {
+	if decision.Locked {
+		loginFailures.WithLabelValues(failureLocked).Inc()
+		return httperror.Locked(httperror.AccountLockedMsg, decision.RetryAfter)
+	}
+
+	loginFailures.WithLabelValues(failureThrottled).Inc()
+	return httperror.TooManyRequests(httperror.TooManyAttemptsMsg, decision.RetryAfter)
+}
+
+// revokeSessions revokes the refresh tokens matching filter and the access
+// tokens issued with them that have not expired yet.
+func (u *authUsecase) revokeSessions(ctx context.Context, filter models.RefreshTokenFilter, now time.Time) error {
+	tokens, err := u.refreshTokenRepository.GetLive(ctx, filter, now)
+
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if !token.AccessExpiresAt.After(now) {
+			continue
+		}
+
+		if err := u.revocationList.Revoke(ctx, token.AccessJTI, token.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	return u.refreshTokenRepository.Revoke(ctx, filter, now)
+}
+
 // verifyPassword implements domain.AuthUsecase.
 func (u *authUsecase) verifyPassword(password string, hash string) bool {
 	err := utils.CheckPasswordHash(password, hash)
 	return err
 }
 
-func (u *authUsecase) createAccessToken(ctx context.Context, user userModel.User, accessTokenTTL time.Duration) (accessToken string, err error) {
+func (u *authUsecase) createAccessToken(ctx context.Context, user userModel.User, jti string, accessTokenTTL time.Duration) (accessToken string, expiresAt time.Time, err error) {
 	userIDStr := strconv.Itoa(int(user.ID))
 
 	accessTokenClaims := models.AccessTokenClaims{
 		Aud:      userIDStr,
+		Jti:      jti,
 		Username: user.Username,
 		Role:     user.Role,
 	}
@@ -85,18 +366,62 @@ func (u *authUsecase) createAccessToken(ctx context.Context, user userModel.User
 		TimeExpire: accessTokenTTL,
 	}
 
-	accessToken, _, err = jwtrsa.GenerateJWT(inputJWT)
-	return
+	if ring := jwtrsa.Default(); ring != nil {
+		return ring.GenerateJWT(inputJWT)
+	}
+
+	return jwtrsa.GenerateJWT(inputJWT)
 }
 
-func (u *authUsecase) createAuthResponse(ctx context.Context, user userModel.User) (token models.AuthResponse, err error) {
-	accessTokenTTL := 2 * time.Hour
+// createAuthResponse issues an access token and a refresh token. The
+// refresh token joins family, or starts a new one when family is empty.
+func (u *authUsecase) createAuthResponse(ctx context.Context, user userModel.User, family string) (token models.AuthResponse, err error) {
+	jti, err := utils.GenerateToken(16)
 
-	accessToken, err := u.createAccessToken(ctx, user, accessTokenTTL)
+	if err != nil {
+		return
+	}
 
-	token.TokenType = "Bearer"
+	accessToken, accessExpiresAt, err := u.createAccessToken(ctx, user, jti, constant.AccessTokenTTL)
+
+	if err != nil {
+		return
+	}
+
+	refreshToken, err := utils.GenerateToken(32)
+
+	if err != nil {
+		return
+	}
+
+	if family == "" {
+		if family, err = utils.GenerateToken(16); err != nil {
+			return
+		}
+	}
+
+	now := time.Now()
+	refreshTokenTTL := config.Config().RefreshTokenTTL()
+
+	_, err = u.refreshTokenRepository.Add(ctx, models.RefreshToken{
+		TokenHash:       utils.HashToken(refreshToken),
+		Family:          family,
+		Username:        user.Username,
+		AccessJTI:       jti,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       now.Add(refreshTokenTTL),
+		CreatedAt:       now,
+	})
+
+	if err != nil {
+		return
+	}
+
+	token.TokenType = constant.TokenType
 	token.AccessToken = accessToken
-	token.ExpiresIn = int(accessTokenTTL.Minutes())
+	token.ExpiresIn = int(constant.AccessTokenTTL.Minutes())
+	token.RefreshToken = refreshToken
+	token.RefreshExpiresIn = int(refreshTokenTTL.Minutes())
 
 	return
 }
@@ -116,8 +441,16 @@ func generateMapClaims(claims interface{}) map[string]interface{} {
 	return result
 }
 
-func NewAuthUsecase(userRepository userDomain.UserRepository) domain.AuthUsecase {
+func NewAuthUsecase(userRepository userDomain.UserRepository, refreshTokenRepository domain.RefreshTokenRepository, twoFactorRepository domain.TwoFactorRepository, identityRepository domain.IdentityRepository, revocationList revocation.List, loginTracker lockout.Tracker, unitOfWork databases.UnitOfWork) domain.AuthUsecase {
+	metrics.Register(loginFailures)
+
 	return &authUsecase{
-		userRepository: userRepository,
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		twoFactorRepository:    twoFactorRepository,
+		identityRepository:     identityRepository,
+		revocationList:         revocationList,
+		loginTracker:           loginTracker,
+		unitOfWork:             unitOfWork,
 	}
 }
@@ -0,0 +1,22 @@
+package usecases
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons a sign-in failed, as counted by loginFailures.
+const (
+	failureInvalidLogin = "invalid_login"
+	failureThrottled    = "throttled"
+	failureLocked       = "locked"
+	failureSecondFactor = "second_factor"
+	failureOIDC         = "oidc"
+)
+
+var loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "auth",
+	Name:      "login_failures_total",
+	Help:      "Failed sign-ins by reason.",
+}, []string{"reason"})
@@ -0,0 +1,220 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	userModel "github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/oidc"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// AuthWithOIDC implements domain.AuthUsecase. Users the identity provider
+// signs in are added on their first sign-in and get the role their groups
+// map to on every sign-in. They have no password. Locked accounts are
+// refused and users who enrolled a TOTP secret, or whose role requires
+// one, get a models.LoginChallengeResponse as with a password.
+func (u *authUsecase) AuthWithOIDC(ctx context.Context, authReq models.LoginOIDC) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		client := oidc.Default()
+
+		if client == nil {
+			output <- utils.Result{Error: httperror.NotFound(httperror.OIDCDisabledMsg)}
+			return
+		}
+
+		claims, err := client.Exchange(ctx, authReq.Code, authReq.State)
+
+		if errors.Is(err, oidc.ErrInvalidToken) || errors.Is(err, oidc.ErrCodeRefused) {
+			loginFailures.WithLabelValues(failureOIDC).Inc()
+			output <- utils.Result{Error: httperror.Unauthorized(httperror.OIDCLoginFailedMsg)}
+			return
+		}
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		identity, err := client.Identify(claims)
+
+		if errors.Is(err, oidc.ErrNoRole) {
+			loginFailures.WithLabelValues(failureOIDC).Inc()
+			output <- utils.Result{Error: httperror.Forbidden(httperror.OIDCNoRoleMsg)}
+			return
+		}
+
+		// Names of API keys can not be taken by a user
+		if err != nil || strings.HasPrefix(identity.Username, constant.APIKeyUsernamePrefix) {
+			loginFailures.WithLabelValues(failureOIDC).Inc()
+			output <- utils.Result{Error: httperror.Unauthorized(httperror.OIDCLoginFailedMsg)}
+			return
+		}
+
+		user, err := u.provision(ctx, identity)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		// The linked user may be named differently at the provider
+		if decision := u.loginTracker.Check(user.Username, authReq.IP, time.Now()); !decision.Allowed() {
+			output <- utils.Result{Error: throttleError(decision)}
+			return
+		}
+
+		challenge, err := u.challenge(ctx, user, config.Config().TwoFactorRequired(user.Role))
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if challenge != nil {
+			output <- utils.Result{Data: *challenge}
+			return
+		}
+
+		authResponse, err := u.createAuthResponse(ctx, user, "")
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: authResponse}
+	}()
+
+	return output
+}
+
+// LinkOIDC implements domain.AuthUsecase. The user can then sign in with
+// the account at the identity provider and keeps their role.
+func (u *authUsecase) LinkOIDC(ctx context.Context, username string, link models.OIDCLink) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		client := oidc.Default()
+
+		if client == nil {
+			output <- utils.Result{Error: httperror.NotFound(httperror.OIDCDisabledMsg)}
+			return
+		}
+
+		var identity models.UserIdentity
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			user, err := u.userRepository.GetByUsername(ctx, username)
+
+			if err != nil {
+				return err
+			}
+
+			_, err = u.identityRepository.GetBySubject(ctx, client.Issuer(), link.Subject)
+
+			if err == nil {
+				return httperror.Conflict(httperror.OIDCAlreadyLinkedMsg)
+			}
+
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			identity, err = u.identityRepository.Add(ctx, models.UserIdentity{
+				Issuer:   client.Issuer(),
+				Subject:  link.Subject,
+				UserID:   user.ID,
+				Username: user.Username,
+			})
+
+			return err
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: identity}
+	}()
+
+	return output
+}
+
+// provision returns the user identity signs in. Users are found by their
+// account at the provider, never by username, so a user that was not added
+// by a sign-in is only signed in once it is linked with LinkOIDC. Only the
+// role of users added by a sign-in follows their groups.
+func (u *authUsecase) provision(ctx context.Context, identity oidc.Identity) (user userModel.User, err error) {
+	err = u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		link, err := u.identityRepository.GetBySubject(ctx, identity.Issuer, identity.Subject)
+
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil {
+			user, err = u.userRepository.GetByUsername(ctx, link.Username)
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if err == nil && user.ID == link.UserID {
+				if !link.Provisioned || user.Role == identity.Role {
+					return nil
+				}
+
+				user.Role = identity.Role
+				user, err = u.userRepository.Update(ctx, user)
+
+				return err
+			}
+
+			// The user was removed, a user of the same name is someone else
+			if err = u.identityRepository.Delete(ctx, link.ID); err != nil {
+				return err
+			}
+		}
+
+		_, err = u.userRepository.GetByUsername(ctx, identity.Username)
+
+		if err == nil {
+			return httperror.Conflict(httperror.OIDCAccountExistsMsg)
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if user, err = u.userRepository.Add(ctx, userModel.User{Username: identity.Username, Role: identity.Role}); err != nil {
+			return err
+		}
+
+		_, err = u.identityRepository.Add(ctx, models.UserIdentity{
+			Issuer:      identity.Issuer,
+			Subject:     identity.Subject,
+			UserID:      user.ID,
+			Username:    user.Username,
+			Provisioned: true,
+		})
+
+		return err
+	})
+
+	return user, err
+}
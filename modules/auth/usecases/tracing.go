@@ -0,0 +1,92 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+// tracedAuthUsecase runs every call of a domain.AuthUsecase in a span, see tracing.Result.
+type tracedAuthUsecase struct {
+	next domain.AuthUsecase
+}
+
+// NewTracedAuthUsecase returns next with every call traced.
+func NewTracedAuthUsecase(next domain.AuthUsecase) domain.AuthUsecase {
+	return &tracedAuthUsecase{next: next}
+}
+
+// AuthWithOIDC implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) AuthWithOIDC(ctx context.Context, authReq models.LoginOIDC) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.AuthWithOIDC")
+	return tracing.Result(span, u.next.AuthWithOIDC(ctx, authReq))
+}
+
+// AuthWithPassword implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) AuthWithPassword(ctx context.Context, authReq models.LoginAuth) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.AuthWithPassword")
+	return tracing.Result(span, u.next.AuthWithPassword(ctx, authReq))
+}
+
+// AuthWithTwoFactor implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) AuthWithTwoFactor(ctx context.Context, authReq models.LoginTwoFactor) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.AuthWithTwoFactor")
+	return tracing.Result(span, u.next.AuthWithTwoFactor(ctx, authReq))
+}
+
+// ConfirmTwoFactor implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) ConfirmTwoFactor(ctx context.Context, username string, code models.TwoFactorCode) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.ConfirmTwoFactor")
+	return tracing.Result(span, u.next.ConfirmTwoFactor(ctx, username, code))
+}
+
+// EnrollTwoFactor implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) EnrollTwoFactor(ctx context.Context, username string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.EnrollTwoFactor")
+	return tracing.Result(span, u.next.EnrollTwoFactor(ctx, username))
+}
+
+// EnrollTwoFactorWithChallenge implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) EnrollTwoFactorWithChallenge(ctx context.Context, challenge models.LoginChallengeAuth) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.EnrollTwoFactorWithChallenge")
+	return tracing.Result(span, u.next.EnrollTwoFactorWithChallenge(ctx, challenge))
+}
+
+// LinkOIDC implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) LinkOIDC(ctx context.Context, username string, link models.OIDCLink) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.LinkOIDC")
+	return tracing.Result(span, u.next.LinkOIDC(ctx, username, link))
+}
+
+// Logout implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) Logout(ctx context.Context, logout models.LogoutAuth) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.Logout")
+	return tracing.Result(span, u.next.Logout(ctx, logout))
+}
+
+// PurgeExpired implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) PurgeExpired(ctx context.Context) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.PurgeExpired")
+	return tracing.Result(span, u.next.PurgeExpired(ctx))
+}
+
+// Refresh implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) Refresh(ctx context.Context, refreshReq models.RefreshAuth) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.Refresh")
+	return tracing.Result(span, u.next.Refresh(ctx, refreshReq))
+}
+
+// RevokeSessions implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) RevokeSessions(ctx context.Context, username string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.RevokeSessions")
+	return tracing.Result(span, u.next.RevokeSessions(ctx, username))
+}
+
+// Unlock implements domain.AuthUsecase.
+func (u *tracedAuthUsecase) Unlock(ctx context.Context, username string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.Unlock")
+	return tracing.Result(span, u.next.Unlock(ctx, username))
+}
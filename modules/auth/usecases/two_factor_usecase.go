@@ -0,0 +1,384 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/modules/auth/models"
+	userModel "github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/totp"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// AuthWithTwoFactor implements domain.AuthUsecase. It exchanges the
+// challenge of a sign-in and a TOTP or recovery code for an access token.
+// The first code of a pending secret confirms it and the recovery codes
+// are returned with the tokens. A challenge is dropped after too many wrong
+// codes. Wrong codes count as failed sign-ins, so they can lock the account;
+// the backoff between attempts is left to the password step.
+func (u *authUsecase) AuthWithTwoFactor(ctx context.Context, authReq models.LoginTwoFactor) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var (
+			response models.AuthResponse
+			username string
+			failure  error
+		)
+
+		now := time.Now()
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			challenge, err := u.getChallenge(ctx, authReq.ChallengeToken, now)
+
+			if err != nil {
+				return err
+			}
+
+			username = challenge.Username
+
+			if decision := u.loginTracker.Check(username, authReq.IP, now); decision.Locked {
+				return throttleError(decision)
+			}
+
+			secret, err := u.twoFactorRepository.GetTOTP(ctx, challenge.Username)
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if secret.Secret == "" {
+				return httperror.NewBadRequest(httperror.TwoFactorNotEnrolledMsg)
+			}
+
+			ok, err := u.verifySecondFactor(ctx, &secret, authReq, now)
+
+			if err != nil {
+				return err
+			}
+
+			// A wrong code is counted on the challenge, so it has to commit
+			if !ok {
+				if decision := u.loginTracker.Fail(username, authReq.IP, now); decision.Locked {
+					failure = throttleError(decision)
+					return u.twoFactorRepository.DeleteChallenge(ctx, challenge.ID)
+				}
+
+				failure = httperror.NewUnauthorized(httperror.InvalidTwoFactorCodeMsg)
+				loginFailures.WithLabelValues(failureSecondFactor).Inc()
+				challenge.Attempts++
+
+				if challenge.Attempts >= constant.MaxChallengeAttempts {
+					return u.twoFactorRepository.DeleteChallenge(ctx, challenge.ID)
+				}
+
+				_, err = u.twoFactorRepository.UpdateChallenge(ctx, challenge)
+				return err
+			}
+
+			if err = u.twoFactorRepository.DeleteChallenge(ctx, challenge.ID); err != nil {
+				return err
+			}
+
+			var recoveryCodes []string
+
+			if secret.ConfirmedAt == nil {
+				secret.ConfirmedAt = &now
+
+				if recoveryCodes, err = u.replaceRecoveryCodes(ctx, secret.Username, now); err != nil {
+					return err
+				}
+			}
+
+			if _, err = u.twoFactorRepository.SaveTOTP(ctx, secret); err != nil {
+				return err
+			}
+
+			user, err := u.userRepository.GetByUsername(ctx, challenge.Username)
+
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return httperror.NewUnauthorized(httperror.InvalidLoginMsg)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if response, err = u.createAuthResponse(ctx, user, ""); err != nil {
+				return err
+			}
+
+			response.RecoveryCodes = recoveryCodes
+
+			return nil
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if failure != nil {
+			output <- utils.Result{Error: failure}
+			return
+		}
+
+		u.loginTracker.Succeed(username)
+
+		output <- utils.Result{Data: response}
+	}()
+
+	return output
+}
+
+// EnrollTwoFactor implements domain.AuthUsecase. It creates a pending TOTP
+// secret for username, replacing one that was never confirmed.
+func (u *authUsecase) EnrollTwoFactor(ctx context.Context, username string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var enrollment models.TwoFactorEnrollment
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) (err error) {
+			enrollment, err = u.enroll(ctx, username, time.Now())
+			return
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: enrollment}
+	}()
+
+	return output
+}
+
+// EnrollTwoFactorWithChallenge implements domain.AuthUsecase. It lets a
+// user whose role requires two-factor enroll during sign-in, before they
+// have an access token. The challenge is not used up.
+func (u *authUsecase) EnrollTwoFactorWithChallenge(ctx context.Context, challengeReq models.LoginChallengeAuth) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var enrollment models.TwoFactorEnrollment
+
+		now := time.Now()
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			challenge, err := u.getChallenge(ctx, challengeReq.ChallengeToken, now)
+
+			if err != nil {
+				return err
+			}
+
+			enrollment, err = u.enroll(ctx, challenge.Username, now)
+			return err
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: enrollment}
+	}()
+
+	return output
+}
+
+// ConfirmTwoFactor implements domain.AuthUsecase. A valid code turns the
+// pending secret of username on and returns new recovery codes, which are
+// shown this once.
+func (u *authUsecase) ConfirmTwoFactor(ctx context.Context, username string, code models.TwoFactorCode) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var recoveryCodes []string
+
+		now := time.Now()
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			secret, err := u.twoFactorRepository.GetTOTP(ctx, username)
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if secret.Secret == "" {
+				return httperror.NewBadRequest(httperror.TwoFactorNotEnrolledMsg)
+			}
+
+			if secret.ConfirmedAt != nil {
+				return httperror.NewConflict(httperror.TwoFactorEnabledMsg)
+			}
+
+			step, ok := totp.Validate(secret.Secret, code.Code, now, constant.TOTPSkew, secret.LastStep)
+
+			if !ok {
+				return httperror.NewBadRequest(httperror.InvalidTwoFactorCodeMsg)
+			}
+
+			secret.LastStep = step
+			secret.ConfirmedAt = &now
+
+			if _, err = u.twoFactorRepository.SaveTOTP(ctx, secret); err != nil {
+				return err
+			}
+
+			recoveryCodes, err = u.replaceRecoveryCodes(ctx, username, now)
+			return err
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: models.TwoFactorRecoveryCodes{RecoveryCodes: recoveryCodes}}
+	}()
+
+	return output
+}
+
+// challenge starts the second step of signing in user when they enrolled a
+// TOTP secret or required is set. It returns nil when the first factor is
+// enough.
+func (u *authUsecase) challenge(ctx context.Context, user userModel.User, required bool) (*models.LoginChallengeResponse, error) {
+	secret, err := u.twoFactorRepository.GetTOTP(ctx, user.Username)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	enrolled := secret.ConfirmedAt != nil
+
+	if !enrolled && !required {
+		return nil, nil
+	}
+
+	token, err := utils.GenerateToken(32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.twoFactorRepository.AddChallenge(ctx, models.LoginChallenge{
+		TokenHash: utils.HashToken(token),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(constant.ChallengeTokenTTL),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginChallengeResponse{
+		TwoFactorRequired:  true,
+		EnrollmentRequired: !enrolled,
+		ChallengeToken:     token,
+		ExpiresIn:          int(constant.ChallengeTokenTTL.Minutes()),
+	}, nil
+}
+
+// getChallenge returns the live challenge of token.
+func (u *authUsecase) getChallenge(ctx context.Context, token string, now time.Time) (models.LoginChallenge, error) {
+	challenge, err := u.twoFactorRepository.GetChallenge(ctx, utils.HashToken(token))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !challenge.ExpiresAt.After(now)) {
+		return challenge, httperror.NewUnauthorized(httperror.InvalidChallengeMsg)
+	}
+
+	return challenge, err
+}
+
+func (u *authUsecase) enroll(ctx context.Context, username string, now time.Time) (enrollment models.TwoFactorEnrollment, err error) {
+	secret, err := u.twoFactorRepository.GetTOTP(ctx, username)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	if secret.ConfirmedAt != nil {
+		return enrollment, httperror.NewConflict(httperror.TwoFactorEnabledMsg)
+	}
+
+	key, err := totp.GenerateSecret()
+
+	if err != nil {
+		return
+	}
+
+	if _, err = u.twoFactorRepository.SaveTOTP(ctx, models.UserTOTP{Username: username, Secret: key, CreatedAt: now}); err != nil {
+		return
+	}
+
+	return models.TwoFactorEnrollment{Secret: key, URI: totp.URI(config.Config().TOTPIssuer(), username, key)}, nil
+}
+
+// verifySecondFactor checks the TOTP or recovery code of authReq. An
+// accepted TOTP code moves secret past its step; recovery codes only work
+// once the secret is confirmed.
+func (u *authUsecase) verifySecondFactor(ctx context.Context, secret *models.UserTOTP, authReq models.LoginTwoFactor, now time.Time) (bool, error) {
+	if authReq.RecoveryCode != "" {
+		if secret.ConfirmedAt == nil {
+			return false, nil
+		}
+
+		return u.twoFactorRepository.UseRecoveryCode(ctx, secret.Username, hashRecoveryCode(authReq.RecoveryCode), now)
+	}
+
+	step, ok := totp.Validate(secret.Secret, authReq.Code, now, constant.TOTPSkew, secret.LastStep)
+
+	if ok {
+		secret.LastStep = step
+	}
+
+	return ok, nil
+}
+
+// replaceRecoveryCodes hands out a new set of recovery codes for username.
+func (u *authUsecase) replaceRecoveryCodes(ctx context.Context, username string, now time.Time) ([]string, error) {
+	codes := make([]string, constant.RecoveryCodeCount)
+	records := make([]models.RecoveryCode, constant.RecoveryCodeCount)
+
+	for i := range codes {
+		random := make([]byte, 5)
+
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+
+		code := base32.StdEncoding.EncodeToString(random)
+		codes[i] = code[:4] + "-" + code[4:]
+		records[i] = models.RecoveryCode{Username: username, CodeHash: hashRecoveryCode(codes[i]), CreatedAt: now}
+	}
+
+	if err := u.twoFactorRepository.ReplaceRecoveryCodes(ctx, username, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode hashes code ignoring case and dashes, as users retype it.
+func hashRecoveryCode(code string) string {
+	return utils.HashToken(strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", "")))
+}
@@ -11,15 +11,18 @@ type BookRepository interface {
 	Add(ctx context.Context, data models.Book) (models.Book, error)
 	Get(ctx context.Context, filter models.BookFilter) ([]models.Book, int64, error)
 	GetByBookID(ctx context.Context, book_id string) (models.Book, error)
-	GetLast(ctx context.Context, genre string) (models.Book, error)
+	GetByBookIDForUpdate(ctx context.Context, book_id string) (models.Book, error)
+	GetByBookIDs(ctx context.Context, book_ids []string) ([]models.Book, error)
+	CountByStatus(ctx context.Context) ([]models.StatusCount, error)
 	Update(ctx context.Context, data models.Book) (models.Book, error)
 	Delete(ctx context.Context, book_id string) error
 }
 
 type BookUsecase interface {
 	Get(ctx context.Context, filter models.BookFilter) <-chan utils.Result
-	GetLast(ctx context.Context, genre string) <-chan utils.Result
 	GetByBookID(ctx context.Context, book_id string) <-chan utils.Result
+	Search(ctx context.Context, filter models.BookSearch) <-chan utils.Result
+	RebuildIndex(ctx context.Context) <-chan utils.Result
 	Add(ctx context.Context, data models.Book) <-chan utils.Result
 	Update(ctx context.Context, data models.Book) <-chan utils.Result
 	Delete(ctx context.Context, book_id string) <-chan utils.Result
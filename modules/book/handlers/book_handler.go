@@ -17,6 +17,7 @@ type BookHandler interface {
 	Add(c echo.Context) error
 	Get(c echo.Context) error
 	GetByBookID(c echo.Context) error
+	Search(c echo.Context) error
 	Delete(c echo.Context) error
 	Update(c echo.Context) error
 }
@@ -30,12 +31,15 @@ func NewBookHandler(e *echo.Echo, bookUsecase domain.BookUsecase) BookHandler {
 		bookUsecase: bookUsecase,
 	}
 
+	authenticate := middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount())
+
 	group := e.Group("/book")
-	group.DELETE("/:book-id", handler.Delete, middlewares.VerifyBasicAuth(config.Config().BasicAuthUsername, config.Config().BasicAuthPassword))
+	group.DELETE("/:book-id", handler.Delete, authenticate, middlewares.RequirePermission(constant.BookDelete))
 	group.GET("", handler.Get)
+	group.GET("/search", handler.Search)
 	group.GET("/:book-id", handler.GetByBookID)
-	group.POST("", handler.Add, middlewares.VerifyBasicAuth(config.Config().BasicAuthUsername, config.Config().BasicAuthPassword))
-	group.PUT("/:book-id", handler.Update, middlewares.VerifyBasicAuth(config.Config().BasicAuthUsername, config.Config().BasicAuthPassword))
+	group.POST("", handler.Add, authenticate, middlewares.RequirePermission(constant.BookWrite))
+	group.PUT("/:book-id", handler.Update, authenticate, middlewares.RequirePermission(constant.BookWrite))
 	return handler
 }
 
@@ -47,23 +51,10 @@ func (h *bookHandler) Add(c echo.Context) error {
 	}
 
 	if err := c.Validate(data); err != nil {
-		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		return utils.ResponseError(err, c)
 	}
 
-	result := <-h.bookUsecase.GetLast(c.Request().Context(), data.Genre)
-
-	if result.Error != nil {
-		return utils.ResponseError(result.Error, c)
-	}
-
-	expend := result.Data.(models.Book)
-
-	if expend == (models.Book{}) {
-		expend.Genre = data.Genre
-	}
-	expend = data.ToBook(expend)
-
-	result = <-h.bookUsecase.Add(c.Request().Context(), expend)
+	result := <-h.bookUsecase.Add(c.Request().Context(), data.ToBook(models.Book{}))
 	if result.Error != nil {
 		return utils.ResponseError(result.Error, c)
 	}
@@ -75,12 +66,6 @@ func (h *bookHandler) Add(c echo.Context) error {
 func (h *bookHandler) Delete(c echo.Context) error {
 	bookID := utils.ConvertString(c.Param("book-id"))
 
-	role := c.Get("role").(string)
-
-	if role != constant.Admin && role != constant.SuperAdmin {
-		return utils.ResponseError(httperror.Unauthorized(httperror.UnauthorizedErrorMessage), c)
-	}
-
 	result := <-h.bookUsecase.Delete(c.Request().Context(), bookID)
 
 	if result.Error != nil {
@@ -123,6 +108,31 @@ func (h *bookHandler) GetByBookID(c echo.Context) error {
 	return utils.Response(result.Data, "Get book success", http.StatusOK, c)
 }
 
+// Search implements BookHandler.
+func (h *bookHandler) Search(c echo.Context) error {
+	filter := new(models.BookSearch)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(filter); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	result := <-h.bookUsecase.Search(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Search book success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
 // Update implements BookHandler.
 func (h *bookHandler) Update(c echo.Context) error {
 	bookID := utils.ConvertString(c.Param("book-id"))
@@ -145,7 +155,7 @@ func (h *bookHandler) Update(c echo.Context) error {
 	}
 
 	if err := c.Validate(data); err != nil {
-		utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		return utils.ResponseError(err, c)
 	}
 
 	expend = data.ToBook(expend)
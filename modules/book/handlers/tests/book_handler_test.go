@@ -1,23 +1,46 @@
 package tests
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/book/models"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/usecases"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/search"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -30,6 +53,7 @@ var (
 	bookRows                = []string{"id", "book_id", "title", "genre", "author", "publisher", "publication_year", "status", "timestamp"}
 	bookResult              = []driver.Value{1, "TEST-DRAMA-0001", testStr, testStr, testStr, testStr, dateStr, constant.AvailableStatus, dateStr}
 	emptyResult             = []driver.Value{0, "", "", "", "", "", "", "", ""}
+	sequenceRows            = []string{"value"}
 	testStr                 = "test"
 	dateStr                 = "2024-01-01"
 )
@@ -41,6 +65,7 @@ type Suite struct {
 	mock           sqlmock.Sqlmock
 	bookRepository domain.BookRepository
 	bookUsecase    domain.BookUsecase
+	index          search.Index
 	bookHandler    handlers.BookHandler
 }
 
@@ -64,7 +89,8 @@ func (s *Suite) SetupSuite() {
 	s.Require().NoError(err)
 
 	s.bookRepository = repositories.NewBookRepository(s.DB)
-	s.bookUsecase = usecases.NewBookUsecase(s.bookRepository)
+	s.index = search.NewMemoryIndex(models.SearchWeights)
+	s.bookUsecase = usecases.NewBookUsecase(s.bookRepository, sequence.New(s.DB, constant.DefaultSequenceWidth), s.index)
 	s.bookHandler = handlers.NewBookHandler(s.e, s.bookUsecase)
 }
 
@@ -81,15 +107,17 @@ func (s *Suite) TestAddBook() {
 		bindErr        bool
 		validatorErr   bool
 		sqlErr         error
-		sqlGetLastErr  error
+		sqlSequenceErr error
+		errorCode      httperror.ErrorCode
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
-		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
-		{name: "sql get last error", sqlGetLastErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest, errorCode: httperror.CodeValidationFailed},
+		{name: "sql sequence error", sqlSequenceErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "duplicate error", sqlErr: gorm.ErrDuplicatedKey, expectedStatus: http.StatusConflict, errorCode: httperror.CodeDuplicate},
 	}
 
 	for _, tt := range tests {
@@ -114,26 +142,57 @@ func (s *Suite) TestAddBook() {
 
 		c.SetPath(bookEndpoint)
 
-		if tt.sqlErr == nil && !tt.bindErr && !tt.validatorErr && tt.sqlGetLastErr != nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetLastErr)
-		} else if tt.sqlErr != nil && !tt.bindErr && !tt.validatorErr && tt.sqlGetLastErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyResult...))
+		if !tt.bindErr && !tt.validatorErr && tt.sqlSequenceErr != nil {
 			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlSequenceErr)
 			s.mock.ExpectRollback()
-		} else if !tt.bindErr && !tt.validatorErr && tt.sqlGetLastErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyResult...))
+		} else if !tt.bindErr && !tt.validatorErr {
 			s.mock.ExpectBegin()
 			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(sequenceRows).AddRow(1))
 			s.mock.ExpectCommit()
+			s.mock.ExpectBegin()
+
+			if tt.sqlErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+				s.mock.ExpectRollback()
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				s.mock.ExpectCommit()
+			}
 		}
 
 		err = s.bookHandler.Add(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
+
+		if tt.errorCode != "" {
+			var body utils.BaseWrapperModel
+			s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+			s.Require().Equal(string(tt.errorCode), body.ErrorCode, tt.name)
+
+			if tt.validatorErr {
+				s.Require().Equal([]httperror.Detail{{Field: "title", Tag: "required", Message: "title is required"}}, body.Details)
+			}
+		}
 	}
 }
 
+func (s *Suite) TestBookMetrics() {
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"status", "total"}).
+		AddRow(constant.AvailableStatus, 3).
+		AddRow(constant.OnHoldStatus, 1))
+
+	expected := `
+# HELP perpustakaan_books_total Books in the catalogue by status.
+# TYPE perpustakaan_books_total gauge
+perpustakaan_books_total{status="AVAILABLE"} 3
+perpustakaan_books_total{status="ON HOLD"} 1
+`
+	s.Require().NoError(testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expected), "perpustakaan_books_total"))
+	s.Require().NoError(s.mock.ExpectationsWereMet())
+}
+
 func (s *Suite) TestDeleteBook() {
 	tests := []struct {
 		name           string
@@ -145,7 +204,7 @@ func (s *Suite) TestDeleteBook() {
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
-		{name: "role error", roleErr: true, expectedStatus: http.StatusUnauthorized},
+		{name: "role error", roleErr: true, expectedStatus: http.StatusForbidden},
 	}
 
 	for _, tt := range tests {
@@ -177,7 +236,7 @@ func (s *Suite) TestDeleteBook() {
 			s.mock.ExpectCommit()
 		}
 
-		err := s.bookHandler.Delete(c)
+		err := middlewares.RequirePermission(constant.BookDelete)(s.bookHandler.Delete)(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
 	}
@@ -239,12 +298,14 @@ func (s *Suite) TestGetByBookID() {
 	tests := []struct {
 		name           string
 		sqlErr         error
+		notFound       bool
 		expectedStatus int
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
 	}
 
 	for _, tt := range tests {
@@ -259,6 +320,8 @@ func (s *Suite) TestGetByBookID() {
 
 		if tt.sqlErr != nil {
 			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows))
 		} else {
 			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
 		}
@@ -269,6 +332,88 @@ func (s *Suite) TestGetByBookID() {
 	}
 }
 
+func (s *Suite) TestGetByBookIDTracing() {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	s.Require().NoError(tracing.RegisterDB(s.DB))
+
+	req := httptest.NewRequest(http.MethodGet, bookEndpoint+"/test", nil)
+	rec := httptest.NewRecorder()
+
+	c := s.e.NewContext(req, rec)
+	c.SetPath(bookEndpoint + "/:book-id")
+	c.SetParamNames("book-id")
+	c.SetParamValues(testStr)
+
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+
+	err := handlers.NewBookHandler(echo.New(), usecases.NewTracedBookUsecase(s.bookUsecase)).GetByBookID(c)
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, rec.Code)
+
+	spans := recorder.Ended()
+	s.Require().Len(spans, 2)
+
+	query, usecase := spans[0], spans[1]
+	s.Require().Equal("gorm.query", query.Name())
+	s.Require().Equal("BookUsecase.GetByBookID", usecase.Name())
+	s.Require().Equal(usecase.SpanContext().SpanID(), query.Parent().SpanID())
+	s.Require().Contains(usecase.Attributes(), attribute.String("book.id", testStr))
+	s.Require().Equal(codes.Unset, usecase.Status().Code)
+}
+
+func (s *Suite) TestSearchBook() {
+	s.index.Index(search.Document{ID: "TEST-FANTASY-0001", Fields: map[string]string{"title": "Harry Potter", "author": "Rowling"}})
+
+	tests := []struct {
+		name           string
+		query          string
+		bindErr        bool
+		noHits         bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", query: "harry", expectedStatus: http.StatusOK},
+		{name: "success with typo", query: "hary potter", expectedStatus: http.StatusOK},
+		{name: "no hits", query: "tolkien", noHits: true, expectedStatus: http.StatusOK},
+		{name: "missing query", expectedStatus: http.StatusBadRequest},
+		{name: "bind error", query: "harry", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "sql error", query: "harry", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("q", tt.query)
+		q.Set("page", "1")
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, bookEndpoint+"/search?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(bookEndpoint + "/search")
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.query != "" && !tt.bindErr && !tt.noHits {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(1, "TEST-FANTASY-0001", "Harry Potter", testStr, "Rowling", testStr, dateStr, constant.AvailableStatus, dateStr))
+		}
+
+		err := s.bookHandler.Search(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
 func (s *Suite) TestUpdateBook() {
 	var tests = []struct {
 		name           string
@@ -337,6 +482,64 @@ func (s *Suite) TestUpdateBook() {
 	}
 }
 
+func (s *Suite) TestDeleteBookAuthentication() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	s.Require().NoError(err)
+
+	signToken := func(role string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"username": testStr,
+			"role":     role,
+			"exp":      time.Now().Add(time.Hour).Unix(),
+		}).SignedString(key)
+		s.Require().NoError(err)
+
+		return "Bearer " + token
+	}
+
+	config.Config().PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	config.Config().BasicAuthUsername = "admin"
+	config.Config().BasicAuthPassword = "admin"
+	config.Config().BasicAuthRole = ""
+	s.Require().Equal(constant.Admin, config.Config().BasicAccount().Role)
+
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	handlers.NewBookHandler(e, s.bookUsecase)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "no credentials", expectedStatus: http.StatusUnauthorized},
+		{name: "basic auth", authorization: "Basic YWRtaW46YWRtaW4=", expectedStatus: http.StatusOK},
+		{name: "wrong basic auth", authorization: "Basic YWRtaW46d3Jvbmc=", expectedStatus: http.StatusUnauthorized},
+		{name: "admin token", authorization: signToken(constant.Admin), expectedStatus: http.StatusOK},
+		{name: "karyawan token", authorization: signToken(constant.Karyawan), expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, bookEndpoint+"/test", nil)
+		req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+		rec := httptest.NewRecorder()
+
+		if tt.expectedStatus == http.StatusOK {
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
+		e.ServeHTTP(rec, req)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+	}
+
+	config.LoadConfig()
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(Suite))
 }
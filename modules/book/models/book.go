@@ -15,3 +15,9 @@ type Book struct {
 func (Book) TableName() string {
 	return "book"
 }
+
+// StatusCount is how many books have Status.
+type StatusCount struct {
+	Status string `json:"status"`
+	Total  int64  `json:"total"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+
+// SearchWeights boosts matches in the more telling fields of a book.
+var SearchWeights = map[string]float64{
+	"title":     3,
+	"author":    2,
+	"genre":     1.5,
+	"publisher": 1,
+}
+
+type BookSearch struct {
+	Query string `json:"q" query:"q" validate:"required"`
+	utils.PaginationRequest
+}
+
+type BookSearchResult struct {
+	Book
+	Score float64 `json:"score"`
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
 	"gorm.io/gorm"
 )
 
@@ -14,30 +15,43 @@ type bookRepository struct {
 
 // Add implements domain.BookRepository.
 func (r *bookRepository) Add(ctx context.Context, data models.Book) (result models.Book, err error) {
-	err = r.db.WithContext(ctx).Create(&data).Error
+	err = databases.Conn(ctx, r.db).Create(&data).Error
 	return data, err
 }
 
 // Delete implements domain.BookRepository.
 func (r *bookRepository) Delete(ctx context.Context, book_id string) error {
-	return r.db.WithContext(ctx).Where("book_id = ?", book_id).Delete(&models.Book{}).Error
+	return databases.Conn(ctx, r.db).Where("book_id = ?", book_id).Delete(&models.Book{}).Error
 }
 
-// GetLast implements domain.BookRepository.
-func (r *bookRepository) GetLast(ctx context.Context, genre string) (result models.Book, err error) {
-	err = r.db.WithContext(ctx).Select("book_id").Last(&result, "genre = ?", genre).Error
+// GetByBookID implements domain.BookRepository.
+func (r *bookRepository) GetByBookID(ctx context.Context, book_id string) (result models.Book, err error) {
+	err = databases.Conn(ctx, r.db).Where("book_id = ?", book_id).First(&result).Error
 	return
 }
 
-// GetByBookID implements domain.BookRepository.
-func (r *bookRepository) GetByBookID(ctx context.Context, book_id string) (result models.Book, err error) {
-	err = r.db.WithContext(ctx).Where("book_id = ?", book_id).First(&result).Error
+// GetByBookIDForUpdate implements domain.BookRepository. The book row stays
+// locked until the surrounding transaction ends.
+func (r *bookRepository) GetByBookIDForUpdate(ctx context.Context, book_id string) (result models.Book, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("book_id = ?", book_id).First(&result).Error
+	return
+}
+
+// GetByBookIDs implements domain.BookRepository.
+func (r *bookRepository) GetByBookIDs(ctx context.Context, book_ids []string) (result []models.Book, err error) {
+	err = databases.Conn(ctx, r.db).Where("book_id IN ?", book_ids).Find(&result).Error
+	return
+}
+
+// CountByStatus implements domain.BookRepository.
+func (r *bookRepository) CountByStatus(ctx context.Context) (result []models.StatusCount, err error) {
+	err = databases.Conn(ctx, r.db).Model(&models.Book{}).Select("status, COUNT(*) AS total").Group("status").Scan(&result).Error
 	return
 }
 
 // Get implements domain.BookRepository.
 func (r *bookRepository) Get(ctx context.Context, filter models.BookFilter) (result []models.Book, total int64, err error) {
-	db := r.db.WithContext(ctx)
+	db := databases.Conn(ctx, r.db)
 	db = buildFilterQuery(db, filter)
 
 	if err = db.Model(&models.Book{}).Count(&total).Error; err != nil {
@@ -57,7 +71,7 @@ func (r *bookRepository) Get(ctx context.Context, filter models.BookFilter) (res
 
 // Update implements domain.BookRepository.
 func (r *bookRepository) Update(ctx context.Context, data models.Book) (result models.Book, err error) {
-	err = r.db.WithContext(ctx).Save(&data).Error
+	err = databases.Conn(ctx, r.db).Save(&data).Error
 	return data, err
 }
 
@@ -3,30 +3,52 @@ package usecases
 import (
 	"context"
 
+	"github.com/Zeroaril7/perpustakaan-go/config"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/search"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 )
 
 type bookUsecase struct {
 	bookRepository domain.BookRepository
+	sequence       sequence.Sequence
+	index          search.Index
 }
 
-// Add implements domain.BookUsecase.
+// Add implements domain.BookUsecase. The book ID is taken from the book
+// sequence.
 func (u *bookUsecase) Add(ctx context.Context, data models.Book) <-chan utils.Result {
 	output := make(chan utils.Result)
 
 	go func() {
 		defer close(output)
 
+		bookID, err := u.sequence.NextID(ctx, config.Config().BookIDTemplate(), map[string]string{
+			constant.InstituteField: constant.Institute,
+			constant.GenreField:     data.Genre,
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		data.BookID = bookID
+
 		result, err := u.bookRepository.Add(ctx, data)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
+		u.index.Index(result.ToDocument())
+
 		output <- utils.Result{Data: result}
 	}()
 
@@ -43,10 +65,12 @@ func (u *bookUsecase) Delete(ctx context.Context, book_id string) <-chan utils.R
 		err := u.bookRepository.Delete(ctx, book_id)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
+		u.index.Delete(book_id)
+
 		output <- utils.Result{}
 	}()
 
@@ -63,7 +87,7 @@ func (u *bookUsecase) Get(ctx context.Context, filter models.BookFilter) <-chan
 		result, total, err := u.bookRepository.Get(ctx, filter)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -73,17 +97,17 @@ func (u *bookUsecase) Get(ctx context.Context, filter models.BookFilter) <-chan
 	return output
 }
 
-// GetLast implements domain.BookUsecase.
-func (u *bookUsecase) GetLast(ctx context.Context, genre string) <-chan utils.Result {
+// GetByBookID implements domain.BookUsecase.
+func (u *bookUsecase) GetByBookID(ctx context.Context, book_id string) <-chan utils.Result {
 	output := make(chan utils.Result)
 
 	go func() {
 		defer close(output)
 
-		result, err := u.bookRepository.GetLast(ctx, genre)
+		result, err := u.bookRepository.GetByBookID(ctx, book_id)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -93,21 +117,80 @@ func (u *bookUsecase) GetLast(ctx context.Context, genre string) <-chan utils.Re
 	return output
 }
 
-// GetByBookID implements domain.BookUsecase.
-func (u *bookUsecase) GetByBookID(ctx context.Context, book_id string) <-chan utils.Result {
+// Search implements domain.BookUsecase. Books are ranked by relevance to
+// the query.
+func (u *bookUsecase) Search(ctx context.Context, filter models.BookSearch) <-chan utils.Result {
 	output := make(chan utils.Result)
 
 	go func() {
 		defer close(output)
 
-		result, err := u.bookRepository.GetByBookID(ctx, book_id)
+		limit := 0
+
+		if !filter.DisablePagination {
+			limit = int(filter.GetLimit())
+		}
+
+		hits, total := u.index.Search(filter.Query, limit, int(filter.GetOffset()))
+
+		result := make([]models.BookSearchResult, 0, len(hits))
+
+		if len(hits) == 0 {
+			output <- utils.Result{Data: result, Total: int64(total)}
+			return
+		}
+
+		bookIDs := make([]string, len(hits))
+
+		for i, hit := range hits {
+			bookIDs[i] = hit.ID
+		}
+
+		books, err := u.bookRepository.GetByBookIDs(ctx, bookIDs)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		output <- utils.Result{Data: result}
+		byBookID := make(map[string]models.Book, len(books))
+
+		for _, book := range books {
+			byBookID[book.BookID] = book
+		}
+
+		for _, hit := range hits {
+			if book, ok := byBookID[hit.ID]; ok {
+				result = append(result, models.BookSearchResult{Book: book, Score: hit.Score})
+			}
+		}
+
+		output <- utils.Result{Data: result, Total: int64(total)}
+	}()
+
+	return output
+}
+
+// RebuildIndex implements domain.BookUsecase. Every book in the catalog is
+// indexed again.
+func (u *bookUsecase) RebuildIndex(ctx context.Context) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		books, total, err := u.bookRepository.Get(ctx, models.BookFilter{PaginationRequest: utils.PaginationRequest{DisablePagination: true}})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		for _, book := range books {
+			u.index.Index(book.ToDocument())
+		}
+
+		output <- utils.Result{Total: total}
 	}()
 
 	return output
@@ -123,16 +206,20 @@ func (u *bookUsecase) Update(ctx context.Context, data models.Book) <-chan utils
 		result, err := u.bookRepository.Update(ctx, data)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
+		u.index.Index(result.ToDocument())
+
 		output <- utils.Result{Data: result}
 	}()
 
 	return output
 }
 
-func NewBookUsecase(bookRepository domain.BookRepository) domain.BookUsecase {
-	return &bookUsecase{bookRepository: bookRepository}
+func NewBookUsecase(bookRepository domain.BookRepository, sequence sequence.Sequence, index search.Index) domain.BookUsecase {
+	metrics.Register(bookCollector{bookRepository: bookRepository})
+
+	return &bookUsecase{bookRepository: bookRepository, sequence: sequence, index: index}
 }
@@ -0,0 +1,41 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var booksDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metrics.Namespace, "books", "total"),
+	"Books in the catalogue by status.", []string{"status"}, nil)
+
+// bookCollector counts the books in the database on every scrape.
+type bookCollector struct {
+	bookRepository domain.BookRepository
+}
+
+// Describe implements prometheus.Collector.
+func (c bookCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- booksDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c bookCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), constant.MetricsQueryTimeout)
+	defer cancel()
+
+	counts, err := c.bookRepository.CountByStatus(ctx)
+
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(booksDesc, err)
+		return
+	}
+
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(booksDesc, prometheus.GaugeValue, float64(count.Total), count.Status)
+	}
+}
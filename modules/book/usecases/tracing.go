@@ -0,0 +1,63 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedBookUsecase runs every call of a domain.BookUsecase in a span, see tracing.Result.
+type tracedBookUsecase struct {
+	next domain.BookUsecase
+}
+
+// NewTracedBookUsecase returns next with every call traced.
+func NewTracedBookUsecase(next domain.BookUsecase) domain.BookUsecase {
+	return &tracedBookUsecase{next: next}
+}
+
+// Get implements domain.BookUsecase.
+func (u *tracedBookUsecase) Get(ctx context.Context, filter models.BookFilter) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.Get")
+	return tracing.Result(span, u.next.Get(ctx, filter))
+}
+
+// GetByBookID implements domain.BookUsecase.
+func (u *tracedBookUsecase) GetByBookID(ctx context.Context, book_id string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.GetByBookID", attribute.String("book.id", book_id))
+	return tracing.Result(span, u.next.GetByBookID(ctx, book_id))
+}
+
+// Search implements domain.BookUsecase.
+func (u *tracedBookUsecase) Search(ctx context.Context, filter models.BookSearch) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.Search")
+	return tracing.Result(span, u.next.Search(ctx, filter))
+}
+
+// RebuildIndex implements domain.BookUsecase.
+func (u *tracedBookUsecase) RebuildIndex(ctx context.Context) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.RebuildIndex")
+	return tracing.Result(span, u.next.RebuildIndex(ctx))
+}
+
+// Add implements domain.BookUsecase.
+func (u *tracedBookUsecase) Add(ctx context.Context, data models.Book) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.Add")
+	return tracing.Result(span, u.next.Add(ctx, data))
+}
+
+// Update implements domain.BookUsecase.
+func (u *tracedBookUsecase) Update(ctx context.Context, data models.Book) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.Update")
+	return tracing.Result(span, u.next.Update(ctx, data))
+}
+
+// Delete implements domain.BookUsecase.
+func (u *tracedBookUsecase) Delete(ctx context.Context, book_id string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "BookUsecase.Delete", attribute.String("book.id", book_id))
+	return tracing.Result(span, u.next.Delete(ctx, book_id))
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+type FineRepository interface {
+	Get(ctx context.Context, filter models.FineFilter) ([]models.Fine, int64, error)
+	GetByIDForUpdate(ctx context.Context, id int64) (models.Fine, error)
+	GetByLoanID(ctx context.Context, loan_id string) (models.Fine, error)
+	GetUnpaidTotal(ctx context.Context, username string) (int64, error)
+	Update(ctx context.Context, data models.Fine) (models.Fine, error)
+}
+
+type FineUsecase interface {
+	Get(ctx context.Context, filter models.FineFilter) <-chan utils.Result
+	Settle(ctx context.Context, username string, id int64, status string) <-chan utils.Result
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type FineHandler interface {
+	GetByUsername(c echo.Context) error
+	Settle(c echo.Context) error
+}
+
+type fineHandler struct {
+	fineUsecase domain.FineUsecase
+}
+
+func NewFineHandler(e *echo.Echo, fineUsecase domain.FineUsecase) FineHandler {
+	handler := &fineHandler{fineUsecase: fineUsecase}
+
+	group := e.Group("/user", middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()))
+	group.GET("/:username/fines", handler.GetByUsername, middlewares.RequirePermission(constant.FineRead))
+	group.PUT("/:username/fines/:id", handler.Settle, middlewares.RequirePermission(constant.FineManage))
+
+	return handler
+}
+
+// GetByUsername implements FineHandler.
+func (h *fineHandler) GetByUsername(c echo.Context) error {
+	filter := new(models.FineFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	filter.Username = utils.ConvertString(c.Param("username"))
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	result := <-h.fineUsecase.Get(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get fine success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// Settle implements FineHandler.
+func (h *fineHandler) Settle(c echo.Context) error {
+	data := new(models.FineSettle)
+
+	if err := c.Bind(data); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	username := utils.ConvertString(c.Param("username"))
+	id := utils.ConvertInt64(c.Param("id"))
+
+	result := <-h.fineUsecase.Settle(c.Request().Context(), username, id, data.Status)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Settle fine success", http.StatusOK, c)
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	fineEndpoint                  = "/user/:username/fines"
+	fineSettleBodyFilePath        = "test_data/fine_settle_body_req.json"
+	fineSettleBodyInvalidFilePath = "test_data/fine_settle_body_invalid_req.json"
+	fineSettleBodyStatusFilePath  = "test_data/fine_settle_body_unknown_status_req.json"
+	fineRows                      = []string{"id", "loan_id", "username", "book_id", "days_late", "rate", "amount", "status", "updated_at"}
+	fineResult                    = []driver.Value{1, "LOAN-TEST-0001", testStr, "TEST-DRAMA-0001", 3, 1000, 3000, constant.FineUnpaidStatus, time.Now()}
+	paidFineResult                = []driver.Value{1, "LOAN-TEST-0001", testStr, "TEST-DRAMA-0001", 3, 1000, 3000, constant.FinePaidStatus, time.Now()}
+	otherFineResult               = []driver.Value{1, "LOAN-TEST-0001", "other", "TEST-DRAMA-0001", 3, 1000, 3000, constant.FineUnpaidStatus, time.Now()}
+	testStr                       = "test"
+)
+
+type Suite struct {
+	suite.Suite
+	e              *echo.Echo
+	DB             *gorm.DB
+	mock           sqlmock.Sqlmock
+	fineRepository domain.FineRepository
+	fineUsecase    domain.FineUsecase
+	fineHandler    handlers.FineHandler
+}
+
+func (s *Suite) SetupSuite() {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	s.e = echo.New()
+	s.e.Validator = validator.NewCustomValidator()
+	db, s.mock, err = sqlmock.New()
+	s.Require().NoError(err)
+
+	dialector := mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	})
+
+	s.DB, err = gorm.Open(dialector, &gorm.Config{})
+	s.Require().NoError(err)
+
+	s.fineRepository = repositories.NewFineRepository(s.DB)
+	s.fineUsecase = usecases.NewFineUsecase(s.fineRepository, databases.NewUnitOfWork(s.DB))
+	s.fineHandler = handlers.NewFineHandler(s.e, s.fineUsecase)
+}
+
+func (s *Suite) TearDownSuite() {
+	db, err := s.DB.DB()
+	s.Require().NoError(err)
+	db.Close()
+}
+
+func (s *Suite) TestGetByUsername() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		totalErr       bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "total error", totalErr: true, sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("status", constant.FineUnpaidStatus)
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/user/test/fines?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(fineEndpoint)
+		c.SetParamNames("username")
+		c.SetParamValues(testStr)
+
+		if tt.sqlErr != nil && !tt.bindErr && tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.sqlErr != nil && !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows).AddRow(fineResult...))
+		}
+
+		err := s.fineHandler.GetByUsername(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestSettleFine() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		unknownStatus  bool
+		notFound       bool
+		otherUser      bool
+		settled        bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "unknown status", unknownStatus: true, expectedStatus: http.StatusBadRequest},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "other user", otherUser: true, expectedStatus: http.StatusNotFound},
+		{name: "already settled", settled: true, expectedStatus: http.StatusConflict},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		var bodyFilepath string
+		if tt.bindErr {
+			bodyFilepath = fineSettleBodyInvalidFilePath
+		} else if tt.unknownStatus {
+			bodyFilepath = fineSettleBodyStatusFilePath
+		} else {
+			bodyFilepath = fineSettleBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPut, "/user/test/fines/1", jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(fineEndpoint + "/:id")
+		c.SetParamNames("username", "id")
+		c.SetParamValues(testStr, "1")
+
+		if !tt.bindErr && !tt.unknownStatus {
+			s.mock.ExpectBegin()
+
+			if tt.notFound {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows))
+			} else if tt.otherUser {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows).AddRow(otherFineResult...))
+			} else if tt.settled {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows).AddRow(paidFineResult...))
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows).AddRow(fineResult...))
+			}
+
+			if tt.sqlErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+				s.mock.ExpectRollback()
+			} else if tt.notFound || tt.otherUser || tt.settled {
+				s.mock.ExpectRollback()
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				s.mock.ExpectCommit()
+			}
+		}
+
+		err = s.fineHandler.Settle(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
+	}
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Fine struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	LoanID    string    `json:"loan_id"`
+	Username  string    `json:"username"`
+	BookID    string    `json:"book_id"`
+	DaysLate  int64     `json:"days_late"`
+	Rate      int64     `json:"rate"`
+	Amount    int64     `json:"amount"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Fine) TableName() string {
+	return "fine"
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+
+type FineFilter struct {
+	Username string `json:"username" query:"username"`
+	LoanID   string `json:"loan_id" query:"loan_id"`
+	Status   string `json:"status" query:"status"`
+	utils.PaginationRequest
+}
@@ -0,0 +1,5 @@
+package models
+
+type FineSettle struct {
+	Status string `json:"status" validate:"required,oneof=PAID WAIVED"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+
+// Charge records the fine for a loan that is daysLate days past its end
+// date at rate per day, capped at limit when limit is positive. A fine that
+// is not settled yet keeps accruing.
+func (e Fine) Charge(loan_id, username, book_id string, daysLate, rate, limit int64) Fine {
+	e.LoanID = loan_id
+	e.Username = username
+	e.BookID = book_id
+	e.DaysLate = daysLate
+	e.Rate = rate
+	e.Amount = daysLate * rate
+
+	if limit > 0 && e.Amount > limit {
+		e.Amount = limit
+	}
+
+	if e.Status == "" {
+		e.Status = constant.FineAccruingStatus
+	}
+
+	return e
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type fineRepository struct {
+	db *gorm.DB
+}
+
+// Get implements domain.FineRepository.
+func (r *fineRepository) Get(ctx context.Context, filter models.FineFilter) (result []models.Fine, total int64, err error) {
+	db := databases.Conn(ctx, r.db)
+	db = buildFilterQuery(db, filter)
+
+	if err = db.Model(&models.Fine{}).Count(&total).Error; err != nil {
+		return
+	}
+
+	if !filter.DisablePagination {
+		db = db.Offset(int(filter.GetOffset())).Limit(int(filter.GetLimit()))
+	}
+
+	if err = db.Order("id").Find(&result).Error; err != nil {
+		return
+	}
+
+	return
+}
+
+// GetByIDForUpdate implements domain.FineRepository. The fine row stays
+// locked until the surrounding transaction ends.
+func (r *fineRepository) GetByIDForUpdate(ctx context.Context, id int64) (result models.Fine, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("id = ?", id).First(&result).Error
+	return
+}
+
+// GetByLoanID implements domain.FineRepository.
+func (r *fineRepository) GetByLoanID(ctx context.Context, loan_id string) (result models.Fine, err error) {
+	err = databases.Conn(ctx, r.db).Where("loan_id = ?", loan_id).Limit(1).Find(&result).Error
+	return
+}
+
+// GetUnpaidTotal implements domain.FineRepository.
+func (r *fineRepository) GetUnpaidTotal(ctx context.Context, username string) (total int64, err error) {
+	err = databases.Conn(ctx, r.db).Model(&models.Fine{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("username = ? AND status = ?", username, constant.FineUnpaidStatus).
+		Scan(&total).Error
+	return
+}
+
+// Update implements domain.FineRepository. A fine without an ID is inserted.
+func (r *fineRepository) Update(ctx context.Context, data models.Fine) (result models.Fine, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+func NewFineRepository(db *gorm.DB) domain.FineRepository {
+	return &fineRepository{db: db}
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	"gorm.io/gorm"
+)
+
+func buildFilterQuery(db *gorm.DB, f models.FineFilter) *gorm.DB {
+	if f.Username != "" {
+		db = db.Where("username = ?", f.Username)
+	}
+
+	if f.LoanID != "" {
+		db = db.Where("loan_id = ?", f.LoanID)
+	}
+
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+
+	return db
+}
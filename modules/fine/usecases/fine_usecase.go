@@ -0,0 +1,83 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+type fineUsecase struct {
+	fineRepository domain.FineRepository
+	unitOfWork     databases.UnitOfWork
+}
+
+// Get implements domain.FineUsecase.
+func (u *fineUsecase) Get(ctx context.Context, filter models.FineFilter) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, total, err := u.fineRepository.Get(ctx, filter)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result, Total: total}
+	}()
+
+	return output
+}
+
+// Settle implements domain.FineUsecase. Only an unpaid fine of the given
+// member can be marked paid or waived.
+func (u *fineUsecase) Settle(ctx context.Context, username string, id int64, status string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var result models.Fine
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			fine, err := u.fineRepository.GetByIDForUpdate(ctx, id)
+
+			if err != nil {
+				return err
+			}
+
+			if fine.Username != username {
+				return httperror.NotFound(httperror.NotFoundErrorMessage)
+			}
+
+			if fine.Status != constant.FineUnpaidStatus {
+				return httperror.Conflict("Fine is not unpaid")
+			}
+
+			fine.Status = status
+
+			result, err = u.fineRepository.Update(ctx, fine)
+			return err
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+func NewFineUsecase(fineRepository domain.FineRepository, unitOfWork databases.UnitOfWork) domain.FineUsecase {
+	return &fineUsecase{fineRepository: fineRepository, unitOfWork: unitOfWork}
+}
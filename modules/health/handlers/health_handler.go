@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/health"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type HealthHandler interface {
+	Livez(c echo.Context) error
+	Readyz(c echo.Context) error
+	HealthCheck(c echo.Context) error
+}
+
+type healthHandler struct {
+	checks *health.Health
+}
+
+// NewHealthHandler serves the probes of container orchestrators: /livez
+// answers while the process does and /readyz while it should get traffic.
+func NewHealthHandler(e *echo.Echo, checks *health.Health) HealthHandler {
+	handler := &healthHandler{checks: checks}
+
+	e.GET("/livez", handler.Livez)
+	e.GET("/readyz", handler.Readyz)
+	e.GET("/v1/health-check", handler.HealthCheck)
+
+	return handler
+}
+
+// Livez implements HealthHandler. It does not run the checks, a database
+// that is down is no reason to restart the service.
+func (h *healthHandler) Livez(c echo.Context) error {
+	return utils.Response(nil, "This service is alive", http.StatusOK, c)
+}
+
+// Readyz implements HealthHandler.
+func (h *healthHandler) Readyz(c echo.Context) error {
+	if !health.Ready() {
+		return utils.Response(nil, "This service is shutting down", http.StatusServiceUnavailable, c)
+	}
+
+	if report := h.checks.Run(c.Request().Context()); !report.Up() {
+		return utils.Response(nil, "This service is not ready", http.StatusServiceUnavailable, c)
+	}
+
+	return utils.Response(nil, "This service is ready", http.StatusOK, c)
+}
+
+// HealthCheck implements HealthHandler. With verbose=1 the result and
+// latency of every check are in the data.
+func (h *healthHandler) HealthCheck(c echo.Context) error {
+	verbose, _ := strconv.ParseBool(c.QueryParam("verbose"))
+	report := h.checks.Run(c.Request().Context())
+
+	var data interface{}
+
+	if verbose {
+		data = report
+	}
+
+	if !health.Ready() {
+		return utils.Response(data, "This service is shutting down", http.StatusServiceUnavailable, c)
+	}
+
+	log := logger.FromContext(c.Request().Context()).Named("health")
+
+	if !report.Up() {
+		log.Warn("This service is not healthy", zap.Any("checks", report.Checks))
+		return utils.Response(data, "This service is not healthy", http.StatusServiceUnavailable, c)
+	}
+
+	log.Debug("This service is running properly")
+	return utils.Response(data, "This service is running properly", http.StatusOK, c)
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Zeroaril7/perpustakaan-go/modules/health/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/health"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type Suite struct {
+	suite.Suite
+	e       *echo.Echo
+	DB      *gorm.DB
+	mock    sqlmock.Sqlmock
+	handler handlers.HealthHandler
+}
+
+func (s *Suite) SetupSuite() {
+	s.e = echo.New()
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	s.Require().NoError(err)
+	s.mock = mock
+
+	// gorm pings the connection when it opens it
+	s.mock.ExpectPing()
+
+	dialector := mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	})
+
+	s.DB, err = gorm.Open(dialector, &gorm.Config{})
+	s.Require().NoError(err)
+
+	checks := health.New(time.Second)
+	checks.Register("database", health.Database(s.DB))
+	s.handler = handlers.NewHealthHandler(s.e, checks)
+}
+
+func (s *Suite) TearDownSuite() {
+	health.SetReady(false)
+
+	db, err := s.DB.DB()
+	s.Require().NoError(err)
+	db.Close()
+}
+
+func (s *Suite) TestProbes() {
+	tests := []struct {
+		name           string
+		path           string
+		notReady       bool
+		pingErr        error
+		noPing         bool
+		expectedStatus int
+	}{
+		{name: "alive", path: "/livez", noPing: true, expectedStatus: http.StatusOK},
+		{name: "alive while the database is down", path: "/livez", noPing: true, notReady: true, expectedStatus: http.StatusOK},
+		{name: "ready", path: "/readyz", expectedStatus: http.StatusOK},
+		{name: "database down", path: "/readyz", pingErr: errors.New("connection refused"), expectedStatus: http.StatusServiceUnavailable},
+		{name: "shutting down", path: "/readyz", notReady: true, noPing: true, expectedStatus: http.StatusServiceUnavailable},
+		{name: "health check", path: "/v1/health-check", expectedStatus: http.StatusOK},
+		{name: "health check database down", path: "/v1/health-check", pingErr: errors.New("connection refused"), expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		health.SetReady(!tt.notReady)
+
+		if tt.pingErr != nil {
+			s.mock.ExpectPing().WillReturnError(tt.pingErr)
+		} else if !tt.noPing {
+			s.mock.ExpectPing()
+		}
+
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		s.e.ServeHTTP(rec, req)
+
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
+	}
+}
+
+func (s *Suite) TestVerboseHealthCheck() {
+	health.SetReady(true)
+	s.mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health-check?verbose=1", nil)
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	s.Require().Equal(http.StatusServiceUnavailable, rec.Code)
+
+	var body struct {
+		Data health.Report `json:"data"`
+	}
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	s.Require().Equal(health.StatusDown, body.Data.Status)
+	s.Require().Len(body.Data.Checks, 1)
+	s.Require().Equal("database", body.Data.Checks[0].Name)
+	s.Require().Equal("connection refused", body.Data.Checks[0].Error)
+
+	// Without verbose there is no report
+	s.mock.ExpectPing()
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/health-check", nil)
+	rec = httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+
+	s.Require().Equal(http.StatusOK, rec.Code)
+	s.Require().JSONEq(`{"success":true,"data":null,"message":"This service is running properly","code":200}`, rec.Body.String())
+}
+
+func TestHealthHandler(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
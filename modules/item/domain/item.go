@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+type ItemRepository interface {
+	Add(ctx context.Context, data models.Item) (models.Item, error)
+	Get(ctx context.Context, filter models.ItemFilter) ([]models.Item, int64, error)
+	GetByBarcode(ctx context.Context, barcode string) (models.Item, error)
+	GetByBarcodeForUpdate(ctx context.Context, barcode string) (models.Item, error)
+	GetAvailableByBookID(ctx context.Context, book_id string) (models.Item, error)
+	CountByStatus(ctx context.Context, book_id string) (map[string]int64, error)
+	Update(ctx context.Context, data models.Item) (models.Item, error)
+	Delete(ctx context.Context, barcode string) error
+}
+
+type ItemUsecase interface {
+	Get(ctx context.Context, filter models.ItemFilter) <-chan utils.Result
+	GetByBarcode(ctx context.Context, barcode string) <-chan utils.Result
+	Add(ctx context.Context, data models.Item) <-chan utils.Result
+	Update(ctx context.Context, data models.Item) <-chan utils.Result
+	Delete(ctx context.Context, barcode string) <-chan utils.Result
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type ItemHandler interface {
+	Add(c echo.Context) error
+	Delete(c echo.Context) error
+	Get(c echo.Context) error
+	GetByBarcode(c echo.Context) error
+	Update(c echo.Context) error
+}
+
+type itemHandler struct {
+	itemUsecase domain.ItemUsecase
+}
+
+func NewItemHandler(e *echo.Echo, itemUsecase domain.ItemUsecase) ItemHandler {
+	handler := &itemHandler{
+		itemUsecase: itemUsecase,
+	}
+
+	authenticate := middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount())
+
+	group := e.Group("/item")
+	group.DELETE("/:barcode", handler.Delete, authenticate, middlewares.RequirePermission(constant.ItemDelete))
+	group.GET("", handler.Get)
+	group.GET("/:barcode", handler.GetByBarcode)
+	group.POST("", handler.Add, authenticate, middlewares.RequirePermission(constant.ItemWrite))
+	group.PUT("/:barcode", handler.Update, authenticate, middlewares.RequirePermission(constant.ItemWrite))
+
+	return handler
+}
+
+// Add implements ItemHandler.
+func (h *itemHandler) Add(c echo.Context) error {
+	data := new(models.ItemAdd)
+
+	if err := c.Bind(data); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	expend := data.ToItem(models.Item{})
+
+	result := <-h.itemUsecase.Add(c.Request().Context(), expend)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Add item success", http.StatusOK, c)
+}
+
+// Delete implements ItemHandler.
+func (h *itemHandler) Delete(c echo.Context) error {
+	barcode := utils.ConvertString(c.Param("barcode"))
+
+	result := <-h.itemUsecase.Delete(c.Request().Context(), barcode)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(nil, "Delete item success", http.StatusOK, c)
+}
+
+// Get implements ItemHandler.
+func (h *itemHandler) Get(c echo.Context) error {
+	filter := new(models.ItemFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	result := <-h.itemUsecase.Get(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get item success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// GetByBarcode implements ItemHandler.
+func (h *itemHandler) GetByBarcode(c echo.Context) error {
+	barcode := utils.ConvertString(c.Param("barcode"))
+
+	result := <-h.itemUsecase.GetByBarcode(c.Request().Context(), barcode)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Get item success", http.StatusOK, c)
+}
+
+// Update implements ItemHandler.
+func (h *itemHandler) Update(c echo.Context) error {
+	barcode := utils.ConvertString(c.Param("barcode"))
+
+	result := <-h.itemUsecase.GetByBarcode(c.Request().Context(), barcode)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	expend := result.Data.(models.Item)
+
+	if expend == (models.Item{}) {
+		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+	}
+
+	data := new(models.ItemUpdate)
+
+	if err := c.Bind(data); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	expend = data.ToItem(expend)
+
+	result = <-h.itemUsecase.Update(c.Request().Context(), expend)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Update item success", http.StatusOK, c)
+}
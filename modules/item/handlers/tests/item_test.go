@@ -0,0 +1,405 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
+	bookRepo "github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	itemEndpoint                  = "/item"
+	itemBodyFilePath              = "test_data/item_body_req.json"
+	itemBodyInvalidFilePath       = "test_data/item_body_invalid_req.json"
+	itemBodyEmptyFilePath         = "test_data/item_body_empty_req.json"
+	itemUpdateBodyFilePath        = "test_data/item_update_body_req.json"
+	itemUpdateBodyInvalidFilePath = "test_data/item_update_body_invalid_req.json"
+	itemUpdateBodyEmptyFilePath   = "test_data/item_update_body_empty_req.json"
+	itemUpdateBodyStatusFilePath  = "test_data/item_update_body_unknown_status_req.json"
+	itemUpdateBodyOnLoanFilePath  = "test_data/item_update_body_on_loan_req.json"
+	itemRows                      = []string{"id", "barcode", "book_id", "shelf_location", "condition", "status", "timestamp"}
+	itemResult                    = []driver.Value{1, "ITEM-0001", "FAKHRIL-Drama-0001", "A-01", constant.ItemGoodCondition, constant.ItemAvailableStatus, dateStr}
+	onLoanItemResult              = []driver.Value{1, "ITEM-0001", "FAKHRIL-Drama-0001", "A-01", constant.ItemGoodCondition, constant.ItemOnLoanStatus, dateStr}
+	emptyItemResult               = []driver.Value{0, "", "", "", "", "", ""}
+	statusCountRows               = []string{"status", "total"}
+	bookRows                      = []string{"id", "book_id", "title", "genre", "author", "publisher", "publication_year", "status", "timestamp"}
+	bookResult                    = []driver.Value{1, "FAKHRIL-Drama-0001", testStr, testStr, testStr, testStr, dateStr, constant.AvailableStatus, dateStr}
+	emptyBookResult               = []driver.Value{0, "", "", "", "", "", "", "", ""}
+	testStr                       = "test"
+	dateStr                       = "2024-01-01"
+)
+
+type Suite struct {
+	suite.Suite
+	e              *echo.Echo
+	DB             *gorm.DB
+	mock           sqlmock.Sqlmock
+	bookRepository bookDomain.BookRepository
+	itemRepository domain.ItemRepository
+	itemUsecase    domain.ItemUsecase
+	itemHandler    handlers.ItemHandler
+}
+
+func (s *Suite) SetupSuite() {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	s.e = echo.New()
+	s.e.Validator = validator.NewCustomValidator()
+	db, s.mock, err = sqlmock.New()
+	s.Require().NoError(err)
+
+	dialector := mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	})
+
+	s.DB, err = gorm.Open(dialector, &gorm.Config{})
+	s.Require().NoError(err)
+
+	s.bookRepository = bookRepo.NewBookRepository(s.DB)
+	s.itemRepository = repositories.NewItemRepository(s.DB)
+	s.itemUsecase = usecases.NewItemUsecase(s.itemRepository, s.bookRepository, databases.NewUnitOfWork(s.DB))
+	s.itemHandler = handlers.NewItemHandler(s.e, s.itemUsecase)
+}
+
+func (s *Suite) TearDownSuite() {
+	db, err := s.DB.DB()
+	s.Require().NoError(err)
+	db.Close()
+}
+
+func (s *Suite) expectSyncBookStatus(sqlErr error) {
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(statusCountRows).AddRow(constant.ItemAvailableStatus, 1))
+	s.mock.ExpectBegin()
+	if sqlErr != nil {
+		s.mock.ExpectExec("").WithArgs().WillReturnError(sqlErr)
+		s.mock.ExpectRollback()
+	} else {
+		s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+		s.mock.ExpectCommit()
+	}
+}
+
+// expectLockedEdit expects the book and the copy to be locked in a
+// transaction, with the copy in status.
+func (s *Suite) expectLockedEdit(status string) {
+	result := itemResult
+
+	if status == constant.ItemOnLoanStatus {
+		result = onLoanItemResult
+	}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(result...))
+}
+
+func (s *Suite) TestAddItem() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		validatorErr   bool
+		notFound       bool
+		sqlGetBookErr  error
+		sqlErr         error
+		sqlUpdateErr   error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "sql get book error", sqlGetBookErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "book not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql update book error", sqlUpdateErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		var bodyFilepath string
+		if tt.bindErr {
+			bodyFilepath = itemBodyInvalidFilePath
+		} else if tt.validatorErr {
+			bodyFilepath = itemBodyEmptyFilePath
+		} else {
+			bodyFilepath = itemBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, itemEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(itemEndpoint)
+
+		if tt.sqlGetBookErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetBookErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyBookResult...))
+		} else if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else if !tt.bindErr && !tt.validatorErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			s.mock.ExpectBegin()
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+			s.expectSyncBookStatus(tt.sqlUpdateErr)
+		}
+
+		err = s.itemHandler.Add(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestDeleteItem() {
+	tests := []struct {
+		name           string
+		notFound       bool
+		onLoan         bool
+		sqlGetItemErr  error
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "sql get item error", sqlGetItemErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "on loan", onLoan: true, expectedStatus: http.StatusConflict},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, itemEndpoint+"/test", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(itemEndpoint + "/:barcode")
+		c.SetParamNames("barcode")
+		c.SetParamValues(testStr)
+
+		if tt.sqlGetItemErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetItemErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(emptyItemResult...))
+		} else if tt.onLoan {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(onLoanItemResult...))
+			s.expectLockedEdit(constant.ItemOnLoanStatus)
+			s.mock.ExpectRollback()
+		} else if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(itemResult...))
+			s.expectLockedEdit(constant.ItemAvailableStatus)
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(itemResult...))
+			s.expectLockedEdit(constant.ItemAvailableStatus)
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(statusCountRows).AddRow(constant.ItemAvailableStatus, 1))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err := s.itemHandler.Delete(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestGetItem() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		totalErr       bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "total error", totalErr: true, sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("book_id", testStr)
+		q.Set("status", constant.ItemAvailableStatus)
+		q.Set("condition", constant.ItemGoodCondition)
+		q.Set("shelf_location", testStr)
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, itemEndpoint+"?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(itemEndpoint)
+
+		if tt.sqlErr != nil && !tt.bindErr && tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.sqlErr != nil && !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(itemResult...))
+		}
+
+		err := s.itemHandler.Get(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestGetByBarcode() {
+	tests := []struct {
+		name           string
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, itemEndpoint+"/test", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(itemEndpoint + "/:barcode")
+		c.SetParamNames("barcode")
+		c.SetParamValues(testStr)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(itemResult...))
+		}
+
+		err := s.itemHandler.GetByBarcode(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestUpdateItem() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		validatorErr   bool
+		unknownStatus  bool
+		loanStatus     bool
+		notFound       bool
+		onLoan         bool
+		sqlGetItemErr  error
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "sql get item error", sqlGetItemErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "unknown status", unknownStatus: true, expectedStatus: http.StatusBadRequest},
+		{name: "status left to loans", loanStatus: true, expectedStatus: http.StatusBadRequest},
+		{name: "on loan", onLoan: true, expectedStatus: http.StatusConflict},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		var bodyFilepath string
+		if tt.bindErr {
+			bodyFilepath = itemUpdateBodyInvalidFilePath
+		} else if tt.validatorErr {
+			bodyFilepath = itemUpdateBodyEmptyFilePath
+		} else if tt.unknownStatus {
+			bodyFilepath = itemUpdateBodyStatusFilePath
+		} else if tt.loanStatus {
+			bodyFilepath = itemUpdateBodyOnLoanFilePath
+		} else {
+			bodyFilepath = itemUpdateBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPut, itemEndpoint+"/test", jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(itemEndpoint + "/:barcode")
+		c.SetParamNames("barcode")
+		c.SetParamValues(testStr)
+
+		if tt.sqlGetItemErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetItemErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(emptyItemResult...))
+		} else {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(itemResult...))
+		}
+
+		if tt.onLoan {
+			s.expectLockedEdit(constant.ItemOnLoanStatus)
+			s.mock.ExpectRollback()
+		} else if tt.sqlErr != nil {
+			s.expectLockedEdit(constant.ItemAvailableStatus)
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else if !tt.bindErr && !tt.validatorErr && !tt.unknownStatus && !tt.loanStatus && !tt.notFound && tt.sqlGetItemErr == nil {
+			s.expectLockedEdit(constant.ItemAvailableStatus)
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(statusCountRows).AddRow(constant.ItemAvailableStatus, 1))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err = s.itemHandler.Update(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+func (m *ItemAdd) ToItem(e Item) Item {
+	e.Barcode = m.Barcode
+	e.BookID = m.BookID
+	e.ShelfLocation = m.ShelfLocation
+	e.Condition = m.Condition
+	e.Status = constant.ItemAvailableStatus
+	e.Timestamp = utils.ConvertString(utils.GetLocalTime())
+
+	if e.Condition == "" {
+		e.Condition = constant.ItemGoodCondition
+	}
+
+	return e
+}
+
+func (m *ItemUpdate) ToItem(e Item) Item {
+	e.ShelfLocation = m.ShelfLocation
+	e.Condition = m.Condition
+	e.Status = m.Status
+	e.Timestamp = utils.ConvertString(utils.GetLocalTime())
+
+	return e
+}
+
+// BookStatus derives the status of a bibliographic record from how many of
+// its copies are in each status.
+func BookStatus(counts map[string]int64) string {
+	if counts[constant.ItemAvailableStatus] > 0 {
+		return constant.AvailableStatus
+	}
+
+	if counts[constant.ItemOnHoldStatus] > 0 {
+		return constant.OnHoldStatus
+	}
+
+	return constant.NotAvailableStatus
+}
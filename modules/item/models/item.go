@@ -0,0 +1,20 @@
+package models
+
+type Item struct {
+	ID            int64  `json:"id" gorm:"primaryKey"`
+	Barcode       string `json:"barcode"`
+	BookID        string `json:"book_id"`
+	ShelfLocation string `json:"shelf_location"`
+	Condition     string `json:"condition"`
+	Status        string `json:"status"`
+	Timestamp     string `json:"timestamp"`
+}
+
+func (Item) TableName() string {
+	return "item"
+}
+
+type ItemStatusCount struct {
+	Status string
+	Total  int64
+}
@@ -0,0 +1,8 @@
+package models
+
+type ItemAdd struct {
+	Barcode       string `json:"barcode" validate:"required"`
+	BookID        string `json:"book_id" validate:"required"`
+	ShelfLocation string `json:"shelf_location" validate:"required"`
+	Condition     string `json:"condition" validate:"omitempty,oneof=GOOD FAIR POOR"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+
+type ItemFilter struct {
+	BookID        string `json:"book_id" query:"book_id"`
+	Status        string `json:"status" query:"status"`
+	Condition     string `json:"condition" query:"condition"`
+	ShelfLocation string `json:"shelf_location" query:"shelf_location"`
+	utils.PaginationRequest
+}
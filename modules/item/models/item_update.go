@@ -0,0 +1,7 @@
+package models
+
+type ItemUpdate struct {
+	ShelfLocation string `json:"shelf_location" validate:"required"`
+	Condition     string `json:"condition" validate:"required,oneof=GOOD FAIR POOR"`
+	Status        string `json:"status" validate:"required,oneof=AVAILABLE LOST 'IN REPAIR'"`
+}
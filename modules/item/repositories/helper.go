@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	"gorm.io/gorm"
+)
+
+func buildFilterQuery(db *gorm.DB, f models.ItemFilter) *gorm.DB {
+	if f.BookID != "" {
+		db = db.Where("book_id = ?", f.BookID)
+	}
+
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+
+	if f.Condition != "" {
+		db = db.Where(map[string]interface{}{"condition": f.Condition})
+	}
+
+	if f.ShelfLocation != "" {
+		db = db.Where("shelf_location = ?", f.ShelfLocation)
+	}
+
+	return db
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type itemRepository struct {
+	db *gorm.DB
+}
+
+// Add implements domain.ItemRepository.
+func (r *itemRepository) Add(ctx context.Context, data models.Item) (result models.Item, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// CountByStatus implements domain.ItemRepository.
+func (r *itemRepository) CountByStatus(ctx context.Context, book_id string) (result map[string]int64, err error) {
+	var rows []models.ItemStatusCount
+
+	err = databases.Conn(ctx, r.db).Model(&models.Item{}).
+		Select("status, count(*) AS total").
+		Where("book_id = ?", book_id).
+		Group("status").
+		Scan(&rows).Error
+
+	result = make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.Status] = row.Total
+	}
+
+	return
+}
+
+// Delete implements domain.ItemRepository.
+func (r *itemRepository) Delete(ctx context.Context, barcode string) error {
+	return databases.Conn(ctx, r.db).Where("barcode = ?", barcode).Delete(&models.Item{}).Error
+}
+
+// Get implements domain.ItemRepository.
+func (r *itemRepository) Get(ctx context.Context, filter models.ItemFilter) (result []models.Item, total int64, err error) {
+	db := databases.Conn(ctx, r.db)
+	db = buildFilterQuery(db, filter)
+
+	if err = db.Model(&models.Item{}).Count(&total).Error; err != nil {
+		return
+	}
+
+	if !filter.DisablePagination {
+		db = db.Offset(int(filter.GetOffset())).Limit(int(filter.GetLimit()))
+	}
+
+	if err = db.Find(&result).Error; err != nil {
+		return
+	}
+
+	return
+}
+
+// GetAvailableByBookID implements domain.ItemRepository.
+func (r *itemRepository) GetAvailableByBookID(ctx context.Context, book_id string) (result models.Item, err error) {
+	err = databases.Conn(ctx, r.db).Where("book_id = ? AND status = ?", book_id, constant.ItemAvailableStatus).Order("id").Limit(1).Find(&result).Error
+	return
+}
+
+// GetByBarcode implements domain.ItemRepository.
+func (r *itemRepository) GetByBarcode(ctx context.Context, barcode string) (result models.Item, err error) {
+	err = databases.Conn(ctx, r.db).Where("barcode = ?", barcode).First(&result).Error
+	return
+}
+
+// GetByBarcodeForUpdate implements domain.ItemRepository. The item row stays
+// locked until the surrounding transaction ends.
+func (r *itemRepository) GetByBarcodeForUpdate(ctx context.Context, barcode string) (result models.Item, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("barcode = ?", barcode).First(&result).Error
+	return
+}
+
+// Update implements domain.ItemRepository.
+func (r *itemRepository) Update(ctx context.Context, data models.Item) (result models.Item, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+func NewItemRepository(db *gorm.DB) domain.ItemRepository {
+	return &itemRepository{db: db}
+}
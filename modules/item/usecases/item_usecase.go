@@ -0,0 +1,231 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
+	bookModel "github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type itemUsecase struct {
+	itemRepository domain.ItemRepository
+	bookRepository bookDomain.BookRepository
+	unitOfWork     databases.UnitOfWork
+}
+
+// Add implements domain.ItemUsecase.
+func (u *itemUsecase) Add(ctx context.Context, data models.Item) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		book, err := u.bookRepository.GetByBookID(ctx, data.BookID)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if book == (bookModel.Book{}) {
+			output <- utils.Result{Error: httperror.NotFound("Book ID not found")}
+			return
+		}
+
+		result, err := u.itemRepository.Add(ctx, data)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if err = u.syncBookStatus(ctx, book); err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// Delete implements domain.ItemUsecase. Copies on loan or on hold can not
+// be deleted.
+func (u *itemUsecase) Delete(ctx context.Context, barcode string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		item, err := u.itemRepository.GetByBarcode(ctx, barcode)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if item == (models.Item{}) {
+			output <- utils.Result{Error: httperror.NotFound(httperror.NotFoundErrorMessage)}
+			return
+		}
+
+		err = u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			book, err := u.bookRepository.GetByBookIDForUpdate(ctx, item.BookID)
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if _, err = u.getEditableItem(ctx, barcode); err != nil {
+				return err
+			}
+
+			if err = u.itemRepository.Delete(ctx, barcode); err != nil {
+				return err
+			}
+
+			if book == (bookModel.Book{}) {
+				return nil
+			}
+
+			return u.syncBookStatus(ctx, book)
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{}
+	}()
+
+	return output
+}
+
+// Get implements domain.ItemUsecase.
+func (u *itemUsecase) Get(ctx context.Context, filter models.ItemFilter) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, total, err := u.itemRepository.Get(ctx, filter)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result, Total: total}
+	}()
+
+	return output
+}
+
+// GetByBarcode implements domain.ItemUsecase.
+func (u *itemUsecase) GetByBarcode(ctx context.Context, barcode string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, err := u.itemRepository.GetByBarcode(ctx, barcode)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// Update implements domain.ItemUsecase. Copies on loan or on hold can not
+// be edited, their status follows the loan or the reservation.
+func (u *itemUsecase) Update(ctx context.Context, data models.Item) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var result models.Item
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			book, err := u.bookRepository.GetByBookIDForUpdate(ctx, data.BookID)
+
+			if err != nil {
+				return err
+			}
+
+			item, err := u.getEditableItem(ctx, data.Barcode)
+
+			if err != nil {
+				return err
+			}
+
+			data.ID = item.ID
+			data.BookID = item.BookID
+
+			if result, err = u.itemRepository.Update(ctx, data); err != nil {
+				return err
+			}
+
+			return u.syncBookStatus(ctx, book)
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// getEditableItem returns the copy of barcode, locked until the surrounding
+// transaction ends, unless a loan or a reservation holds it.
+func (u *itemUsecase) getEditableItem(ctx context.Context, barcode string) (models.Item, error) {
+	item, err := u.itemRepository.GetByBarcodeForUpdate(ctx, barcode)
+
+	if err != nil {
+		return item, err
+	}
+
+	if item.Status == constant.ItemOnLoanStatus || item.Status == constant.ItemOnHoldStatus {
+		return item, httperror.Conflict("Copy is on loan or on hold")
+	}
+
+	return item, nil
+}
+
+// syncBookStatus recomputes the book status from the copies that are
+// currently available for lending.
+func (u *itemUsecase) syncBookStatus(ctx context.Context, book bookModel.Book) error {
+	counts, err := u.itemRepository.CountByStatus(ctx, book.BookID)
+
+	if err != nil {
+		return err
+	}
+
+	book.Status = models.BookStatus(counts)
+
+	_, err = u.bookRepository.Update(ctx, book)
+	return err
+}
+
+func NewItemUsecase(itemRepository domain.ItemRepository, bookRepository bookDomain.BookRepository, unitOfWork databases.UnitOfWork) domain.ItemUsecase {
+	return &itemUsecase{itemRepository: itemRepository, bookRepository: bookRepository, unitOfWork: unitOfWork}
+}
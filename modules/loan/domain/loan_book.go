@@ -11,16 +11,25 @@ type LoanBookRepository interface {
 	Add(ctx context.Context, data models.LoanBook) (models.LoanBook, error)
 	Get(ctx context.Context, filter models.LoanBookFilter) ([]models.LoanBook, int64, error)
 	GetByLoanID(ctx context.Context, loan_id string) (models.LoanBook, error)
-	GetLast(ctx context.Context, username string) (models.LoanBook, error)
+	GetByLoanIDForUpdate(ctx context.Context, loan_id string) (models.LoanBook, error)
+	CountActive(ctx context.Context, username string) (int64, error)
+	CountOverdue(ctx context.Context, username string, date string) (int64, error)
+	CountBorrowed(ctx context.Context, date string) (active int64, overdue int64, err error)
+	GetOverdue(ctx context.Context, date string, filter models.LoanBookFilter) ([]models.LoanBook, int64, error)
 	Update(ctx context.Context, data models.LoanBook) (models.LoanBook, error)
 	Delete(ctx context.Context, loan_id string) error
+	AddRenewal(ctx context.Context, data models.LoanRenewal) (models.LoanRenewal, error)
+	GetRenewals(ctx context.Context, loan_id string) ([]models.LoanRenewal, error)
 }
 
 type LoanBookUsecase interface {
 	Get(ctx context.Context, filter models.LoanBookFilter) <-chan utils.Result
-	GetLast(ctx context.Context, user string) <-chan utils.Result
 	GetByLoanID(ctx context.Context, loan_id string) <-chan utils.Result
+	GetOverdue(ctx context.Context, filter models.LoanBookFilter) <-chan utils.Result
+	AccrueFines(ctx context.Context) <-chan utils.Result
 	Add(ctx context.Context, data models.LoanBook) <-chan utils.Result
 	Update(ctx context.Context, data models.LoanBook) <-chan utils.Result
 	Delete(ctx context.Context, loan_id string) <-chan utils.Result
+	Renew(ctx context.Context, data models.LoanBook, renewedBy string) <-chan utils.Result
+	GetRenewals(ctx context.Context, loan_id string) <-chan utils.Result
 }
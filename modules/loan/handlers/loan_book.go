@@ -18,6 +18,9 @@ type LoanBookHandler interface {
 	Delete(c echo.Context) error
 	Get(c echo.Context) error
 	GetByLoanID(c echo.Context) error
+	GetOverdue(c echo.Context) error
+	GetRenewals(c echo.Context) error
+	Renew(c echo.Context) error
 	Update(c echo.Context) error
 }
 
@@ -28,12 +31,15 @@ type loanBookHandler struct {
 func NewLoanBookHandler(e *echo.Echo, loanBookUsecase domain.LoanBookUsecase) LoanBookHandler {
 	handler := &loanBookHandler{loanBookUsecase: loanBookUsecase}
 
-	group := e.Group("/loan-book", middlewares.VerifyJWTRSA(config.Config().PublicKey), middlewares.EchoSetCredential())
-	group.POST("", handler.Add)
-	group.DELETE("/:loan-id", handler.Delete)
-	group.GET("", handler.Get)
-	group.GET("/:loan-id", handler.GetByLoanID)
-	group.PUT("/:loan-id", handler.Update)
+	group := e.Group("/loan-book", middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()))
+	group.POST("", handler.Add, middlewares.RequirePermission(constant.LoanWrite))
+	group.DELETE("/:loan-id", handler.Delete, middlewares.RequirePermission(constant.LoanDelete))
+	group.GET("", handler.Get, middlewares.RequirePermission(constant.LoanRead))
+	group.GET("/overdue", handler.GetOverdue, middlewares.RequirePermission(constant.LoanRead))
+	group.GET("/:loan-id", handler.GetByLoanID, middlewares.RequirePermission(constant.LoanRead))
+	group.PUT("/:loan-id", handler.Update, middlewares.RequirePermission(constant.LoanManage))
+	group.POST("/:loan-id/renew", handler.Renew, middlewares.RequirePermission(constant.LoanWrite))
+	group.GET("/:loan-id/renewals", handler.GetRenewals, middlewares.RequirePermission(constant.LoanRead))
 
 	return handler
 }
@@ -47,25 +53,17 @@ func (h *loanBookHandler) Add(c echo.Context) error {
 	}
 
 	if err := c.Validate(data); err != nil {
-		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		return utils.ResponseError(err, c)
 	}
 
-	result := <-h.loanBookUsecase.GetLast(c.Request().Context(), data.Username)
-
-	if result.Error != nil {
-		return utils.ResponseError(result.Error, c)
-	}
-
-	expend := result.Data.(models.LoanBook)
-
-	if expend == (models.LoanBook{}) {
-		expend.Username = data.Username
+	if !canAccess(c, data.Username) {
+		return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
 	}
 
-	expend = data.ToLoanBook(expend)
+	expend := data.ToLoanBook(models.LoanBook{})
 	expend.Status = constant.LoanBorrowedStatus
 
-	result = <-h.loanBookUsecase.Add(c.Request().Context(), expend)
+	result := <-h.loanBookUsecase.Add(c.Request().Context(), expend)
 
 	if result.Error != nil {
 		return utils.ResponseError(result.Error, c)
@@ -78,12 +76,6 @@ func (h *loanBookHandler) Add(c echo.Context) error {
 func (h *loanBookHandler) Delete(c echo.Context) error {
 	loan_id := utils.ConvertString(c.Param("loan-id"))
 
-	role := c.Get("role").(string)
-
-	if role != constant.Admin && role != constant.SuperAdmin {
-		return utils.ResponseError(httperror.Unauthorized(httperror.UnauthorizedErrorMessage), c)
-	}
-
 	result := <-h.loanBookUsecase.Delete(c.Request().Context(), loan_id)
 
 	if result.Error != nil {
@@ -105,6 +97,8 @@ func (h *loanBookHandler) Get(c echo.Context) error {
 		filter.SetDefault()
 	}
 
+	scopeToCaller(c, filter)
+
 	result := <-h.loanBookUsecase.Get(c.Request().Context(), *filter)
 
 	if result.Error != nil {
@@ -124,10 +118,100 @@ func (h *loanBookHandler) GetByLoanID(c echo.Context) error {
 		return utils.ResponseError(result.Error, c)
 	}
 
+	if expend, ok := result.Data.(models.LoanBook); ok && expend != (models.LoanBook{}) && !canAccess(c, expend.Username) {
+		return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
+	}
+
 	return utils.Response(result.Data, "Get loan book success", http.StatusOK, c)
 }
 
-// Update implements LoanBookHandler.
+// GetOverdue implements LoanBookHandler.
+func (h *loanBookHandler) GetOverdue(c echo.Context) error {
+	filter := new(models.LoanBookFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	scopeToCaller(c, filter)
+
+	result := <-h.loanBookUsecase.GetOverdue(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get overdue loan book success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// GetRenewals implements LoanBookHandler.
+func (h *loanBookHandler) GetRenewals(c echo.Context) error {
+	loan_id := utils.ConvertString(c.Param("loan-id"))
+
+	if !canManage(c) {
+		result := <-h.loanBookUsecase.GetByLoanID(c.Request().Context(), loan_id)
+
+		if result.Error != nil {
+			return utils.ResponseError(result.Error, c)
+		}
+
+		expend := result.Data.(models.LoanBook)
+
+		if expend == (models.LoanBook{}) {
+			return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+		}
+
+		if !canAccess(c, expend.Username) {
+			return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
+		}
+	}
+
+	result := <-h.loanBookUsecase.GetRenewals(c.Request().Context(), loan_id)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Get loan book renewals success", http.StatusOK, c)
+}
+
+// Renew implements LoanBookHandler.
+func (h *loanBookHandler) Renew(c echo.Context) error {
+	loan_id := utils.ConvertString(c.Param("loan-id"))
+
+	result := <-h.loanBookUsecase.GetByLoanID(c.Request().Context(), loan_id)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	expend := result.Data.(models.LoanBook)
+
+	if expend == (models.LoanBook{}) {
+		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+	}
+
+	username := utils.ConvertString(c.Get("username"))
+
+	if !canAccess(c, expend.Username) {
+		return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
+	}
+
+	result = <-h.loanBookUsecase.Renew(c.Request().Context(), expend, username)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Renew loan book success", http.StatusOK, c)
+}
+
+// Update implements LoanBookHandler. Only loan managers may edit a loan;
+// members extend their own loans through Renew.
 func (h *loanBookHandler) Update(c echo.Context) error {
 	loan_id := utils.ConvertString(c.Param("loan-id"))
 
@@ -143,14 +227,14 @@ func (h *loanBookHandler) Update(c echo.Context) error {
 		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
 	}
 
-	data := new(models.LoanBookAdd)
+	data := new(models.LoanBookUpdate)
 
 	if err := c.Bind(data); err != nil {
 		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
 	}
 
 	if err := c.Validate(data); err != nil {
-		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		return utils.ResponseError(err, c)
 	}
 
 	expend = data.ToLoanBook(expend)
@@ -163,3 +247,22 @@ func (h *loanBookHandler) Update(c echo.Context) error {
 
 	return utils.Response(result.Data, "Update loan book success", http.StatusOK, c)
 }
+
+// canManage reports whether the caller may act on loans of other users.
+func canManage(c echo.Context) bool {
+	return middlewares.Can(c, constant.LoanManage)
+}
+
+// canAccess reports whether the caller may act on the loans of username:
+// their own, or anyone's when they can manage loans.
+func canAccess(c echo.Context, username string) bool {
+	return username == utils.ConvertString(c.Get("username")) || canManage(c)
+}
+
+// scopeToCaller limits filter to the loans of the caller unless they can
+// manage loans, e.g. for KARYAWAN.
+func scopeToCaller(c echo.Context, filter *models.LoanBookFilter) {
+	if !canManage(c) {
+		filter.User = utils.ConvertString(c.Get("username"))
+	}
+}
@@ -0,0 +1,46 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/migration"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestConcurrentLoansWithRowLocks races two lenders for the single copy of
+// a book on a server with row locks. TEST_DB_DRIVER is mysql or postgres and
+// TEST_DB_DSN points to an empty scratch database, e.g.
+//
+//	TEST_DB_DRIVER=postgres TEST_DB_DSN=postgres://... go test -tags integration ./modules/loan/...
+func TestConcurrentLoansWithRowLocks(t *testing.T) {
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_DSN")
+
+	if driver == "" || dsn == "" {
+		t.Skip("TEST_DB_DRIVER and TEST_DB_DSN are not set")
+	}
+
+	dialector, err := databases.Dialector(driver, dsn)
+	require.NoError(t, err)
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	files, err := migration.Files(driver)
+	require.NoError(t, err)
+
+	migrator, err := migration.New(db, files)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	errs := lendConcurrently(t, db)
+	requireOneLoan(t, db, errs)
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bookModel "github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	bookRepo "github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
+	fineRepo "github.com/Zeroaril7/perpustakaan-go/modules/fine/repositories"
+	itemModel "github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	itemRepo "github.com/Zeroaril7/perpustakaan-go/modules/item/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/usecases"
+	reservationRepo "github.com/Zeroaril7/perpustakaan-go/modules/reservation/repositories"
+	userRepo "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases/dbtest"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// SQLite serialises writers with immediate transactions, so it can not show
+// a race between two lenders. It still checks that the book row is read for
+// update, which is what keeps MySQL and PostgreSQL from lending one copy
+// twice; the race itself is covered by the integration build tag.
+func TestConcurrentLoansForOneBook(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+
+	var reads, locked int32
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:check_book_lock", func(tx *gorm.DB) {
+		if tx.Statement.Table != "book" {
+			return
+		}
+
+		atomic.AddInt32(&reads, 1)
+
+		if _, ok := tx.Statement.Clauses["FOR"]; ok {
+			atomic.AddInt32(&locked, 1)
+		}
+	}))
+
+	errs := lendConcurrently(t, db)
+
+	require.Equal(t, int32(2), atomic.LoadInt32(&reads))
+	require.Equal(t, int32(2), atomic.LoadInt32(&locked), "the book must be read for update")
+	requireOneLoan(t, db, errs)
+}
+
+// lendConcurrently lends the single copy of a book to two members at once
+// and returns the error of each attempt. Each lender is held after it reads
+// the copies until the other one has read them too, so that without a lock
+// both would see the copy as available.
+func lendConcurrently(t *testing.T, db *gorm.DB) []interface{} {
+	require.NoError(t, db.Create(&bookModel.Book{BookID: "FAKHRIL-Drama-0001", Title: testStr, Genre: "Drama", Status: constant.AvailableStatus}).Error)
+	require.NoError(t, db.Create(&itemModel.Item{Barcode: "ITEM-0001", BookID: "FAKHRIL-Drama-0001", Status: constant.ItemAvailableStatus}).Error)
+
+	var readers int32
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:wait_for_readers", func(tx *gorm.DB) {
+		if tx.Statement.Table != "item" {
+			return
+		}
+
+		atomic.AddInt32(&readers, 1)
+
+		for i := 0; i < 20 && atomic.LoadInt32(&readers) < 2; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+
+	loanBookUsecase := usecases.NewLoanBookUsecase(
+		repositories.NewLoanBookRepository(db),
+		bookRepo.NewBookRepository(db),
+		itemRepo.NewItemRepository(db),
+		reservationRepo.NewReservationRepository(db),
+		fineRepo.NewFineRepository(db),
+		userRepo.NewUserRepository(db),
+		databases.NewUnitOfWork(db),
+		sequence.New(db, constant.DefaultSequenceWidth),
+	)
+
+	var wg sync.WaitGroup
+	usernames := []string{"first", "second"}
+	errs := make([]interface{}, len(usernames))
+
+	for i, username := range usernames {
+		wg.Add(1)
+
+		go func(i int, username string) {
+			defer wg.Done()
+
+			result := <-loanBookUsecase.Add(context.Background(), models.LoanBook{
+				BookID:        "FAKHRIL-Drama-0001",
+				Username:      username,
+				LoanStartDate: dateStr,
+				LoanEndDate:   dueDateStr,
+				Status:        constant.LoanBorrowedStatus,
+			})
+
+			errs[i] = result.Error
+		}(i, username)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// requireOneLoan checks that exactly one of errs is a conflict and that the
+// copy went out on a single loan.
+func requireOneLoan(t *testing.T, db *gorm.DB, errs []interface{}) {
+	var success, refused int
+
+	for _, err := range errs {
+		if err == nil {
+			success++
+			continue
+		}
+
+		coded, ok := err.(interface{ Code() int })
+		require.True(t, ok, err)
+		require.Equal(t, http.StatusConflict, coded.Code(), err)
+		refused++
+	}
+
+	require.Equal(t, 1, success)
+	require.Equal(t, 1, refused)
+
+	var loans int64
+	require.NoError(t, db.Model(&models.LoanBook{}).Count(&loans).Error)
+	require.Equal(t, int64(1), loans)
+
+	var item itemModel.Item
+	require.NoError(t, db.Where("barcode = ?", "ITEM-0001").First(&item).Error)
+	require.Equal(t, constant.ItemOnLoanStatus, item.Status)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"net/http"
@@ -8,15 +9,27 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
 	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
 	bookRepo "github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
+	fineDomain "github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	fineRepo "github.com/Zeroaril7/perpustakaan-go/modules/fine/repositories"
+	itemDomain "github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	itemRepo "github.com/Zeroaril7/perpustakaan-go/modules/item/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/handlers"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/usecases"
+	reservationDomain "github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	reservationRepo "github.com/Zeroaril7/perpustakaan-go/modules/reservation/repositories"
+	userDomain "github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
+	userRepo "github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/suite"
@@ -25,29 +38,60 @@ import (
 )
 
 var (
-	loanBookEndpoint            = "/loan-book"
-	loanBookBodyFilePath        = "test_data/loan_book_body_req.json"
-	loanBookBodyInvalidFilePath = "test_data/loan_book_body_invalid_req.json"
-	loanBookBodyEmptyFilePath   = "test_data/loan_book_body_empty_req.json"
-	loanBookRows                = []string{"id", "loan_id", "book_id", "title", "username", "loan_start_date", "loan_end_date", "status"}
-	loanBookResult              = []driver.Value{1, "LOAN-TEST-0001", "TEST-DRAMA-0001", testStr, testStr, dateStr, dateStr, constant.LoanBorrowedStatus}
-	emptyLoanBookResult         = []driver.Value{0, "", "", "", "", "", "", ""}
-	bookRows                    = []string{"id", "book_id", "title", "genre", "author", "publisher", "publication_year", "status", "timestamp"}
-	bookResult                  = []driver.Value{1, "TEST-DRAMA-0001", testStr, testStr, testStr, testStr, dateStr, constant.AvailableStatus, dateStr}
-	emptyBookResult             = []driver.Value{0, "", "", "", "", "", "", "", ""}
-	testStr                     = "test"
-	dateStr                     = "2024-01-01"
+	loanBookEndpoint                  = "/loan-book"
+	loanBookBodyFilePath              = "test_data/loan_book_body_req.json"
+	loanBookBodyInvalidFilePath       = "test_data/loan_book_body_invalid_req.json"
+	loanBookBodyEmptyFilePath         = "test_data/loan_book_body_empty_req.json"
+	loanBookBodyDateFilePath          = "test_data/loan_book_body_invalid_date_req.json"
+	loanBookUpdateBodyFilePath        = "test_data/loan_book_update_body_req.json"
+	loanBookUpdateBodyInvalidFilePath = "test_data/loan_book_update_body_invalid_req.json"
+	loanBookUpdateBodyEmptyFilePath   = "test_data/loan_book_update_body_empty_req.json"
+	loanBookUpdateBodyStatusFilePath  = "test_data/loan_book_update_body_unknown_status_req.json"
+	loanBookUpdateBodyReopenFilePath  = "test_data/loan_book_update_body_reopen_req.json"
+	loanBookRows                      = []string{"id", "loan_id", "book_id", "barcode", "title", "username", "loan_start_date", "loan_end_date", "status", "renewal_count"}
+	loanBookResult                    = []driver.Value{1, "LOAN-TEST-0001", "TEST-DRAMA-0001", "ITEM-0001", testStr, testStr, dateStr, dateStr, constant.LoanBorrowedStatus, 0}
+	emptyLoanBookResult               = []driver.Value{0, "", "", "", "", "", "", "", "", 0}
+	dueDateStr                        = time.Now().AddDate(0, 0, 3).Format(constant.LoanDateLayout)
+	renewableLoanBookResult           = []driver.Value{1, "LOAN-TEST-0001", "TEST-DRAMA-0001", "ITEM-0001", testStr, testStr, dateStr, dueDateStr, constant.LoanBorrowedStatus, 0}
+	renewedLoanBookResult             = []driver.Value{1, "LOAN-TEST-0001", "TEST-DRAMA-0001", "ITEM-0001", testStr, testStr, dateStr, dueDateStr, constant.LoanBorrowedStatus, constant.DefaultMaxRenewals}
+	returnedLoanBookResult            = []driver.Value{1, "LOAN-TEST-0001", "TEST-DRAMA-0001", "ITEM-0001", testStr, testStr, dateStr, dueDateStr, constant.LoanReturnedStatus, 0}
+	loanRenewalRows                   = []string{"id", "loan_id", "previous_end_date", "new_end_date", "renewed_by", "renewed_at"}
+	loanRenewalResult                 = []driver.Value{1, "LOAN-TEST-0001", dateStr, dateStr, testStr, time.Now()}
+	bookRows                          = []string{"id", "book_id", "title", "genre", "author", "publisher", "publication_year", "status", "timestamp"}
+	bookResult                        = []driver.Value{1, "TEST-DRAMA-0001", testStr, testStr, testStr, testStr, dateStr, constant.AvailableStatus, dateStr}
+	unavailableBookResult             = []driver.Value{1, "TEST-DRAMA-0001", testStr, testStr, testStr, testStr, dateStr, constant.NotAvailableStatus, dateStr}
+	emptyBookResult                   = []driver.Value{0, "", "", "", "", "", "", "", ""}
+	itemRows                          = []string{"id", "barcode", "book_id", "shelf_location", "condition", "status", "timestamp"}
+	itemResult                        = []driver.Value{1, "ITEM-0001", "Drama-0004", "A-01", constant.ItemGoodCondition, constant.ItemAvailableStatus, dateStr}
+	heldItemResult                    = []driver.Value{2, "ITEM-0002", "Drama-0004", "A-01", constant.ItemGoodCondition, constant.ItemOnHoldStatus, dateStr}
+	loanedItemResult                  = []driver.Value{1, "ITEM-0001", "Drama-0004", "A-01", constant.ItemGoodCondition, constant.ItemOnLoanStatus, dateStr}
+	statusCountRows                   = []string{"status", "total"}
+	reservationRows                   = []string{"id", "book_id", "username", "barcode", "status", "queued_at", "ready_at", "pickup_deadline"}
+	readyReservationResult            = []driver.Value{1, "Drama-0004", testStr, "ITEM-0002", constant.ReservationReadyStatus, time.Now(), time.Now(), time.Now()}
+	waitingReservationResult          = []driver.Value{2, "Drama-0004", "other", "", constant.ReservationWaitingStatus, time.Now(), nil, nil}
+	fineRows                          = []string{"id", "loan_id", "username", "book_id", "days_late", "rate", "amount", "status", "updated_at"}
+	accruingFineResult                = []driver.Value{1, "LOAN-TEST-0001", testStr, "TEST-DRAMA-0001", 1, 1000, 1000, constant.FineAccruingStatus, time.Now()}
+	unpaidFineResult                  = []driver.Value{1, "LOAN-TEST-0001", testStr, "TEST-DRAMA-0001", 1, 1000, 1000, constant.FineUnpaidStatus, time.Now()}
+	userRows                          = []string{"id", "username", "password", "role"}
+	userResult                        = []driver.Value{1, testStr, testStr, constant.Karyawan}
+	sequenceRows                      = []string{"value"}
+	testStr                           = "test"
+	dateStr                           = "2024-01-01"
 )
 
 type Suite struct {
 	suite.Suite
-	e                  *echo.Echo
-	DB                 *gorm.DB
-	mock               sqlmock.Sqlmock
-	bookRepository     bookDomain.BookRepository
-	loanBookRepository domain.LoanBookRepository
-	loanBookUsecase    domain.LoanBookUsecase
-	loanBookHandler    handlers.LoanBookHandler
+	e                     *echo.Echo
+	DB                    *gorm.DB
+	mock                  sqlmock.Sqlmock
+	bookRepository        bookDomain.BookRepository
+	itemRepository        itemDomain.ItemRepository
+	reservationRepository reservationDomain.ReservationRepository
+	fineRepository        fineDomain.FineRepository
+	userRepository        userDomain.UserRepository
+	loanBookRepository    domain.LoanBookRepository
+	loanBookUsecase       domain.LoanBookUsecase
+	loanBookHandler       handlers.LoanBookHandler
 }
 
 func (s *Suite) SetupSuite() {
@@ -70,8 +114,12 @@ func (s *Suite) SetupSuite() {
 	s.Require().NoError(err)
 
 	s.bookRepository = bookRepo.NewBookRepository(s.DB)
+	s.itemRepository = itemRepo.NewItemRepository(s.DB)
 	s.loanBookRepository = repositories.NewLoanBookRepository(s.DB)
-	s.loanBookUsecase = usecases.NewLoanBookUsecase(s.loanBookRepository, s.bookRepository)
+	s.reservationRepository = reservationRepo.NewReservationRepository(s.DB)
+	s.fineRepository = fineRepo.NewFineRepository(s.DB)
+	s.userRepository = userRepo.NewUserRepository(s.DB)
+	s.loanBookUsecase = usecases.NewLoanBookUsecase(s.loanBookRepository, s.bookRepository, s.itemRepository, s.reservationRepository, s.fineRepository, s.userRepository, databases.NewUnitOfWork(s.DB), sequence.New(s.DB, constant.DefaultSequenceWidth))
 	s.loanBookHandler = handlers.NewLoanBookHandler(s.e, s.loanBookUsecase)
 }
 
@@ -83,32 +131,48 @@ func (s *Suite) TearDownSuite() {
 
 func (s *Suite) TestAddLoanBook() {
 	var tests = []struct {
-		name           string
-		bindErr        bool
-		validatorErr   bool
-		notFound       bool
-		sqlGetDataErr  error
-		sqlGetLastErr  error
-		sqlErr         error
-		sqlUpdateErr   error
-		expectedStatus int
+		name             string
+		bindErr          bool
+		validatorErr     bool
+		invalidDate      bool
+		notFound         bool
+		noCopy           bool
+		reserved         bool
+		ineligible       bool
+		unavailable      bool
+		otherUser        bool
+		sqlGetDataErr    error
+		sqlSequenceErr   error
+		sqlErr           error
+		sqlUpdateItemErr error
+		sqlUpdateErr     error
+		expectedStatus   int
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
-		{name: "sql get last error", sqlGetLastErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql get data error", sqlGetDataErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
 		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "invalid end date", validatorErr: true, invalidDate: true, expectedStatus: http.StatusBadRequest},
+		{name: "other user", otherUser: true, expectedStatus: http.StatusForbidden},
 		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "no available copy", noCopy: true, expectedStatus: http.StatusConflict},
+		{name: "reserved copy", reserved: true, expectedStatus: http.StatusOK},
+		{name: "not eligible", ineligible: true, expectedStatus: http.StatusConflict},
+		{name: "book not available", ineligible: true, unavailable: true, expectedStatus: http.StatusConflict},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql update item error", sqlUpdateItemErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql update error", sqlUpdateErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql sequence error", sqlSequenceErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 	}
 
 	for _, tt := range tests {
 		var bodyFilepath string
 		if tt.bindErr {
 			bodyFilepath = loanBookBodyInvalidFilePath
+		} else if tt.invalidDate {
+			bodyFilepath = loanBookBodyDateFilePath
 		} else if tt.validatorErr {
 			bodyFilepath = loanBookBodyEmptyFilePath
 		} else {
@@ -126,44 +190,108 @@ func (s *Suite) TestAddLoanBook() {
 		c := s.e.NewContext(req, rec)
 
 		c.SetPath(loanBookEndpoint)
+		c.Set("role", constant.Karyawan)
 
-		if tt.sqlErr == nil && tt.sqlGetLastErr != nil && tt.sqlGetDataErr == nil && tt.sqlUpdateErr == nil && !tt.bindErr && !tt.validatorErr && !tt.notFound {
-			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetLastErr)
-		} else if !tt.bindErr && !tt.validatorErr && tt.sqlGetDataErr != nil && tt.sqlErr == nil && tt.sqlUpdateErr == nil && tt.sqlGetLastErr == nil && !tt.notFound {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetDataErr)
-		} else if tt.sqlErr == nil && tt.sqlUpdateErr == nil && !tt.bindErr && !tt.validatorErr && tt.sqlGetDataErr == nil && tt.sqlGetLastErr == nil && tt.notFound {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyBookResult...))
-		} else if !tt.bindErr && !tt.validatorErr && tt.sqlGetDataErr == nil && tt.sqlUpdateErr == nil && tt.sqlErr != nil && tt.sqlGetLastErr == nil && !tt.notFound {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
-			s.mock.ExpectRollback()
-		} else if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetDataErr == nil && tt.sqlUpdateErr != nil && tt.sqlErr == nil && tt.sqlGetLastErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-			s.mock.ExpectCommit()
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlUpdateErr)
-			s.mock.ExpectRollback()
-		} else if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetDataErr == nil && tt.sqlUpdateErr == nil && tt.sqlErr == nil && tt.sqlGetLastErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-			s.mock.ExpectCommit()
+		if tt.otherUser {
+			c.Set("username", "other")
+		} else {
+			c.Set("username", testStr)
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser {
 			s.mock.ExpectBegin()
+			if tt.sqlGetDataErr != nil {
+				s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetDataErr)
+			} else if tt.notFound {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyBookResult...))
+			} else if tt.unavailable {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(unavailableBookResult...))
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			}
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser && tt.sqlGetDataErr == nil && !tt.notFound {
+			if tt.reserved {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(readyReservationResult...))
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows))
+			}
+
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+
+			if tt.ineligible {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(constant.DefaultLoanLimit))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(5000))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(0))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(0))
+			}
+
+			if tt.reserved {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(heldItemResult...))
+			} else if tt.noCopy {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows))
+			} else if !tt.ineligible {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(itemResult...))
+			}
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser && tt.sqlGetDataErr == nil && !tt.notFound && !tt.noCopy && !tt.ineligible {
+			if tt.sqlSequenceErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlSequenceErr)
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(sequenceRows).AddRow(1))
+			}
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser && tt.sqlGetDataErr == nil && !tt.notFound && !tt.noCopy && !tt.ineligible && tt.sqlSequenceErr == nil {
+			if tt.sqlErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+		}
+
+		if tt.reserved {
 			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-			s.mock.ExpectCommit()
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser && tt.sqlGetDataErr == nil && !tt.notFound && !tt.noCopy && !tt.ineligible && tt.sqlSequenceErr == nil && tt.sqlErr == nil {
+			if tt.sqlUpdateItemErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlUpdateItemErr)
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser && tt.sqlGetDataErr == nil && !tt.notFound && !tt.noCopy && !tt.ineligible && tt.sqlSequenceErr == nil && tt.sqlErr == nil && tt.sqlUpdateItemErr == nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(statusCountRows))
+			if tt.sqlUpdateErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlUpdateErr)
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.otherUser {
+			if tt.expectedStatus == http.StatusOK {
+				s.mock.ExpectCommit()
+			} else {
+				s.mock.ExpectRollback()
+			}
 		}
 
 		err = s.loanBookHandler.Add(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
+
+		if tt.ineligible {
+			s.Require().Contains(rec.Body.String(), constant.OverdueLoansReason)
+		}
 	}
 }
 
@@ -180,7 +308,7 @@ func (s *Suite) TestDeleteLoanBook() {
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
-		{name: "role error", roleErr: true, expectedStatus: http.StatusUnauthorized},
+		{name: "role error", roleErr: true, expectedStatus: http.StatusForbidden},
 	}
 
 	for _, tt := range tests {
@@ -212,7 +340,7 @@ func (s *Suite) TestDeleteLoanBook() {
 			s.mock.ExpectCommit()
 		}
 
-		err := s.loanBookHandler.Delete(c)
+		err := middlewares.RequirePermission(constant.LoanDelete)(s.loanBookHandler.Delete)(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
 	}
@@ -282,6 +410,7 @@ func (s *Suite) TestGetLoanBook() {
 func (s *Suite) TestGetByLoanID() {
 	tests := []struct {
 		name           string
+		otherUser      bool
 		sqlErr         error
 		expectedStatus int
 	}{
@@ -289,6 +418,7 @@ func (s *Suite) TestGetByLoanID() {
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "other user", otherUser: true, expectedStatus: http.StatusForbidden},
 	}
 
 	for _, tt := range tests {
@@ -300,6 +430,13 @@ func (s *Suite) TestGetByLoanID() {
 		c.SetPath(loanBookEndpoint + "/:loan-id")
 		c.SetParamNames("loan-id")
 		c.SetParamValues(testStr)
+		c.Set("role", constant.Karyawan)
+
+		if tt.otherUser {
+			c.Set("username", "other")
+		} else {
+			c.Set("username", testStr)
+		}
 
 		if tt.sqlErr != nil {
 			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
@@ -313,12 +450,250 @@ func (s *Suite) TestGetByLoanID() {
 	}
 }
 
+func (s *Suite) TestGetOverdueLoanBook() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		totalErr       bool
+		sqlErr         error
+		sqlGetUserErr  error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "total error", totalErr: true, sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql get user error", sqlGetUserErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("user", testStr)
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, loanBookEndpoint+"/overdue?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(loanBookEndpoint + "/overdue")
+
+		if tt.sqlErr != nil && tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if !tt.bindErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			if tt.sqlGetUserErr != nil {
+				s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetUserErr)
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+			}
+		}
+
+		err := s.loanBookHandler.GetOverdue(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
+func (s *Suite) TestAccrueFines() {
+	invalidDateResult := []driver.Value{2, "LOAN-TEST-0002", "TEST-DRAMA-0001", "ITEM-0002", testStr, testStr, dateStr, "next week", constant.LoanBorrowedStatus, 0}
+
+	// The loan with an end date that cannot be parsed is skipped
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(2))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(invalidDateResult...).AddRow(loanBookResult...))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows).AddRow(accruingFineResult...))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit()
+
+	result := <-s.loanBookUsecase.AccrueFines(context.Background())
+	s.Require().Nil(result.Error)
+	s.Require().Equal(int64(2), result.Total)
+	s.Require().NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *Suite) TestRenewLoanBook() {
+	tests := []struct {
+		name              string
+		loan              []driver.Value
+		otherUser         bool
+		notFound          bool
+		waiting           bool
+		sqlGetLoanIDErr   error
+		sqlReservationErr error
+		sqlErr            error
+		sqlAddRenewalErr  error
+		expectedStatus    int
+	}{
+		{name: "success", loan: renewableLoanBookResult, expectedStatus: http.StatusOK},
+		{name: "success", loan: renewableLoanBookResult, expectedStatus: http.StatusOK},
+		{name: "sql get loan id error", sqlGetLoanIDErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "other user", loan: renewableLoanBookResult, otherUser: true, expectedStatus: http.StatusForbidden},
+		{name: "returned loan", loan: returnedLoanBookResult, expectedStatus: http.StatusConflict},
+		{name: "renewal limit", loan: renewedLoanBookResult, expectedStatus: http.StatusConflict},
+		{name: "overdue", loan: loanBookResult, expectedStatus: http.StatusConflict},
+		{name: "active reservation", loan: renewableLoanBookResult, waiting: true, expectedStatus: http.StatusConflict},
+		{name: "sql reservation error", loan: renewableLoanBookResult, sqlReservationErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", loan: renewableLoanBookResult, sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql add renewal error", loan: renewableLoanBookResult, sqlAddRenewalErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, loanBookEndpoint+"/test/renew", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(loanBookEndpoint + "/:loan-id/renew")
+		c.SetParamNames("loan-id")
+		c.SetParamValues(testStr)
+		c.Set("role", constant.Karyawan)
+
+		if tt.otherUser {
+			c.Set("username", "other")
+		} else {
+			c.Set("username", testStr)
+		}
+
+		if tt.sqlGetLoanIDErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetLoanIDErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
+		} else {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(tt.loan...))
+		}
+
+		if tt.loan != nil && !tt.otherUser {
+			s.mock.ExpectBegin()
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(tt.loan...))
+		}
+
+		if tt.expectedStatus != http.StatusConflict || tt.waiting {
+			if tt.sqlReservationErr != nil {
+				s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlReservationErr)
+			} else if tt.waiting {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(waitingReservationResult...))
+			} else if tt.loan != nil && !tt.otherUser {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows))
+			}
+		}
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.expectedStatus == http.StatusOK || tt.sqlAddRenewalErr != nil {
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+
+			// The renewal is recorded in the same transaction as the new end date
+			if tt.sqlAddRenewalErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlAddRenewalErr)
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+		}
+
+		if tt.loan != nil && !tt.otherUser {
+			if tt.expectedStatus == http.StatusOK {
+				s.mock.ExpectCommit()
+			} else {
+				s.mock.ExpectRollback()
+			}
+		}
+
+		err := s.loanBookHandler.Renew(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
+	}
+}
+
+func (s *Suite) TestGetRenewals() {
+	tests := []struct {
+		name            string
+		manager         bool
+		notFound        bool
+		otherUser       bool
+		sqlErr          error
+		sqlGetLoanIDErr error
+		expectedStatus  int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success as manager", manager: true, expectedStatus: http.StatusOK},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql get loan id error", sqlGetLoanIDErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "not found loan id", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "other user", otherUser: true, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, loanBookEndpoint+"/test/renewals", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(loanBookEndpoint + "/:loan-id/renewals")
+		c.SetParamNames("loan-id")
+		c.SetParamValues(testStr)
+
+		if tt.manager {
+			c.Set("role", constant.Admin)
+		} else {
+			c.Set("role", constant.Karyawan)
+		}
+
+		if tt.otherUser {
+			c.Set("username", "other")
+		} else {
+			c.Set("username", testStr)
+		}
+
+		if !tt.manager {
+			if tt.sqlGetLoanIDErr != nil {
+				s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetLoanIDErr)
+			} else if tt.notFound {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
+			} else {
+				s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
+			}
+		}
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.sqlGetLoanIDErr == nil && !tt.notFound && !tt.otherUser {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanRenewalRows).AddRow(loanRenewalResult...))
+		}
+
+		err := s.loanBookHandler.GetRenewals(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+	}
+}
+
 func (s *Suite) TestUpdateLoanBook() {
 	var tests = []struct {
 		name             string
 		bindErr          bool
 		validatorErr     bool
+		unknownStatus    bool
+		reopen           bool
+		copyTaken        bool
 		notFound         bool
+		waiting          bool
+		settled          bool
+		roleErr          bool
 		sqlGetBookIDErr  error
 		sqlGetLoanIDErr  error
 		sqlErr           error
@@ -327,10 +702,16 @@ func (s *Suite) TestUpdateLoanBook() {
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success with waiting reservation", waiting: true, expectedStatus: http.StatusOK},
+		{name: "success with settled fine", settled: true, expectedStatus: http.StatusOK},
+		{name: "reopen returned loan", reopen: true, expectedStatus: http.StatusOK},
+		{name: "reopen returned loan of a taken copy", reopen: true, copyTaken: true, expectedStatus: http.StatusConflict},
 		{name: "sql get loan id error", sqlGetLoanIDErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "not found loan id", sqlGetLoanIDErr: sql.ErrNoRows, notFound: true, expectedStatus: http.StatusNotFound},
 		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
 		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "unknown status", unknownStatus: true, expectedStatus: http.StatusBadRequest},
+		{name: "role error", roleErr: true, expectedStatus: http.StatusForbidden},
 		{name: "sql get book id data error", sqlGetBookIDErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "not found book id", sqlGetBookIDErr: sql.ErrNoRows, notFound: true, expectedStatus: http.StatusNotFound},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
@@ -341,11 +722,15 @@ func (s *Suite) TestUpdateLoanBook() {
 	for _, tt := range tests {
 		var bodyFilepath string
 		if tt.bindErr {
-			bodyFilepath = loanBookBodyInvalidFilePath
+			bodyFilepath = loanBookUpdateBodyInvalidFilePath
 		} else if tt.validatorErr {
-			bodyFilepath = loanBookBodyEmptyFilePath
+			bodyFilepath = loanBookUpdateBodyEmptyFilePath
+		} else if tt.unknownStatus {
+			bodyFilepath = loanBookUpdateBodyStatusFilePath
+		} else if tt.reopen {
+			bodyFilepath = loanBookUpdateBodyReopenFilePath
 		} else {
-			bodyFilepath = loanBookBodyFilePath
+			bodyFilepath = loanBookUpdateBodyFilePath
 		}
 
 		jsonFile, err := os.Open(bodyFilepath)
@@ -361,48 +746,106 @@ func (s *Suite) TestUpdateLoanBook() {
 		c.SetPath(loanBookEndpoint + "/:loan-id")
 		c.SetParamNames("loan-id")
 		c.SetParamValues(testStr)
+		c.Set("username", testStr)
 
-		if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetBookIDErr == nil && tt.sqlUpdateBookErr == nil && tt.sqlErr == nil && tt.sqlGetLoanIDErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-			s.mock.ExpectCommit()
+		if tt.roleErr {
+			c.Set("role", constant.Karyawan)
+		} else {
+			c.Set("role", constant.Admin)
+		}
+
+		stored, item := loanBookResult, loanedItemResult
+
+		if tt.reopen {
+			stored = returnedLoanBookResult
+
+			if !tt.copyTaken {
+				item = itemResult
+			}
+		}
+
+		// The stored loan is read again and locked with its book and copy
+		expectLocked := func(bookErr error, book []driver.Value) bool {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(stored...))
 			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-			s.mock.ExpectCommit()
-		} else if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetBookIDErr == nil && tt.sqlUpdateBookErr == nil && tt.sqlErr == nil && tt.sqlGetLoanIDErr != nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetLoanIDErr)
-		} else if !tt.bindErr && !tt.validatorErr && tt.notFound && tt.sqlGetBookIDErr == nil && tt.sqlUpdateBookErr == nil && tt.sqlErr == nil && tt.sqlGetLoanIDErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(stored...))
+
+			if bookErr != nil {
+				s.mock.ExpectQuery("").WithArgs().WillReturnError(bookErr)
+				return false
+			}
+
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(book...))
+
+			if book[0] == 0 {
+				return false
+			}
+
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(item...))
+			return !tt.copyTaken
+		}
+
+		expectReturn := func(sqlUpdateBookErr error) {
+			if tt.reopen {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			} else {
+				if tt.waiting {
+					s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(waitingReservationResult...))
+					s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				} else {
+					s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows))
+				}
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				if tt.settled {
+					s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows).AddRow(unpaidFineResult...))
+				} else {
+					s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(fineRows))
+					s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+					s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+			}
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(statusCountRows).AddRow(constant.ItemAvailableStatus, 1))
+			if sqlUpdateBookErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(sqlUpdateBookErr)
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+		}
+
+		inTransaction := !tt.bindErr && !tt.validatorErr && !tt.unknownStatus && !tt.roleErr && tt.sqlGetLoanIDErr == nil
+
+		if tt.sqlGetLoanIDErr != nil && tt.notFound {
 			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(emptyLoanBookResult...))
-		} else if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetBookIDErr != nil && tt.sqlUpdateBookErr == nil && tt.sqlErr == nil && tt.sqlGetLoanIDErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetBookIDErr)
-		} else if !tt.bindErr && !tt.validatorErr && tt.notFound && tt.sqlGetBookIDErr != nil && tt.sqlUpdateBookErr == nil && tt.sqlErr == nil && tt.sqlGetLoanIDErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyBookResult...))
-		} else if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetBookIDErr == nil && tt.sqlUpdateBookErr == nil && tt.sqlErr != nil && tt.sqlGetLoanIDErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
-			s.mock.ExpectBegin()
+		} else if tt.sqlGetLoanIDErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlGetLoanIDErr)
+		} else if tt.sqlGetBookIDErr != nil && tt.notFound {
+			expectLocked(nil, emptyBookResult)
+		} else if tt.sqlGetBookIDErr != nil {
+			expectLocked(tt.sqlGetBookIDErr, nil)
+		} else if tt.sqlErr != nil {
+			expectLocked(nil, bookResult)
 			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
-			s.mock.ExpectRollback()
-		} else if !tt.bindErr && !tt.validatorErr && !tt.notFound && tt.sqlGetBookIDErr == nil && tt.sqlUpdateBookErr != nil && tt.sqlErr == nil && tt.sqlGetLoanIDErr == nil {
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
-			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-			s.mock.ExpectCommit()
-			s.mock.ExpectBegin()
-			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlUpdateBookErr)
-			s.mock.ExpectRollback()
-		} else {
+		} else if inTransaction {
+			if expectLocked(nil, bookResult) {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+				expectReturn(tt.sqlUpdateBookErr)
+			}
+		} else if !tt.roleErr {
 			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
 		}
 
-		err = s.loanBookHandler.Update(c)
+		if inTransaction {
+			if tt.expectedStatus == http.StatusOK {
+				s.mock.ExpectCommit()
+			} else {
+				s.mock.ExpectRollback()
+			}
+		}
+
+		err = middlewares.RequirePermission(constant.LoanManage)(s.loanBookHandler.Update)(c)
 		s.Require().NoError(err)
-		s.Require().Equal(tt.expectedStatus, rec.Code)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
 	}
 }
 
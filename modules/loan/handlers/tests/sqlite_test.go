@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases/dbtest"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanBookRepositoryOnSQLite(t *testing.T) {
+	db := dbtest.OpenSQLite(t)
+	ctx := context.Background()
+	repository := repositories.NewLoanBookRepository(db)
+
+	for i, username := range []string{"first", "first", "second"} {
+		_, err := repository.Add(ctx, models.LoanBook{
+			LoanID:        fmt.Sprintf("LOAN-%s-%04d", username, i+1),
+			BookID:        "FAKHRIL-Drama-0001",
+			Username:      username,
+			LoanStartDate: dateStr,
+			LoanEndDate:   dateStr,
+			Status:        constant.LoanBorrowedStatus,
+		})
+		require.NoError(t, err)
+	}
+
+	filter := models.LoanBookFilter{User: "first"}
+	filter.SetDefault()
+
+	loans, total, err := repository.Get(ctx, filter)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, loans, 2)
+
+	filter.User = ""
+
+	overdue, total, err := repository.GetOverdue(ctx, "2024-01-02", filter)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Len(t, overdue, 3)
+
+	count, err := repository.CountOverdue(ctx, "second", "2024-01-02")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	active, overdueCount, err := repository.CountBorrowed(ctx, "2024-01-01")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), active)
+	require.Equal(t, int64(0), overdueCount)
+
+	// The gauges count every borrowed loan, all of them overdue by now
+	usecases.NewLoanBookUsecase(repository, nil, nil, nil, nil, nil, nil, nil)
+
+	expected := `
+# HELP perpustakaan_loans_active Loans that are borrowed and not returned yet.
+# TYPE perpustakaan_loans_active gauge
+perpustakaan_loans_active 3
+# HELP perpustakaan_loans_overdue Borrowed loans past their end date.
+# TYPE perpustakaan_loans_overdue gauge
+perpustakaan_loans_overdue 3
+`
+	require.NoError(t, testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expected), "perpustakaan_loans_active", "perpustakaan_loans_overdue"))
+}
@@ -2,38 +2,74 @@ package models
 
 import (
 	"fmt"
-	"strings"
+	"math"
+	"time"
 
 	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
-	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 )
 
 func (m *LoanBookAdd) ToLoanBook(e LoanBook) LoanBook {
-	e.LoanID = generateLoanID(e)
 	e.BookID = m.BookID
 	e.LoanStartDate = m.LoanStartDate
 	e.LoanEndDate = m.LoanEndDate
 	e.Username = m.Username
+
+	if m.Barcode != "" {
+		e.Barcode = m.Barcode
+	}
+
+	return e
+}
+
+// ToLoanBook applies the update to e. The book, the copy and the borrower
+// of a loan do not change.
+func (m *LoanBookUpdate) ToLoanBook(e LoanBook) LoanBook {
+	e.LoanStartDate = m.LoanStartDate
+	e.LoanEndDate = m.LoanEndDate
 	e.Status = m.Status
 
 	return e
 }
 
-func generateLoanID(data LoanBook) string {
+// DaysLate returns how many whole days the loan is past its end date at now,
+// or zero when it is not overdue. It fails when the end date cannot be
+// parsed.
+func (e LoanBook) DaysLate(now time.Time) (int64, error) {
+	end, err := time.ParseInLocation(constant.LoanDateLayout, e.LoanEndDate, now.Location())
 
-	var loanID string
-	var number string
+	if err != nil {
+		return 0, fmt.Errorf("loan %s has an invalid end date: %w", e.LoanID, err)
+	}
 
-	if data.LoanID == "" {
-		number = utils.GetFourDigitsNumber("1")
-		loanID = fmt.Sprintf("%s-%s-%v", constant.Loan, data.Username, number)
-	} else {
-		lastBookData := strings.Split(data.LoanID, "-")
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := int64(math.Round(today.Sub(end).Hours() / 24))
 
-		lastNumber := utils.ConvertInt(lastBookData[2]) + 1
-		number = utils.GetFourDigitsNumber(utils.ConvertString(lastNumber))
-		loanID = fmt.Sprintf("%s-%s-%v", constant.Loan, data.Username, number)
+	if days < 0 {
+		return 0, nil
 	}
 
-	return loanID
+	return days, nil
+}
+
+// Renew extends the loan end date by period and returns the renewal record
+// of now. The end date is read in the location of now, as DaysLate does.
+func (e *LoanBook) Renew(period time.Duration, renewedBy string, now time.Time) (LoanRenewal, error) {
+	end, err := time.ParseInLocation(constant.LoanDateLayout, e.LoanEndDate, now.Location())
+
+	if err != nil {
+		return LoanRenewal{}, err
+	}
+
+	renewal := LoanRenewal{
+		LoanID:          e.LoanID,
+		PreviousEndDate: e.LoanEndDate,
+		NewEndDate:      end.Add(period).Format(constant.LoanDateLayout),
+		RenewedBy:       renewedBy,
+		RenewedAt:       now,
+	}
+
+	e.LoanEndDate = renewal.NewEndDate
+	e.RenewalCount++
+
+	return renewal, nil
 }
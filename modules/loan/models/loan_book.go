@@ -4,13 +4,21 @@ type LoanBook struct {
 	ID            int64  `json:"id" gorm:"primaryKey"`
 	LoanID        string `json:"loan_id"`
 	BookID        string `json:"book_id"`
+	Barcode       string `json:"barcode"`
 	Title         string `json:"title"`
 	Username      string `json:"username"`
 	LoanStartDate string `json:"loan_start_date"`
 	LoanEndDate   string `json:"loan_end_date"`
 	Status        string `json:"status"`
+	RenewalCount  int    `json:"renewal_count"`
 }
 
 func (LoanBook) TableName() string {
 	return "loan_book"
 }
+
+type LoanBookOverdue struct {
+	LoanBook
+	DaysLate int64 `json:"days_late"`
+	Fine     int64 `json:"fine"`
+}
@@ -1,6 +1,7 @@
 package models
 
 type LoanBookUpdate struct {
-	BookID string `json:"book_id" validate:"required"`
-	Status string `json:"status"`
+	LoanStartDate string `json:"loan_start_date" validate:"required,datetime=2006-01-02"`
+	LoanEndDate   string `json:"loan_end_date" validate:"required,datetime=2006-01-02"`
+	Status        string `json:"status" validate:"required,oneof=BORROWED RETURNED"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type LoanRenewal struct {
+	ID              int64     `json:"id" gorm:"primaryKey"`
+	LoanID          string    `json:"loan_id"`
+	PreviousEndDate string    `json:"previous_end_date"`
+	NewEndDate      string    `json:"new_end_date"`
+	RenewedBy       string    `json:"renewed_by"`
+	RenewedAt       time.Time `json:"renewed_at"`
+}
+
+func (LoanRenewal) TableName() string {
+	return "loan_renewal"
+}
@@ -8,7 +8,7 @@ import (
 
 func buildFilterQuery(db *gorm.DB, f models.LoanBookFilter) *gorm.DB {
 	if f.User != "" {
-		db = db.Where("user = ?", f.User)
+		db = db.Where("username = ?", f.User)
 	}
 
 	if f.Status != "" {
@@ -5,6 +5,8 @@ import (
 
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
 	"gorm.io/gorm"
 )
 
@@ -14,18 +16,55 @@ type loanBookRepository struct {
 
 // Add implements domain.LoanBookRepository.
 func (r *loanBookRepository) Add(ctx context.Context, data models.LoanBook) (result models.LoanBook, err error) {
-	err = r.db.WithContext(ctx).Create(&data).Error
+	err = databases.Conn(ctx, r.db).Create(&data).Error
 	return data, err
 }
 
+// AddRenewal implements domain.LoanBookRepository.
+func (r *loanBookRepository) AddRenewal(ctx context.Context, data models.LoanRenewal) (result models.LoanRenewal, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// CountActive implements domain.LoanBookRepository.
+func (r *loanBookRepository) CountActive(ctx context.Context, username string) (total int64, err error) {
+	err = databases.Conn(ctx, r.db).Model(&models.LoanBook{}).
+		Where("username = ? AND status = ?", username, constant.LoanBorrowedStatus).
+		Count(&total).Error
+	return
+}
+
+// CountOverdue implements domain.LoanBookRepository.
+func (r *loanBookRepository) CountOverdue(ctx context.Context, username string, date string) (total int64, err error) {
+	err = databases.Conn(ctx, r.db).Model(&models.LoanBook{}).
+		Where("username = ? AND status = ? AND loan_end_date < ?", username, constant.LoanBorrowedStatus, date).
+		Count(&total).Error
+	return
+}
+
+// CountBorrowed implements domain.LoanBookRepository. It counts the loans
+// of every user that are borrowed and, of those, overdue on date.
+func (r *loanBookRepository) CountBorrowed(ctx context.Context, date string) (active int64, overdue int64, err error) {
+	if err = databases.Conn(ctx, r.db).Model(&models.LoanBook{}).
+		Where("status = ?", constant.LoanBorrowedStatus).
+		Count(&active).Error; err != nil {
+		return
+	}
+
+	err = databases.Conn(ctx, r.db).Model(&models.LoanBook{}).
+		Where("status = ? AND loan_end_date < ?", constant.LoanBorrowedStatus, date).
+		Count(&overdue).Error
+	return
+}
+
 // Delete implements domain.LoanBookRepository.
 func (r *loanBookRepository) Delete(ctx context.Context, loan_id string) error {
-	return r.db.WithContext(ctx).Where("loan_id = ?", loan_id).Delete(&models.LoanBook{}).Error
+	return databases.Conn(ctx, r.db).Where("loan_id = ?", loan_id).Delete(&models.LoanBook{}).Error
 }
 
 // Get implements domain.LoanBookRepository.
 func (r *loanBookRepository) Get(ctx context.Context, filter models.LoanBookFilter) (result []models.LoanBook, total int64, err error) {
-	db := r.db.WithContext(ctx)
+	db := databases.Conn(ctx, r.db)
 	db = buildFilterQuery(db, filter)
 
 	if err = db.Model(&models.LoanBook{}).Count(&total).Error; err != nil {
@@ -45,19 +84,48 @@ func (r *loanBookRepository) Get(ctx context.Context, filter models.LoanBookFilt
 
 // GetByLoanID implements domain.LoanBookRepository.
 func (r *loanBookRepository) GetByLoanID(ctx context.Context, loan_id string) (result models.LoanBook, err error) {
-	err = r.db.WithContext(ctx).Where("loan_id = ?", loan_id).First(&result).Error
+	err = databases.Conn(ctx, r.db).Where("loan_id = ?", loan_id).First(&result).Error
+	return
+}
+
+// GetByLoanIDForUpdate implements domain.LoanBookRepository. The loan row
+// stays locked until the surrounding transaction ends.
+func (r *loanBookRepository) GetByLoanIDForUpdate(ctx context.Context, loan_id string) (result models.LoanBook, err error) {
+	err = databases.ForUpdate(databases.Conn(ctx, r.db)).Where("loan_id = ?", loan_id).First(&result).Error
+	return
+}
+
+// GetOverdue implements domain.LoanBookRepository. Loans are overdue when
+// they are still borrowed and their end date is before date.
+func (r *loanBookRepository) GetOverdue(ctx context.Context, date string, filter models.LoanBookFilter) (result []models.LoanBook, total int64, err error) {
+	db := databases.Conn(ctx, r.db)
+	db = buildFilterQuery(db, filter)
+	db = db.Where("status = ? AND loan_end_date < ?", constant.LoanBorrowedStatus, date)
+
+	if err = db.Model(&models.LoanBook{}).Count(&total).Error; err != nil {
+		return
+	}
+
+	if !filter.DisablePagination {
+		db = db.Offset(int(filter.GetOffset())).Limit(int(filter.GetLimit()))
+	}
+
+	if err = db.Order("loan_end_date, id").Find(&result).Error; err != nil {
+		return
+	}
+
 	return
 }
 
-// GetLast implements domain.LoanBookRepository.
-func (r *loanBookRepository) GetLast(ctx context.Context, username string) (result models.LoanBook, err error) {
-	err = r.db.WithContext(ctx).Select("loan_id", "username").Last(&result, "username = ?", username).Error
+// GetRenewals implements domain.LoanBookRepository.
+func (r *loanBookRepository) GetRenewals(ctx context.Context, loan_id string) (result []models.LoanRenewal, err error) {
+	err = databases.Conn(ctx, r.db).Where("loan_id = ?", loan_id).Order("id").Find(&result).Error
 	return
 }
 
 // Update implements domain.LoanBookRepository.
 func (r *loanBookRepository) Update(ctx context.Context, data models.LoanBook) (result models.LoanBook, err error) {
-	err = r.db.WithContext(ctx).Save(&data).Error
+	err = databases.Conn(ctx, r.db).Save(&data).Error
 	return data, err
 }
 
@@ -2,19 +2,42 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/Zeroaril7/perpustakaan-go/config"
 	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
 	bookModel "github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	fineDomain "github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	fineModel "github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	itemDomain "github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	itemModel "github.com/Zeroaril7/perpustakaan-go/modules/item/models"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	reservationDomain "github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	reservationModel "github.com/Zeroaril7/perpustakaan-go/modules/reservation/models"
+	userDomain "github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type loanBookUsecase struct {
-	loanBookRepository domain.LoanBookRepository
-	bookRepository     bookDomain.BookRepository
+	loanBookRepository    domain.LoanBookRepository
+	bookRepository        bookDomain.BookRepository
+	itemRepository        itemDomain.ItemRepository
+	reservationRepository reservationDomain.ReservationRepository
+	fineRepository        fineDomain.FineRepository
+	userRepository        userDomain.UserRepository
+	unitOfWork            databases.UnitOfWork
+	sequence              sequence.Sequence
 }
 
 // Add implements domain.LoanBookUsecase.
@@ -24,37 +47,145 @@ func (u *loanBookUsecase) Add(ctx context.Context, data models.LoanBook) <-chan
 	go func() {
 		defer close(output)
 
-		expend, err := u.bookRepository.GetByBookID(ctx, data.BookID)
+		var result models.LoanBook
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			expend, err := u.bookRepository.GetByBookIDForUpdate(ctx, data.BookID)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			if expend == (bookModel.Book{}) {
+				return httperror.NotFound("Book ID not found")
+			}
+
+			reservation, err := u.reservationRepository.GetReady(ctx, data.BookID, data.Username)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			err = u.checkEligibility(ctx, data, expend, reservation)
+
+			if err != nil {
+				return err
+			}
+
+			item, reservation, err := u.getLendableItem(ctx, data, reservation)
+
+			if err != nil {
+				return err
+			}
+
+			data.LoanID, err = u.sequence.NextID(ctx, config.Config().LoanIDTemplate(), map[string]string{
+				constant.UsernameField: data.Username,
+			})
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			data.Title = expend.Title
+			data.Barcode = item.Barcode
+
+			result, err = u.loanBookRepository.Add(ctx, data)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			if reservation != (reservationModel.Reservation{}) {
+				reservation.Status = constant.ReservationFulfilledStatus
+
+				_, err = u.reservationRepository.Update(ctx, reservation)
+
+				if err != nil {
+					return httperror.FromError(err)
+				}
+			}
+
+			item.Status = constant.ItemOnLoanStatus
+
+			_, err = u.itemRepository.Update(ctx, item)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			err = u.syncBookStatus(ctx, expend)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			return nil
+		})
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		if expend == (bookModel.Book{}) {
-			output <- utils.Result{Error: httperror.NotFound("Book ID not found")}
-			return
-		}
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// AccrueFines implements domain.LoanBookUsecase. It brings the fine ledger
+// up to date for every overdue loan.
+func (u *loanBookUsecase) AccrueFines(ctx context.Context) <-chan utils.Result {
+	output := make(chan utils.Result)
 
-		data.Title = expend.Title
+	go func() {
+		defer close(output)
 
-		result, err := u.loanBookRepository.Add(ctx, data)
+		now := utils.GetLocalTime()
+		filter := models.LoanBookFilter{}
+		filter.DisablePagination = true
+
+		loans, total, err := u.loanBookRepository.GetOverdue(ctx, now.Format(constant.LoanDateLayout), filter)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		expend.Status = constant.NotAvailableStatus
+		for _, loan := range loans {
+			if _, err = loan.DaysLate(now); err != nil {
+				logger.FromContext(ctx).Warn("Skipping overdue loan", zap.Error(err))
+				continue
+			}
 
-		_, err = u.bookRepository.Update(ctx, expend)
+			fine, err := u.fineRepository.GetByLoanID(ctx, loan.LoanID)
 
-		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
-			return
+			if err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+
+			book, err := u.bookRepository.GetByBookID(ctx, loan.BookID)
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+
+			fine, err = u.chargeFine(ctx, fine, loan, book.Genre, now)
+
+			if err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+
+			if _, err = u.fineRepository.Update(ctx, fine); err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
 		}
 
-		output <- utils.Result{Data: result}
+		output <- utils.Result{Total: total}
 	}()
 
 	return output
@@ -70,7 +201,7 @@ func (u *loanBookUsecase) Delete(ctx context.Context, loan_id string) <-chan uti
 		err := u.loanBookRepository.Delete(ctx, loan_id)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -90,7 +221,7 @@ func (u *loanBookUsecase) Get(ctx context.Context, filter models.LoanBookFilter)
 		result, total, err := u.loanBookRepository.Get(ctx, filter)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -110,7 +241,7 @@ func (u *loanBookUsecase) GetByLoanID(ctx context.Context, loan_id string) <-cha
 		result, err := u.loanBookRepository.GetByLoanID(ctx, loan_id)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -120,63 +251,240 @@ func (u *loanBookUsecase) GetByLoanID(ctx context.Context, loan_id string) <-cha
 	return output
 }
 
-// GetLast implements domain.LoanBookUsecase.
-func (u *loanBookUsecase) GetLast(ctx context.Context, user string) <-chan utils.Result {
+// GetOverdue implements domain.LoanBookUsecase.
+func (u *loanBookUsecase) GetOverdue(ctx context.Context, filter models.LoanBookFilter) <-chan utils.Result {
 	output := make(chan utils.Result)
 
 	go func() {
 		defer close(output)
 
-		result, err := u.loanBookRepository.GetLast(ctx, user)
+		now := utils.GetLocalTime()
+
+		loans, total, err := u.loanBookRepository.GetOverdue(ctx, now.Format(constant.LoanDateLayout), filter)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		output <- utils.Result{Data: result}
+		result := make([]models.LoanBookOverdue, 0, len(loans))
+
+		for _, loan := range loans {
+			if _, err = loan.DaysLate(now); err != nil {
+				logger.FromContext(ctx).Warn("Skipping overdue loan", zap.Error(err))
+				continue
+			}
+
+			book, err := u.bookRepository.GetByBookID(ctx, loan.BookID)
+
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+
+			fine, err := u.chargeFine(ctx, fineModel.Fine{}, loan, book.Genre, now)
+
+			if err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+
+			result = append(result, models.LoanBookOverdue{LoanBook: loan, DaysLate: fine.DaysLate, Fine: fine.Amount})
+		}
+
+		output <- utils.Result{Data: result, Total: total}
 	}()
 
 	return output
 }
 
-// Update implements domain.LoanBookUsecase.
-func (u *loanBookUsecase) Update(ctx context.Context, data models.LoanBook) <-chan utils.Result {
+// GetRenewals implements domain.LoanBookUsecase.
+func (u *loanBookUsecase) GetRenewals(ctx context.Context, loan_id string) <-chan utils.Result {
 	output := make(chan utils.Result)
 
 	go func() {
 		defer close(output)
 
-		expend, err := u.bookRepository.GetByBookID(ctx, data.BookID)
+		result, err := u.loanBookRepository.GetRenewals(ctx, loan_id)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		if expend == (bookModel.Book{}) {
-			output <- utils.Result{Error: httperror.NotFound("Book ID not found")}
-			return
-		}
+		output <- utils.Result{Data: result, Total: int64(len(result))}
+	}()
+
+	return output
+}
+
+// Renew implements domain.LoanBookUsecase.
+func (u *loanBookUsecase) Renew(ctx context.Context, data models.LoanBook, renewedBy string) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		policy := config.Config().RenewalPolicy()
+
+		var result models.LoanBook
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			loan, err := u.loanBookRepository.GetByLoanIDForUpdate(ctx, data.LoanID)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			if loan.Status != constant.LoanBorrowedStatus {
+				return httperror.Conflict("Only borrowed loans can be renewed")
+			}
+
+			if loan.RenewalCount >= policy.MaxCount {
+				return httperror.Conflict("Loan has reached the renewal limit")
+			}
+
+			now := utils.GetLocalTime()
+			late, err := loan.DaysLate(now)
+
+			if err != nil {
+				return httperror.BadRequest("Loan end date is not a valid date")
+			}
+
+			if late > policy.OverdueMax {
+				return httperror.Conflict("Loan is too far overdue to renew")
+			}
+
+			waiting, err := u.reservationRepository.GetFirstWaiting(ctx, loan.BookID)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			if waiting != (reservationModel.Reservation{}) {
+				return httperror.Conflict("Book has an active reservation")
+			}
+
+			renewal, err := loan.Renew(policy.Period, renewedBy, now)
+
+			if err != nil {
+				return httperror.BadRequest("Loan end date is not a valid date")
+			}
+
+			result, err = u.loanBookRepository.Update(ctx, loan)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
 
-		result, err := u.loanBookRepository.Update(ctx, data)
+			_, err = u.loanBookRepository.AddRenewal(ctx, renewal)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			return nil
+		})
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
-		switch result.Status {
-		case constant.LoanBorrowedStatus:
-			expend.Status = constant.NotAvailableStatus
-		case constant.LoanReturnedStatus:
-			expend.Status = constant.AvailableStatus
-		}
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// Update implements domain.LoanBookUsecase. The loan keeps the book, the
+// copy and the borrower it was made with. A returned loan is only borrowed
+// again while its copy is available.
+func (u *loanBookUsecase) Update(ctx context.Context, data models.LoanBook) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var result models.LoanBook
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			loan, err := u.loanBookRepository.GetByLoanIDForUpdate(ctx, data.LoanID)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			expend, err := u.bookRepository.GetByBookIDForUpdate(ctx, loan.BookID)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			if expend == (bookModel.Book{}) {
+				return httperror.NotFound("Book ID not found")
+			}
+
+			data.ID = loan.ID
+			data.BookID = loan.BookID
+			data.Barcode = loan.Barcode
+			data.Username = loan.Username
 
-		_, err = u.bookRepository.Update(ctx, expend)
+			reopened := loan.Status != constant.LoanBorrowedStatus && data.Status == constant.LoanBorrowedStatus
+
+			var item itemModel.Item
+
+			if data.Barcode != "" {
+				item, err = u.itemRepository.GetByBarcodeForUpdate(ctx, data.Barcode)
+
+				if err != nil {
+					return httperror.FromError(err)
+				}
+			}
+
+			if reopened && item.Status != constant.ItemAvailableStatus {
+				return httperror.Conflict("Copy is not available")
+			}
+
+			result, err = u.loanBookRepository.Update(ctx, data)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			if result.Barcode != "" {
+				switch {
+				case result.Status == constant.LoanBorrowedStatus:
+					item.Status = constant.ItemOnLoanStatus
+					_, err = u.itemRepository.Update(ctx, item)
+				case result.Status == constant.LoanReturnedStatus && item.Status == constant.ItemOnLoanStatus:
+					err = u.releaseItem(ctx, item)
+				}
+
+				if err != nil {
+					return httperror.FromError(err)
+				}
+			}
+
+			if result.Status == constant.LoanReturnedStatus {
+				err = u.settleFine(ctx, result, expend.Genre)
+
+				if err != nil {
+					return httperror.FromError(err)
+				}
+			}
+
+			err = u.syncBookStatus(ctx, expend)
+
+			if err != nil {
+				return httperror.FromError(err)
+			}
+
+			return nil
+		})
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -186,6 +494,204 @@ func (u *loanBookUsecase) Update(ctx context.Context, data models.LoanBook) <-ch
 	return output
 }
 
-func NewLoanBookUsecase(loanBokRepository domain.LoanBookRepository, bookRepository bookDomain.BookRepository) domain.LoanBookUsecase {
-	return &loanBookUsecase{loanBookRepository: loanBokRepository, bookRepository: bookRepository}
+// checkEligibility refuses a loan with every reason that applies: the book
+// has no copy for the member, the member holds too many loans for their
+// role, has unpaid fines or has overdue loans.
+func (u *loanBookUsecase) checkEligibility(ctx context.Context, data models.LoanBook, book bookModel.Book, reservation reservationModel.Reservation) error {
+	var reasons []httperror.Reason
+
+	if book.Status != constant.AvailableStatus && reservation == (reservationModel.Reservation{}) {
+		reasons = append(reasons, httperror.Reason{Code: constant.BookNotAvailableReason, Message: "Book is not available"})
+	}
+
+	user, err := u.userRepository.GetByUsername(ctx, data.Username)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return httperror.FromError(err)
+	}
+
+	active, err := u.loanBookRepository.CountActive(ctx, data.Username)
+
+	if err != nil {
+		return httperror.FromError(err)
+	}
+
+	if limit := config.Config().LoanLimit(user.Role); active >= int64(limit) {
+		reasons = append(reasons, httperror.Reason{Code: constant.LoanLimitReason, Message: fmt.Sprintf("Member already has %d of %d active loans", active, limit)})
+	}
+
+	unpaid, err := u.fineRepository.GetUnpaidTotal(ctx, data.Username)
+
+	if err != nil {
+		return httperror.FromError(err)
+	}
+
+	if unpaid > 0 {
+		reasons = append(reasons, httperror.Reason{Code: constant.UnpaidFinesReason, Message: fmt.Sprintf("Member has unpaid fines of %d", unpaid)})
+	}
+
+	overdue, err := u.loanBookRepository.CountOverdue(ctx, data.Username, utils.GetLocalTime().Format(constant.LoanDateLayout))
+
+	if err != nil {
+		return httperror.FromError(err)
+	}
+
+	if overdue > 0 {
+		reasons = append(reasons, httperror.Reason{Code: constant.OverdueLoansReason, Message: fmt.Sprintf("Member has %d overdue loans", overdue)})
+	}
+
+	if len(reasons) > 0 {
+		return httperror.ConflictWithReasons("Member is not eligible to borrow this book", reasons)
+	}
+
+	return nil
+}
+
+// getLendableItem resolves the copy to lend: the copy set aside for the
+// borrower's reservation, the requested barcode when one is given, otherwise
+// the first available copy of the book.
+func (u *loanBookUsecase) getLendableItem(ctx context.Context, data models.LoanBook, reservation reservationModel.Reservation) (itemModel.Item, reservationModel.Reservation, error) {
+	var (
+		item itemModel.Item
+		err  error
+	)
+
+	if reservation != (reservationModel.Reservation{}) && (data.Barcode == "" || data.Barcode == reservation.Barcode) {
+		item, err = u.itemRepository.GetByBarcode(ctx, reservation.Barcode)
+	} else if data.Barcode != "" {
+		reservation = reservationModel.Reservation{}
+		item, err = u.itemRepository.GetByBarcode(ctx, data.Barcode)
+	} else {
+		reservation = reservationModel.Reservation{}
+		item, err = u.itemRepository.GetAvailableByBookID(ctx, data.BookID)
+	}
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, reservation, httperror.FromError(err)
+	}
+
+	if item == (itemModel.Item{}) {
+		return item, reservation, httperror.Conflict("No available copy for this book")
+	}
+
+	if item.BookID != data.BookID {
+		return item, reservation, httperror.Conflict("Copy does not belong to this book")
+	}
+
+	if item.Status == constant.ItemOnHoldStatus && reservation != (reservationModel.Reservation{}) {
+		return item, reservation, nil
+	}
+
+	if item.Status != constant.ItemAvailableStatus {
+		return item, reservation, httperror.Conflict("Copy is not available")
+	}
+
+	return item, reservation, nil
+}
+
+// releaseItem sets a returned copy aside for the first member waiting for
+// the book, or puts it back on the shelf when nobody is waiting.
+func (u *loanBookUsecase) releaseItem(ctx context.Context, item itemModel.Item) error {
+	next, err := u.reservationRepository.GetFirstWaiting(ctx, item.BookID)
+
+	if err != nil {
+		return err
+	}
+
+	if next != (reservationModel.Reservation{}) {
+		next.Hold(item.Barcode, config.Config().HoldPickupPeriod())
+
+		if _, err = u.reservationRepository.Update(ctx, next); err != nil {
+			return err
+		}
+
+		item.Status = constant.ItemOnHoldStatus
+	} else {
+		item.Status = constant.ItemAvailableStatus
+	}
+
+	_, err = u.itemRepository.Update(ctx, item)
+	return err
+}
+
+// chargeFine prices the days a loan is late under the configured fine
+// policy, using the book genre and the borrower role.
+func (u *loanBookUsecase) chargeFine(ctx context.Context, fine fineModel.Fine, loan models.LoanBook, genre string, now time.Time) (fineModel.Fine, error) {
+	user, err := u.userRepository.GetByUsername(ctx, loan.Username)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fine, err
+	}
+
+	late, err := loan.DaysLate(now)
+
+	if err != nil {
+		return fine, err
+	}
+
+	policy := config.Config().FinePolicy()
+
+	return fine.Charge(loan.LoanID, loan.Username, loan.BookID, late, policy.Rate(genre, user.Role), policy.Cap), nil
+}
+
+// settleFine fixes the final fine of a returned loan so it stops accruing.
+// A fine that is already unpaid, paid or waived is left as it is.
+func (u *loanBookUsecase) settleFine(ctx context.Context, loan models.LoanBook, genre string) error {
+	fine, err := u.fineRepository.GetByLoanID(ctx, loan.LoanID)
+
+	if err != nil {
+		return err
+	}
+
+	now := utils.GetLocalTime()
+	late, err := loan.DaysLate(now)
+
+	if err != nil {
+		return err
+	}
+
+	if (fine.Status != "" && fine.Status != constant.FineAccruingStatus) || (fine == (fineModel.Fine{}) && late == 0) {
+		return nil
+	}
+
+	fine, err = u.chargeFine(ctx, fine, loan, genre, now)
+
+	if err != nil {
+		return err
+	}
+
+	fine.Status = constant.FineUnpaidStatus
+
+	_, err = u.fineRepository.Update(ctx, fine)
+	return err
+}
+
+// syncBookStatus recomputes the book status from the copies that are
+// currently available for lending.
+func (u *loanBookUsecase) syncBookStatus(ctx context.Context, book bookModel.Book) error {
+	counts, err := u.itemRepository.CountByStatus(ctx, book.BookID)
+
+	if err != nil {
+		return err
+	}
+
+	book.Status = itemModel.BookStatus(counts)
+
+	_, err = u.bookRepository.Update(ctx, book)
+	return err
+}
+
+func NewLoanBookUsecase(loanBokRepository domain.LoanBookRepository, bookRepository bookDomain.BookRepository, itemRepository itemDomain.ItemRepository, reservationRepository reservationDomain.ReservationRepository, fineRepository fineDomain.FineRepository, userRepository userDomain.UserRepository, unitOfWork databases.UnitOfWork, sequence sequence.Sequence) domain.LoanBookUsecase {
+	metrics.Register(loanCollector{loanBookRepository: loanBokRepository})
+
+	return &loanBookUsecase{
+		loanBookRepository:    loanBokRepository,
+		bookRepository:        bookRepository,
+		itemRepository:        itemRepository,
+		reservationRepository: reservationRepository,
+		fineRepository:        fineRepository,
+		userRepository:        userRepository,
+		unitOfWork:            unitOfWork,
+		sequence:              sequence,
+	}
 }
@@ -0,0 +1,49 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	activeLoansDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "loans", "active"),
+		"Loans that are borrowed and not returned yet.", nil, nil)
+
+	overdueLoansDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "loans", "overdue"),
+		"Borrowed loans past their end date.", nil, nil)
+)
+
+// loanCollector counts the loans in the database on every scrape.
+type loanCollector struct {
+	loanBookRepository domain.LoanBookRepository
+}
+
+// Describe implements prometheus.Collector.
+func (c loanCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeLoansDesc
+	ch <- overdueLoansDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c loanCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), constant.MetricsQueryTimeout)
+	defer cancel()
+
+	active, overdue, err := c.loanBookRepository.CountBorrowed(ctx, time.Now().Format(constant.LoanDateLayout))
+
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(activeLoansDesc, err)
+		ch <- prometheus.NewInvalidMetric(overdueLoansDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(activeLoansDesc, prometheus.GaugeValue, float64(active))
+	ch <- prometheus.MustNewConstMetric(overdueLoansDesc, prometheus.GaugeValue, float64(overdue))
+}
@@ -0,0 +1,75 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracedLoanBookUsecase runs every call of a domain.LoanBookUsecase in a span, see tracing.Result.
+type tracedLoanBookUsecase struct {
+	next domain.LoanBookUsecase
+}
+
+// NewTracedLoanBookUsecase returns next with every call traced.
+func NewTracedLoanBookUsecase(next domain.LoanBookUsecase) domain.LoanBookUsecase {
+	return &tracedLoanBookUsecase{next: next}
+}
+
+// Get implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) Get(ctx context.Context, filter models.LoanBookFilter) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.Get")
+	return tracing.Result(span, u.next.Get(ctx, filter))
+}
+
+// GetByLoanID implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) GetByLoanID(ctx context.Context, loan_id string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.GetByLoanID", attribute.String("loan.id", loan_id))
+	return tracing.Result(span, u.next.GetByLoanID(ctx, loan_id))
+}
+
+// GetOverdue implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) GetOverdue(ctx context.Context, filter models.LoanBookFilter) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.GetOverdue")
+	return tracing.Result(span, u.next.GetOverdue(ctx, filter))
+}
+
+// AccrueFines implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) AccrueFines(ctx context.Context) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.AccrueFines")
+	return tracing.Result(span, u.next.AccrueFines(ctx))
+}
+
+// Add implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) Add(ctx context.Context, data models.LoanBook) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.Add")
+	return tracing.Result(span, u.next.Add(ctx, data))
+}
+
+// Update implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) Update(ctx context.Context, data models.LoanBook) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.Update")
+	return tracing.Result(span, u.next.Update(ctx, data))
+}
+
+// Delete implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) Delete(ctx context.Context, loan_id string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.Delete", attribute.String("loan.id", loan_id))
+	return tracing.Result(span, u.next.Delete(ctx, loan_id))
+}
+
+// Renew implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) Renew(ctx context.Context, data models.LoanBook, renewedBy string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.Renew")
+	return tracing.Result(span, u.next.Renew(ctx, data, renewedBy))
+}
+
+// GetRenewals implements domain.LoanBookUsecase.
+func (u *tracedLoanBookUsecase) GetRenewals(ctx context.Context, loan_id string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "LoanBookUsecase.GetRenewals", attribute.String("loan.id", loan_id))
+	return tracing.Result(span, u.next.GetRenewals(ctx, loan_id))
+}
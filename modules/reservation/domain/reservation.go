@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+type ReservationRepository interface {
+	Add(ctx context.Context, data models.Reservation) (models.Reservation, error)
+	Get(ctx context.Context, filter models.ReservationFilter) ([]models.Reservation, int64, error)
+	GetByID(ctx context.Context, id int64) (models.Reservation, error)
+	GetActive(ctx context.Context, book_id string, username string) (models.Reservation, error)
+	GetFirstWaiting(ctx context.Context, book_id string) (models.Reservation, error)
+	GetReady(ctx context.Context, book_id string, username string) (models.Reservation, error)
+	GetExpired(ctx context.Context, now time.Time) ([]models.Reservation, error)
+	Update(ctx context.Context, data models.Reservation) (models.Reservation, error)
+}
+
+type ReservationUsecase interface {
+	Add(ctx context.Context, data models.Reservation) <-chan utils.Result
+	Get(ctx context.Context, filter models.ReservationFilter) <-chan utils.Result
+	GetByID(ctx context.Context, id int64) <-chan utils.Result
+	Cancel(ctx context.Context, data models.Reservation) <-chan utils.Result
+	ExpireHolds(ctx context.Context) <-chan utils.Result
+}
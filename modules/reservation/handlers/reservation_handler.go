@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type ReservationHandler interface {
+	Add(c echo.Context) error
+	Cancel(c echo.Context) error
+	Get(c echo.Context) error
+	GetByID(c echo.Context) error
+}
+
+type reservationHandler struct {
+	reservationUsecase domain.ReservationUsecase
+}
+
+func NewReservationHandler(e *echo.Echo, reservationUsecase domain.ReservationUsecase) ReservationHandler {
+	handler := &reservationHandler{reservationUsecase: reservationUsecase}
+
+	group := e.Group("/reservation", middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()))
+	group.POST("", handler.Add, middlewares.RequirePermission(constant.ReservationWrite))
+	group.DELETE("/:reservation-id", handler.Cancel, middlewares.RequirePermission(constant.ReservationWrite))
+	group.GET("", handler.Get, middlewares.RequirePermission(constant.ReservationRead))
+	group.GET("/:reservation-id", handler.GetByID, middlewares.RequirePermission(constant.ReservationRead))
+
+	return handler
+}
+
+// Add implements ReservationHandler.
+func (h *reservationHandler) Add(c echo.Context) error {
+	data := new(models.ReservationAdd)
+
+	if err := c.Bind(data); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	expend := models.Reservation{Username: utils.ConvertString(c.Get("username"))}
+	expend = data.ToReservation(expend)
+
+	result := <-h.reservationUsecase.Add(c.Request().Context(), expend)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Add reservation success", http.StatusOK, c)
+}
+
+// Cancel implements ReservationHandler.
+func (h *reservationHandler) Cancel(c echo.Context) error {
+	id := utils.ConvertInt64(c.Param("reservation-id"))
+
+	result := <-h.reservationUsecase.GetByID(c.Request().Context(), id)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	expend := result.Data.(models.Reservation)
+
+	if expend == (models.Reservation{}) {
+		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+	}
+
+	if !canAccess(c, expend.Username) {
+		return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
+	}
+
+	result = <-h.reservationUsecase.Cancel(c.Request().Context(), expend)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Cancel reservation success", http.StatusOK, c)
+}
+
+// Get implements ReservationHandler.
+func (h *reservationHandler) Get(c echo.Context) error {
+	filter := new(models.ReservationFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	scopeToCaller(c, filter)
+
+	result := <-h.reservationUsecase.Get(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get reservation success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// GetByID implements ReservationHandler.
+func (h *reservationHandler) GetByID(c echo.Context) error {
+	id := utils.ConvertInt64(c.Param("reservation-id"))
+
+	result := <-h.reservationUsecase.GetByID(c.Request().Context(), id)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	if expend, ok := result.Data.(models.Reservation); ok && expend != (models.Reservation{}) && !canAccess(c, expend.Username) {
+		return utils.ResponseError(httperror.Forbidden(httperror.ForbiddenErrorMessage), c)
+	}
+
+	return utils.Response(result.Data, "Get reservation success", http.StatusOK, c)
+}
+
+// canAccess reports whether the caller may act on the reservations of
+// username: their own, or anyone's when they can manage reservations.
+func canAccess(c echo.Context, username string) bool {
+	return username == utils.ConvertString(c.Get("username")) || middlewares.Can(c, constant.ReservationManage)
+}
+
+// scopeToCaller limits filter to the reservations of the caller unless they
+// can manage reservations, e.g. for KARYAWAN.
+func scopeToCaller(c echo.Context, filter *models.ReservationFilter) {
+	if !middlewares.Can(c, constant.ReservationManage) {
+		filter.Username = utils.ConvertString(c.Get("username"))
+	}
+}
@@ -0,0 +1,389 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
+	bookRepo "github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
+	itemDomain "github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	itemRepo "github.com/Zeroaril7/perpustakaan-go/modules/item/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/handlers"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/repositories"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/usecases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	reservationEndpoint            = "/reservation"
+	reservationBodyFilePath        = "test_data/reservation_body_req.json"
+	reservationBodyInvalidFilePath = "test_data/reservation_body_invalid_req.json"
+	reservationBodyEmptyFilePath   = "test_data/reservation_body_empty_req.json"
+	reservationRows                = []string{"id", "book_id", "username", "barcode", "status", "queued_at", "ready_at", "pickup_deadline"}
+	waitingReservationResult       = []driver.Value{1, "FAKHRIL-Drama-0001", testStr, "", constant.ReservationWaitingStatus, time.Now(), nil, nil}
+	readyReservationResult         = []driver.Value{2, "FAKHRIL-Drama-0001", testStr, "ITEM-0001", constant.ReservationReadyStatus, time.Now(), time.Now(), time.Now()}
+	fulfilledReservationResult     = []driver.Value{2, "FAKHRIL-Drama-0001", testStr, "ITEM-0001", constant.ReservationFulfilledStatus, time.Now(), time.Now(), time.Now()}
+	emptyReservationResult         = []driver.Value{0, "", "", "", "", time.Time{}, nil, nil}
+	bookRows                       = []string{"id", "book_id", "title", "genre", "author", "publisher", "publication_year", "status", "timestamp"}
+	bookResult                     = []driver.Value{1, "FAKHRIL-Drama-0001", testStr, testStr, testStr, testStr, dateStr, constant.NotAvailableStatus, dateStr}
+	availableBookResult            = []driver.Value{1, "FAKHRIL-Drama-0001", testStr, testStr, testStr, testStr, dateStr, constant.AvailableStatus, dateStr}
+	emptyBookResult                = []driver.Value{0, "", "", "", "", "", "", "", ""}
+	itemRows                       = []string{"id", "barcode", "book_id", "shelf_location", "condition", "status", "timestamp"}
+	heldItemResult                 = []driver.Value{1, "ITEM-0001", "FAKHRIL-Drama-0001", "A-01", constant.ItemGoodCondition, constant.ItemOnHoldStatus, dateStr}
+	statusCountRows                = []string{"status", "total"}
+	testStr                        = "test"
+	dateStr                        = "2024-01-01"
+)
+
+type Suite struct {
+	suite.Suite
+	e                     *echo.Echo
+	DB                    *gorm.DB
+	mock                  sqlmock.Sqlmock
+	bookRepository        bookDomain.BookRepository
+	itemRepository        itemDomain.ItemRepository
+	reservationRepository domain.ReservationRepository
+	reservationUsecase    domain.ReservationUsecase
+	reservationHandler    handlers.ReservationHandler
+}
+
+func (s *Suite) SetupSuite() {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	s.e = echo.New()
+	s.e.Validator = validator.NewCustomValidator()
+	db, s.mock, err = sqlmock.New()
+	s.Require().NoError(err)
+
+	dialector := mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	})
+
+	s.DB, err = gorm.Open(dialector, &gorm.Config{})
+	s.Require().NoError(err)
+
+	s.bookRepository = bookRepo.NewBookRepository(s.DB)
+	s.itemRepository = itemRepo.NewItemRepository(s.DB)
+	s.reservationRepository = repositories.NewReservationRepository(s.DB)
+	s.reservationUsecase = usecases.NewReservationUsecase(s.reservationRepository, s.bookRepository, s.itemRepository, databases.NewUnitOfWork(s.DB))
+	s.reservationHandler = handlers.NewReservationHandler(s.e, s.reservationUsecase)
+}
+
+func (s *Suite) TearDownSuite() {
+	db, err := s.DB.DB()
+	s.Require().NoError(err)
+	db.Close()
+}
+
+// expectEndReservation covers locking the book and reading the reservation
+// again in the transaction that ends it.
+func (s *Suite) expectEndReservation(reservation []driver.Value) {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(reservation...))
+}
+
+// expectReleaseItem covers handing a held copy to the next in queue.
+func (s *Suite) expectReleaseItem(waiting bool) {
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(itemRows).AddRow(heldItemResult...))
+	if waiting {
+		s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(waitingReservationResult...))
+		s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+	} else {
+		s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows))
+	}
+	s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(statusCountRows).AddRow(constant.ItemOnHoldStatus, 1))
+	s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func (s *Suite) TestAddReservation() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		validatorErr   bool
+		notFound       bool
+		available      bool
+		duplicate      bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "book not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "book available", available: true, expectedStatus: http.StatusConflict},
+		{name: "already reserved", duplicate: true, expectedStatus: http.StatusConflict},
+		{name: "reserved meanwhile", sqlErr: gorm.ErrDuplicatedKey, expectedStatus: http.StatusConflict},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		var bodyFilepath string
+		if tt.bindErr {
+			bodyFilepath = reservationBodyInvalidFilePath
+		} else if tt.validatorErr {
+			bodyFilepath = reservationBodyEmptyFilePath
+		} else {
+			bodyFilepath = reservationBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPost, reservationEndpoint, jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(reservationEndpoint)
+		c.Set("username", testStr)
+
+		if !tt.bindErr && !tt.validatorErr {
+			s.mock.ExpectBegin()
+		}
+
+		if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(emptyBookResult...))
+			s.mock.ExpectRollback()
+		} else if tt.available {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(availableBookResult...))
+			s.mock.ExpectRollback()
+		} else if tt.duplicate {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(waitingReservationResult...))
+			s.mock.ExpectRollback()
+		} else if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows))
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else if !tt.bindErr && !tt.validatorErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(bookRows).AddRow(bookResult...))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows))
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+			s.mock.ExpectCommit()
+		}
+
+		err = s.reservationHandler.Add(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
+	}
+}
+
+func (s *Suite) TestCancelReservation() {
+	tests := []struct {
+		name           string
+		ready          bool
+		waiting        bool
+		notFound       bool
+		otherUser      bool
+		ended          bool
+		role           string
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "success ready hold", ready: true, expectedStatus: http.StatusOK},
+		{name: "success ready hold with waiting", ready: true, waiting: true, expectedStatus: http.StatusOK},
+		{name: "success by admin", otherUser: true, role: constant.Admin, expectedStatus: http.StatusOK},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "other user", otherUser: true, role: constant.Karyawan, expectedStatus: http.StatusForbidden},
+		{name: "ended meanwhile", ended: true, expectedStatus: http.StatusConflict},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, reservationEndpoint+"/1", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(reservationEndpoint + "/:reservation-id")
+		c.SetParamNames("reservation-id")
+		c.SetParamValues("1")
+		c.Set("role", tt.role)
+
+		if tt.otherUser {
+			c.Set("username", "other")
+		} else {
+			c.Set("username", testStr)
+		}
+
+		reservation := waitingReservationResult
+
+		if tt.notFound {
+			reservation = emptyReservationResult
+		} else if tt.ready {
+			reservation = readyReservationResult
+		}
+
+		s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(reservation...))
+
+		if tt.ended {
+			s.expectEndReservation(fulfilledReservationResult)
+			s.mock.ExpectRollback()
+		} else if tt.sqlErr != nil {
+			s.expectEndReservation(reservation)
+			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+			s.mock.ExpectRollback()
+		} else if !tt.notFound && (!tt.otherUser || tt.role == constant.Admin) {
+			s.expectEndReservation(reservation)
+			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+
+			if tt.ready {
+				s.expectReleaseItem(tt.waiting)
+			}
+
+			s.mock.ExpectCommit()
+		}
+
+		err := s.reservationHandler.Cancel(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
+	}
+}
+
+func (s *Suite) TestGetReservation() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		totalErr       bool
+		role           string
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", role: constant.Admin, expectedStatus: http.StatusOK},
+		{name: "success scoped to caller", role: constant.Karyawan, expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "total error", totalErr: true, sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("book_id", testStr)
+		q.Set("username", "other")
+		q.Set("status", constant.ReservationWaitingStatus)
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, reservationEndpoint+"?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(reservationEndpoint)
+		c.Set("username", testStr)
+		c.Set("role", tt.role)
+
+		// Members only see their own reservations
+		username := "other"
+
+		if tt.role != constant.Admin {
+			username = testStr
+		}
+
+		if tt.role != "" {
+			s.mock.ExpectQuery("").WithArgs(testStr, username, constant.ReservationWaitingStatus).WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(waitingReservationResult...))
+		} else if tt.sqlErr != nil && !tt.bindErr && tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.sqlErr != nil && !tt.bindErr && !tt.totalErr {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		}
+
+		err := s.reservationHandler.Get(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code)
+		s.Require().NoError(s.mock.ExpectationsWereMet(), tt.name)
+	}
+}
+
+func (s *Suite) TestGetByID() {
+	tests := []struct {
+		name           string
+		username       string
+		role           string
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", username: testStr, role: constant.Karyawan, expectedStatus: http.StatusOK},
+		{name: "success by admin", username: "other", role: constant.Admin, expectedStatus: http.StatusOK},
+		{name: "other user", username: "other", role: constant.Karyawan, expectedStatus: http.StatusForbidden},
+		{name: "sql error", username: testStr, sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, reservationEndpoint+"/1", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(reservationEndpoint + "/:reservation-id")
+		c.SetParamNames("reservation-id")
+		c.SetParamValues("1")
+		c.Set("username", tt.username)
+		c.Set("role", tt.role)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(waitingReservationResult...))
+		}
+
+		err := s.reservationHandler.GetByID(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+	}
+}
+
+func (s *Suite) TestExpireHolds() {
+	s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(reservationRows).AddRow(readyReservationResult...).AddRow(readyReservationResult...))
+	s.expectEndReservation(readyReservationResult)
+	s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
+	s.expectReleaseItem(true)
+	s.mock.ExpectCommit()
+
+	// The second hold was picked up after it was listed
+	s.expectEndReservation(fulfilledReservationResult)
+	s.mock.ExpectCommit()
+
+	result := <-s.reservationUsecase.ExpireHolds(context.Background())
+	s.Require().Nil(result.Error)
+	s.Require().Equal(int64(1), result.Total)
+	s.Require().NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
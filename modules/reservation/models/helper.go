@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+func (m *ReservationAdd) ToReservation(e Reservation) Reservation {
+	e.BookID = m.BookID
+	e.Status = constant.ReservationWaitingStatus
+	e.QueuedAt = utils.GetLocalTime()
+
+	return e
+}
+
+// Hold sets a copy aside for the reservation until the pickup period ends.
+func (m *Reservation) Hold(barcode string, period time.Duration) {
+	now := utils.GetLocalTime()
+	deadline := now.Add(period)
+
+	m.Barcode = barcode
+	m.Status = constant.ReservationReadyStatus
+	m.ReadyAt = &now
+	m.PickupDeadline = &deadline
+}
+
+// IsActive reports whether the reservation still holds a place in the queue.
+func (m *Reservation) IsActive() bool {
+	return m.Status == constant.ReservationWaitingStatus || m.Status == constant.ReservationReadyStatus
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Reservation struct {
+	ID             int64      `json:"id" gorm:"primaryKey"`
+	BookID         string     `json:"book_id"`
+	Username       string     `json:"username"`
+	Barcode        string     `json:"barcode"`
+	Status         string     `json:"status"`
+	QueuedAt       time.Time  `json:"queued_at"`
+	ReadyAt        *time.Time `json:"ready_at"`
+	PickupDeadline *time.Time `json:"pickup_deadline"`
+}
+
+func (Reservation) TableName() string {
+	return "reservation"
+}
@@ -0,0 +1,5 @@
+package models
+
+type ReservationAdd struct {
+	BookID string `json:"book_id" validate:"required"`
+}
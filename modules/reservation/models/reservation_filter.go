@@ -0,0 +1,10 @@
+package models
+
+import "github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+
+type ReservationFilter struct {
+	BookID   string `json:"book_id" query:"book_id"`
+	Username string `json:"username" query:"username"`
+	Status   string `json:"status" query:"status"`
+	utils.PaginationRequest
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/models"
+	"gorm.io/gorm"
+)
+
+func buildFilterQuery(db *gorm.DB, f models.ReservationFilter) *gorm.DB {
+	if f.BookID != "" {
+		db = db.Where("book_id = ?", f.BookID)
+	}
+
+	if f.Username != "" {
+		db = db.Where("username = ?", f.Username)
+	}
+
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+
+	return db
+}
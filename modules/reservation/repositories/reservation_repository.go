@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+)
+
+type reservationRepository struct {
+	db *gorm.DB
+}
+
+// Add implements domain.ReservationRepository.
+func (r *reservationRepository) Add(ctx context.Context, data models.Reservation) (result models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).Create(&data).Error
+	return data, err
+}
+
+// Get implements domain.ReservationRepository.
+func (r *reservationRepository) Get(ctx context.Context, filter models.ReservationFilter) (result []models.Reservation, total int64, err error) {
+	db := databases.Conn(ctx, r.db)
+	db = buildFilterQuery(db, filter)
+
+	if err = db.Model(&models.Reservation{}).Count(&total).Error; err != nil {
+		return
+	}
+
+	if !filter.DisablePagination {
+		db = db.Offset(int(filter.GetOffset())).Limit(int(filter.GetLimit()))
+	}
+
+	if err = db.Order("id").Find(&result).Error; err != nil {
+		return
+	}
+
+	return
+}
+
+// GetActive implements domain.ReservationRepository.
+func (r *reservationRepository) GetActive(ctx context.Context, book_id string, username string) (result models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).
+		Where("book_id = ? AND username = ? AND status IN ?", book_id, username, []string{constant.ReservationWaitingStatus, constant.ReservationReadyStatus}).
+		Limit(1).Find(&result).Error
+	return
+}
+
+// GetByID implements domain.ReservationRepository.
+func (r *reservationRepository) GetByID(ctx context.Context, id int64) (result models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).Where("id = ?", id).First(&result).Error
+	return
+}
+
+// GetExpired implements domain.ReservationRepository.
+func (r *reservationRepository) GetExpired(ctx context.Context, now time.Time) (result []models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).
+		Where("status = ? AND pickup_deadline < ?", constant.ReservationReadyStatus, now).
+		Order("id").Find(&result).Error
+	return
+}
+
+// GetFirstWaiting implements domain.ReservationRepository.
+func (r *reservationRepository) GetFirstWaiting(ctx context.Context, book_id string) (result models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).
+		Where("book_id = ? AND status = ?", book_id, constant.ReservationWaitingStatus).
+		Order("queued_at, id").Limit(1).Find(&result).Error
+	return
+}
+
+// GetReady implements domain.ReservationRepository.
+func (r *reservationRepository) GetReady(ctx context.Context, book_id string, username string) (result models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).
+		Where("book_id = ? AND username = ? AND status = ?", book_id, username, constant.ReservationReadyStatus).
+		Limit(1).Find(&result).Error
+	return
+}
+
+// Update implements domain.ReservationRepository.
+func (r *reservationRepository) Update(ctx context.Context, data models.Reservation) (result models.Reservation, err error) {
+	err = databases.Conn(ctx, r.db).Save(&data).Error
+	return data, err
+}
+
+func NewReservationRepository(db *gorm.DB) domain.ReservationRepository {
+	return &reservationRepository{db: db}
+}
@@ -0,0 +1,271 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	bookDomain "github.com/Zeroaril7/perpustakaan-go/modules/book/domain"
+	bookModel "github.com/Zeroaril7/perpustakaan-go/modules/book/models"
+	itemDomain "github.com/Zeroaril7/perpustakaan-go/modules/item/domain"
+	itemModel "github.com/Zeroaril7/perpustakaan-go/modules/item/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/reservation/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+type reservationUsecase struct {
+	reservationRepository domain.ReservationRepository
+	bookRepository        bookDomain.BookRepository
+	itemRepository        itemDomain.ItemRepository
+	unitOfWork            databases.UnitOfWork
+}
+
+// Add implements domain.ReservationUsecase.
+func (u *reservationUsecase) Add(ctx context.Context, data models.Reservation) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var result models.Reservation
+
+		// The book stays locked until the reservation is added, so two
+		// requests of the same user can not both find no active one
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			book, err := u.bookRepository.GetByBookIDForUpdate(ctx, data.BookID)
+
+			if err != nil {
+				return err
+			}
+
+			if book == (bookModel.Book{}) {
+				return httperror.NotFound("Book ID not found")
+			}
+
+			if book.Status == constant.AvailableStatus {
+				return httperror.Conflict("Book is available, borrow it directly")
+			}
+
+			active, err := u.reservationRepository.GetActive(ctx, data.BookID, data.Username)
+
+			if err != nil {
+				return err
+			}
+
+			if active != (models.Reservation{}) {
+				return httperror.Conflict("Book is already reserved by this user")
+			}
+
+			result, err = u.reservationRepository.Add(ctx, data)
+
+			return err
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// Cancel implements domain.ReservationUsecase.
+func (u *reservationUsecase) Cancel(ctx context.Context, data models.Reservation) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		var result models.Reservation
+
+		err := u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+			ended, ok, err := u.endReservation(ctx, data, constant.ReservationCancelledStatus)
+
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return httperror.Conflict("Reservation is no longer active")
+			}
+
+			result = ended
+			return nil
+		})
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// ExpireHolds implements domain.ReservationUsecase.
+func (u *reservationUsecase) ExpireHolds(ctx context.Context) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		candidates, err := u.reservationRepository.GetExpired(ctx, utils.GetLocalTime())
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		expired := make([]models.Reservation, 0, len(candidates))
+
+		for _, reservation := range candidates {
+			err = u.unitOfWork.Do(ctx, func(ctx context.Context) error {
+				result, ok, err := u.endReservation(ctx, reservation, constant.ReservationExpiredStatus)
+
+				// A hold picked up or cancelled since it was listed is skipped
+				if ok {
+					expired = append(expired, result)
+				}
+
+				return err
+			})
+
+			if err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return
+			}
+		}
+
+		output <- utils.Result{Data: expired, Total: int64(len(expired))}
+	}()
+
+	return output
+}
+
+// Get implements domain.ReservationUsecase.
+func (u *reservationUsecase) Get(ctx context.Context, filter models.ReservationFilter) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, total, err := u.reservationRepository.Get(ctx, filter)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result, Total: total}
+	}()
+
+	return output
+}
+
+// GetByID implements domain.ReservationUsecase.
+func (u *reservationUsecase) GetByID(ctx context.Context, id int64) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, err := u.reservationRepository.GetByID(ctx, id)
+
+		if err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: result}
+	}()
+
+	return output
+}
+
+// endReservation moves data to status unless it has ended meanwhile, and
+// releases the copy it held. The book and copy rows stay locked until the
+// surrounding transaction ends, like loans lock them, so a returned copy is
+// not handed out twice.
+func (u *reservationUsecase) endReservation(ctx context.Context, data models.Reservation, status string) (result models.Reservation, ok bool, err error) {
+	book, err := u.bookRepository.GetByBookIDForUpdate(ctx, data.BookID)
+
+	if err != nil {
+		return
+	}
+
+	result, err = u.reservationRepository.GetByID(ctx, data.ID)
+
+	if err != nil || !result.IsActive() {
+		return result, false, err
+	}
+
+	wasReady := result.Status == constant.ReservationReadyStatus
+	result.Status = status
+
+	if result, err = u.reservationRepository.Update(ctx, result); err != nil {
+		return
+	}
+
+	if wasReady {
+		if err = u.releaseItem(ctx, book, result.Barcode); err != nil {
+			return
+		}
+	}
+
+	return result, true, nil
+}
+
+// releaseItem hands a copy that is no longer held to the next member in the
+// queue, or puts it back on the shelf when nobody is waiting.
+func (u *reservationUsecase) releaseItem(ctx context.Context, book bookModel.Book, barcode string) error {
+	item, err := u.itemRepository.GetByBarcodeForUpdate(ctx, barcode)
+
+	if err != nil {
+		return err
+	}
+
+	next, err := u.reservationRepository.GetFirstWaiting(ctx, item.BookID)
+
+	if err != nil {
+		return err
+	}
+
+	if next != (models.Reservation{}) {
+		next.Hold(item.Barcode, config.Config().HoldPickupPeriod())
+
+		if _, err = u.reservationRepository.Update(ctx, next); err != nil {
+			return err
+		}
+
+		item.Status = constant.ItemOnHoldStatus
+	} else {
+		item.Status = constant.ItemAvailableStatus
+	}
+
+	if _, err = u.itemRepository.Update(ctx, item); err != nil {
+		return err
+	}
+
+	counts, err := u.itemRepository.CountByStatus(ctx, book.BookID)
+
+	if err != nil {
+		return err
+	}
+
+	book.Status = itemModel.BookStatus(counts)
+
+	_, err = u.bookRepository.Update(ctx, book)
+	return err
+}
+
+func NewReservationUsecase(reservationRepository domain.ReservationRepository, bookRepository bookDomain.BookRepository, itemRepository itemDomain.ItemRepository, unitOfWork databases.UnitOfWork) domain.ReservationUsecase {
+	return &reservationUsecase{reservationRepository: reservationRepository, bookRepository: bookRepository, itemRepository: itemRepository, unitOfWork: unitOfWork}
+}
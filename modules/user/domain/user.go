@@ -17,6 +17,7 @@ type UserRepository interface {
 
 type UserUsecase interface {
 	Add(ctx context.Context, data models.User) <-chan utils.Result
+	ChangePassword(ctx context.Context, username string, data models.UserPassword) <-chan utils.Result
 	Delete(ctx context.Context, username string) <-chan utils.Result
 	Get(ctx context.Context, filter models.UserFilter) <-chan utils.Result
 	GetByUsername(ctx context.Context, username string) <-chan utils.Result
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Zeroaril7/perpustakaan-go/config"
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	fineDomain "github.com/Zeroaril7/perpustakaan-go/modules/fine/domain"
+	fineModels "github.com/Zeroaril7/perpustakaan-go/modules/fine/models"
+	loanDomain "github.com/Zeroaril7/perpustakaan-go/modules/loan/domain"
+	loanModels "github.com/Zeroaril7/perpustakaan-go/modules/loan/models"
+	"github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/password"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/rbac"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// MeHandler serves the signed-in user. The user is always the subject of the
+// access token, never a path or query parameter.
+type MeHandler interface {
+	Get(c echo.Context) error
+	GetFines(c echo.Context) error
+	GetLoans(c echo.Context) error
+	UpdatePassword(c echo.Context) error
+}
+
+type meHandler struct {
+	userUsecase     domain.UserUsecase
+	loanBookUsecase loanDomain.LoanBookUsecase
+	fineUsecase     fineDomain.FineUsecase
+}
+
+func NewMeHandler(e *echo.Echo, userUsecase domain.UserUsecase, loanBookUsecase loanDomain.LoanBookUsecase, fineUsecase fineDomain.FineUsecase) MeHandler {
+	handler := &meHandler{
+		userUsecase:     userUsecase,
+		loanBookUsecase: loanBookUsecase,
+		fineUsecase:     fineUsecase,
+	}
+
+	group := e.Group("/me", middlewares.VerifyJWTRSA(config.Config().PublicKey), middlewares.EchoSetCredential())
+	group.GET("", handler.Get)
+	group.GET("/fines", handler.GetFines)
+	group.GET("/loans", handler.GetLoans)
+	group.PUT("/password", handler.UpdatePassword)
+
+	return handler
+}
+
+// Get implements MeHandler.
+func (h *meHandler) Get(c echo.Context) error {
+	result := <-h.userUsecase.GetByUsername(c.Request().Context(), utils.ConvertString(c.Get("username")))
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	user := result.Data.(models.User)
+
+	if user == (models.User{}) {
+		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+	}
+
+	profile := models.UserProfile{
+		ID:          user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		Permissions: rbac.Default().Permissions(user.Role),
+	}
+
+	return utils.Response(profile, "Get user success", http.StatusOK, c)
+}
+
+// GetFines implements MeHandler.
+func (h *meHandler) GetFines(c echo.Context) error {
+	filter := new(fineModels.FineFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	filter.Username = utils.ConvertString(c.Get("username"))
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	result := <-h.fineUsecase.Get(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get fine success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// GetLoans implements MeHandler.
+func (h *meHandler) GetLoans(c echo.Context) error {
+	filter := new(loanModels.LoanBookFilter)
+
+	if err := c.Bind(filter); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	filter.User = utils.ConvertString(c.Get("username"))
+
+	if !filter.DisablePagination {
+		filter.SetDefault()
+	}
+
+	result := <-h.loanBookUsecase.Get(c.Request().Context(), *filter)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.ResponseWithPagination(result.Data, "Get loan book success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+}
+
+// UpdatePassword implements MeHandler.
+func (h *meHandler) UpdatePassword(c echo.Context) error {
+	data := new(models.UserPassword)
+
+	if err := c.Bind(data); err != nil {
+		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
+	}
+
+	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	username := utils.ConvertString(c.Get("username"))
+
+	if err := password.Default().Check(username, data.NewPassword); err != nil {
+		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+	}
+
+	result := <-h.userUsecase.ChangePassword(c.Request().Context(), username, *data)
+
+	if result.Error != nil {
+		return utils.ResponseError(result.Error, c)
+	}
+
+	return utils.Response(result.Data, "Update password success", http.StatusOK, c)
+}
@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	meEndpoint                      = "/me"
+	loanBookRows                    = []string{"id", "loan_id", "book_id", "barcode", "title", "username", "loan_start_date", "loan_end_date", "status", "renewal_count"}
+	loanBookResult                  = []driver.Value{1, "LOAN-0001", "TEST-DRAMA-0001", "TEST-DRAMA-0001-C1", "test", "test", "2024-01-01", "2024-01-08", constant.LoanBorrowedStatus, 0}
+	fineRows                        = []string{"id", "loan_id", "username", "book_id", "days_late", "rate", "amount", "status"}
+	fineResult                      = []driver.Value{1, "LOAN-0001", "test", "TEST-DRAMA-0001", 2, 1000, 2000, constant.FineUnpaidStatus}
+	userPasswordBodyFilePath        = "test_data/user_password_body_req.json"
+	userPasswordBodyWrongFilePath   = "test_data/user_password_body_wrong_req.json"
+	userPasswordBodyInvalidFilePath = "test_data/user_password_body_invalid_req.json"
+	userPasswordBodyEmptyFilePath   = "test_data/user_password_body_empty_req.json"
+	userPasswordBodyWeakFilePath    = "test_data/user_password_body_weak_req.json"
+	userPasswordBodySameFilePath    = "test_data/user_password_body_same_req.json"
+)
+
+func (s *Suite) TestGetMe() {
+	tests := []struct {
+		name           string
+		notFound       bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, meEndpoint, nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(meEndpoint)
+		c.Set("username", testStr)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnError(tt.sqlErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows(userRows).AddRow(emptyResult...))
+		} else {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+		}
+
+		err := s.meHandler.Get(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().NotContains(rec.Body.String(), "password", tt.name)
+			s.Require().Contains(rec.Body.String(), constant.FineRead, tt.name)
+		}
+	}
+}
+
+func (s *Suite) TestGetMyLoans() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("user", "other")
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, meEndpoint+"/loans?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(meEndpoint + "/loans")
+		c.Set("username", testStr)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnError(tt.sqlErr)
+		} else if !tt.bindErr {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows(loanBookRows).AddRow(loanBookResult...))
+		}
+
+		err := s.meHandler.GetLoans(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+	}
+}
+
+func (s *Suite) TestGetMyFines() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		q := make(url.Values)
+		q.Set("page", "1")
+		q.Set("username", "other")
+
+		if tt.bindErr {
+			q.Set("per_page", "a")
+		} else {
+			q.Set("per_page", "10")
+		}
+
+		req := httptest.NewRequest(http.MethodGet, meEndpoint+"/fines?"+q.Encode(), nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(meEndpoint + "/fines")
+		c.Set("username", testStr)
+
+		if tt.sqlErr != nil {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnError(tt.sqlErr)
+		} else if !tt.bindErr {
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+			s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows(fineRows).AddRow(fineResult...))
+		}
+
+		err := s.meHandler.GetFines(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+	}
+}
+
+func (s *Suite) TestUpdateMyPassword() {
+	tests := []struct {
+		name           string
+		bindErr        bool
+		validatorErr   bool
+		wrongPassword  bool
+		weakPassword   bool
+		samePassword   bool
+		notFound       bool
+		sqlGetDataErr  error
+		sqlErr         error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "wrong current password", wrongPassword: true, expectedStatus: http.StatusBadRequest},
+		{name: "weak new password", weakPassword: true, expectedStatus: http.StatusBadRequest},
+		{name: "same new password", samePassword: true, expectedStatus: http.StatusBadRequest},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
+		{name: "sql get data error", sqlGetDataErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+		{name: "sql error", sqlErr: sql.ErrConnDone, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		var bodyFilepath string
+		if tt.bindErr {
+			bodyFilepath = userPasswordBodyInvalidFilePath
+		} else if tt.validatorErr {
+			bodyFilepath = userPasswordBodyEmptyFilePath
+		} else if tt.wrongPassword {
+			bodyFilepath = userPasswordBodyWrongFilePath
+		} else if tt.weakPassword {
+			bodyFilepath = userPasswordBodyWeakFilePath
+		} else if tt.samePassword {
+			bodyFilepath = userPasswordBodySameFilePath
+		} else {
+			bodyFilepath = userPasswordBodyFilePath
+		}
+
+		jsonFile, err := os.Open(bodyFilepath)
+		s.Require().NoError(err)
+		defer jsonFile.Close()
+
+		req := httptest.NewRequest(http.MethodPut, meEndpoint+"/password", jsonFile)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := s.e.NewContext(req, rec)
+		c.SetPath(meEndpoint + "/password")
+		c.Set("username", testStr)
+
+		if !tt.bindErr && !tt.validatorErr && !tt.weakPassword {
+			if tt.sqlGetDataErr != nil {
+				s.mock.ExpectQuery("").WithArgs(testStr).WillReturnError(tt.sqlGetDataErr)
+			} else if tt.notFound {
+				s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows(userRows))
+			} else {
+				s.mock.ExpectQuery("").WithArgs(testStr).WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
+			}
+		}
+
+		if !tt.bindErr && !tt.validatorErr && !tt.wrongPassword && !tt.weakPassword && !tt.samePassword && !tt.notFound && tt.sqlGetDataErr == nil {
+			s.mock.ExpectBegin()
+
+			if tt.sqlErr != nil {
+				s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
+				s.mock.ExpectRollback()
+			} else {
+				s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+				s.mock.ExpectCommit()
+			}
+		}
+
+		err = s.meHandler.UpdatePassword(c)
+		s.Require().NoError(err)
+		s.Require().Equal(tt.expectedStatus, rec.Code, tt.name)
+	}
+}
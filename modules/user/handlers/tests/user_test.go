@@ -11,11 +11,20 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Zeroaril7/perpustakaan-go/config"
+	bookRepo "github.com/Zeroaril7/perpustakaan-go/modules/book/repositories"
+	fineRepo "github.com/Zeroaril7/perpustakaan-go/modules/fine/repositories"
+	fineUsecases "github.com/Zeroaril7/perpustakaan-go/modules/fine/usecases"
+	itemRepo "github.com/Zeroaril7/perpustakaan-go/modules/item/repositories"
+	loanRepo "github.com/Zeroaril7/perpustakaan-go/modules/loan/repositories"
+	loanUsecases "github.com/Zeroaril7/perpustakaan-go/modules/loan/usecases"
+	reservationRepo "github.com/Zeroaril7/perpustakaan-go/modules/reservation/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/handlers"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/repositories"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/usecases"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sequence"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/validator"
 	"github.com/labstack/echo/v4"
@@ -27,11 +36,17 @@ import (
 var (
 	userEndpoint            = "/user"
 	userRows                = []string{"id", "username", "password", "role"}
-	userResult              = []driver.Value{1, "test", utils.HashPassword("test123"), "ADMIN"}
+	userResult              = []driver.Value{1, "test", utils.HashPassword("Test-1234"), "ADMIN"}
 	emptyResult             = []driver.Value{0, "", "", ""}
 	userBodyFilePath        = "test_data/user_body_req.json"
 	userBodyInvalidFilePath = "test_data/user_body_invalid_req.json"
 	userBodyEmptyFilePath   = "test_data/user_body_empty_req.json"
+	userBodyWeakFilePath    = "test_data/user_body_weak_req.json"
+	updateBodyFilePath      = "test_data/user_update_body_req.json"
+	updateBodyResetFilePath = "test_data/user_update_body_reset_req.json"
+	updateBodyWeakFilePath  = "test_data/user_update_body_weak_req.json"
+	updateBodyInvalidPath   = "test_data/user_update_body_invalid_req.json"
+	updateBodyEmptyFilePath = "test_data/user_update_body_empty_req.json"
 	testStr                 = "test"
 )
 
@@ -43,6 +58,7 @@ type Suite struct {
 	userRepository domain.UserRepository
 	userUsecase    domain.UserUsecase
 	userHandler    handlers.UserHandler
+	meHandler      handlers.MeHandler
 }
 
 func (s *Suite) SetupSuite() {
@@ -68,6 +84,10 @@ func (s *Suite) SetupSuite() {
 	s.userUsecase = usecases.NewUserUsecase(s.userRepository)
 	s.userHandler = handlers.NewUserHandler(s.e, s.userUsecase)
 
+	loanBookUsecase := loanUsecases.NewLoanBookUsecase(loanRepo.NewLoanBookRepository(s.DB), bookRepo.NewBookRepository(s.DB), itemRepo.NewItemRepository(s.DB), reservationRepo.NewReservationRepository(s.DB), fineRepo.NewFineRepository(s.DB), s.userRepository, databases.NewUnitOfWork(s.DB), sequence.New(s.DB, constant.DefaultSequenceWidth))
+	fineUsecase := fineUsecases.NewFineUsecase(fineRepo.NewFineRepository(s.DB), databases.NewUnitOfWork(s.DB))
+	s.meHandler = handlers.NewMeHandler(s.e, s.userUsecase, loanBookUsecase, fineUsecase)
+
 	config.LoadConfig()
 }
 
@@ -84,11 +104,13 @@ func (s *Suite) TestAddUser() {
 		sqlErr         error
 		bindErr        bool
 		validatorErr   bool
+		weakPassword   bool
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "bind error", bindErr: true, expectedStatus: http.StatusBadRequest},
 		{name: "validator error", validatorErr: true, expectedStatus: http.StatusBadRequest},
+		{name: "weak password", weakPassword: true, expectedStatus: http.StatusBadRequest},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 	}
@@ -99,6 +121,8 @@ func (s *Suite) TestAddUser() {
 			bodyFilepath = userBodyInvalidFilePath
 		} else if tt.validatorErr {
 			bodyFilepath = userBodyEmptyFilePath
+		} else if tt.weakPassword {
+			bodyFilepath = userBodyWeakFilePath
 		} else {
 			bodyFilepath = userBodyFilePath
 		}
@@ -119,7 +143,7 @@ func (s *Suite) TestAddUser() {
 			s.mock.ExpectBegin()
 			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
 			s.mock.ExpectRollback()
-		} else if !tt.bindErr && !tt.validatorErr {
+		} else if !tt.bindErr && !tt.validatorErr && !tt.weakPassword {
 			s.mock.ExpectBegin()
 			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
 			s.mock.ExpectCommit()
@@ -217,6 +241,10 @@ func (s *Suite) TestGetUser() {
 		err := s.userHandler.Get(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().NotContains(rec.Body.String(), "password", tt.name)
+		}
 	}
 }
 
@@ -224,10 +252,12 @@ func (s *Suite) TestGetByUsername() {
 	tests := []struct {
 		name           string
 		sqlErr         error
+		notFound       bool
 		expectedStatus int
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
+		{name: "not found", notFound: true, expectedStatus: http.StatusNotFound},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 	}
@@ -244,6 +274,8 @@ func (s *Suite) TestGetByUsername() {
 
 		if tt.sqlErr != nil {
 			s.mock.ExpectQuery("").WithArgs().WillReturnError(tt.sqlErr)
+		} else if tt.notFound {
+			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(emptyResult...))
 		} else {
 			s.mock.ExpectQuery("").WithArgs().WillReturnRows(sqlmock.NewRows(userRows).AddRow(userResult...))
 		}
@@ -251,6 +283,10 @@ func (s *Suite) TestGetByUsername() {
 		err := s.userHandler.GetByUsername(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().NotContains(rec.Body.String(), "password", tt.name)
+		}
 	}
 }
 
@@ -263,9 +299,13 @@ func (s *Suite) TestUpdateUser() {
 		notFound       bool
 		bindErr        bool
 		validatorErr   bool
+		resetPassword  bool
+		weakPassword   bool
 	}{
 		{name: "success", expectedStatus: http.StatusOK},
 		{name: "success", expectedStatus: http.StatusOK},
+		{name: "reset password", resetPassword: true, expectedStatus: http.StatusOK},
+		{name: "weak password", weakPassword: true, expectedStatus: http.StatusBadRequest},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql error", sqlErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
 		{name: "sql get user error", sqlGetUserErr: sql.ErrNoRows, expectedStatus: http.StatusInternalServerError},
@@ -277,11 +317,15 @@ func (s *Suite) TestUpdateUser() {
 	for _, tt := range tests {
 		var bodyFilepath string
 		if tt.bindErr {
-			bodyFilepath = userBodyInvalidFilePath
+			bodyFilepath = updateBodyInvalidPath
 		} else if tt.validatorErr {
-			bodyFilepath = userBodyEmptyFilePath
+			bodyFilepath = updateBodyEmptyFilePath
+		} else if tt.resetPassword {
+			bodyFilepath = updateBodyResetFilePath
+		} else if tt.weakPassword {
+			bodyFilepath = updateBodyWeakFilePath
 		} else {
-			bodyFilepath = userBodyFilePath
+			bodyFilepath = updateBodyFilePath
 		}
 
 		jsonFile, err := os.Open(bodyFilepath)
@@ -309,7 +353,7 @@ func (s *Suite) TestUpdateUser() {
 			s.mock.ExpectBegin()
 			s.mock.ExpectExec("").WithArgs().WillReturnError(tt.sqlErr)
 			s.mock.ExpectRollback()
-		} else if tt.sqlErr == nil && tt.sqlGetUserErr == nil && !tt.notFound && !tt.bindErr && !tt.validatorErr {
+		} else if tt.sqlErr == nil && tt.sqlGetUserErr == nil && !tt.notFound && !tt.bindErr && !tt.validatorErr && !tt.weakPassword {
 			s.mock.ExpectBegin()
 			s.mock.ExpectExec("").WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
 			s.mock.ExpectCommit()
@@ -318,6 +362,10 @@ func (s *Suite) TestUpdateUser() {
 		err = s.userHandler.Update(c)
 		s.Require().NoError(err)
 		s.Require().Equal(tt.expectedStatus, rec.Code)
+
+		if tt.expectedStatus == http.StatusOK {
+			s.Require().NotContains(rec.Body.String(), "password", tt.name)
+		}
 	}
 }
 
@@ -7,7 +7,9 @@ import (
 	"github.com/Zeroaril7/perpustakaan-go/middlewares"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/password"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
@@ -29,7 +31,7 @@ func NewUserHandler(e *echo.Echo, userUsecase domain.UserUsecase) UserHandler {
 		userUsecase: userUsecase,
 	}
 
-	group := e.Group("/user", middlewares.VerifyBasicAuth(config.Config().BasicAuthUsername, config.Config().BasicAuthPassword))
+	group := e.Group("/user", middlewares.Authenticate(config.Config().PublicKey, config.Config().BasicAccount()), middlewares.RequirePermission(constant.UserManage))
 	group.DELETE("/:username", handler.Delete)
 	group.GET("", handler.Get)
 	group.GET("/:username", handler.GetByUsername)
@@ -48,11 +50,14 @@ func (h *userHandler) Add(c echo.Context) error {
 	}
 
 	if err := c.Validate(data); err != nil {
+		return utils.ResponseError(err, c)
+	}
+
+	if err := password.Default().Check(data.Username, data.Password); err != nil {
 		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
 	}
 
-	password := utils.HashPassword(data.Password)
-	data.Password = password
+	data.Password = utils.HashPassword(data.Password)
 
 	expend := models.User{}
 	expend = data.ToUser(expend)
@@ -97,7 +102,7 @@ func (h *userHandler) Get(c echo.Context) error {
 		return utils.ResponseError(result.Error, c)
 	}
 
-	return utils.ResponseWithPagination(result.Data, "Get user success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
+	return utils.ResponseWithPagination(models.ToUserResponses(result.Data.([]models.User)), "Get user success", http.StatusOK, result.Total, filter.GetPaginationRequest(), c)
 }
 
 // GetByUsername implements UserHandler.
@@ -110,7 +115,13 @@ func (h *userHandler) GetByUsername(c echo.Context) error {
 		return utils.ResponseError(result.Error, c)
 	}
 
-	return utils.Response(result.Data, "Get user success", http.StatusOK, c)
+	user := result.Data.(models.User)
+
+	if user == (models.User{}) {
+		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+	}
+
+	return utils.Response(user.ToResponse(), "Get user success", http.StatusOK, c)
 }
 
 // Update implements UserHandler.
@@ -129,18 +140,21 @@ func (h *userHandler) Update(c echo.Context) error {
 		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
 	}
 
-	data := new(models.UserAdd)
+	data := new(models.UserUpdate)
 	if err := c.Bind(data); err != nil {
 		return utils.ResponseError(httperror.BadRequest(httperror.BindErrorMessage), c)
 	}
 
 	if err := c.Validate(data); err != nil {
-		return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		return utils.ResponseError(err, c)
 	}
 
-	if !utils.CheckPasswordHash(data.Password, expend.Password) {
-		newPassword := utils.HashPassword(data.Password)
-		data.Password = newPassword
+	if data.Password != "" {
+		if err := password.Default().Check(expend.Username, data.Password); err != nil {
+			return utils.ResponseError(httperror.BadRequest(err.Error()), c)
+		}
+
+		data.Password = utils.HashPassword(data.Password)
 	}
 
 	expend = data.ToUser(expend)
@@ -151,5 +165,5 @@ func (h *userHandler) Update(c echo.Context) error {
 		return utils.ResponseError(result.Error, c)
 	}
 
-	return utils.Response(result.Data, "Update user success", http.StatusOK, c)
+	return utils.Response(result.Data.(models.User).ToResponse(), "Update user success", http.StatusOK, c)
 }
@@ -7,3 +7,31 @@ func (m *UserAdd) ToUser(e User) User {
 
 	return e
 }
+
+func (m *UserUpdate) ToUser(e User) User {
+	if m.Password != "" {
+		e.Password = m.Password
+	}
+
+	e.Role = m.Role
+
+	return e
+}
+
+func (e User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:       e.ID,
+		Username: e.Username,
+		Role:     e.Role,
+	}
+}
+
+func ToUserResponses(users []User) []UserResponse {
+	result := make([]UserResponse, 0, len(users))
+
+	for _, user := range users {
+		result = append(result, user.ToResponse())
+	}
+
+	return result
+}
@@ -0,0 +1,10 @@
+package models
+
+// UserProfile is what a user sees about themselves. It never carries the
+// password hash.
+type UserProfile struct {
+	ID          int64    `json:"id"`
+	Username    string   `json:"username"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
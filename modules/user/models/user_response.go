@@ -0,0 +1,9 @@
+package models
+
+// UserResponse is a user as the API returns it. It never carries the
+// password hash.
+type UserResponse struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
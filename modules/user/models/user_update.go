@@ -0,0 +1,8 @@
+package models
+
+// UserUpdate changes the role of a user. A password resets theirs; without
+// one the current password is kept.
+type UserUpdate struct {
+	Password string `json:"password"`
+	Role     string `json:"role" validate:"required"`
+}
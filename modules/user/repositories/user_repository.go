@@ -5,6 +5,7 @@ import (
 
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
 	"gorm.io/gorm"
 )
 
@@ -14,18 +15,18 @@ type userRepository struct {
 
 // Add implements domain.UserRepository.
 func (r *userRepository) Add(ctx context.Context, data models.User) (result models.User, err error) {
-	err = r.db.WithContext(ctx).Create(&data).Error
+	err = databases.Conn(ctx, r.db).Create(&data).Error
 	return data, err
 }
 
 // Delete implements domain.UserRepository.
 func (r *userRepository) Delete(ctx context.Context, username string) error {
-	return r.db.WithContext(ctx).Where("username = ?", username).Delete(&models.User{}).Error
+	return databases.Conn(ctx, r.db).Where("username = ?", username).Delete(&models.User{}).Error
 }
 
 // Get implements domain.UserRepository.
 func (r *userRepository) Get(ctx context.Context, filter models.UserFilter) (result []models.User, total int64, err error) {
-	db := r.db.WithContext(ctx)
+	db := databases.Conn(ctx, r.db)
 	db = buildFilterQuery(db, filter)
 
 	if err = db.Model(&models.User{}).Count(&total).Error; err != nil {
@@ -45,13 +46,13 @@ func (r *userRepository) Get(ctx context.Context, filter models.UserFilter) (res
 
 // GetByUsername implements domain.UserRepository.
 func (r *userRepository) GetByUsername(ctx context.Context, username string) (result models.User, err error) {
-	err = r.db.WithContext(ctx).Where("username = ?", username).First(&result).Error
+	err = databases.Conn(ctx, r.db).Where("username = ?", username).First(&result).Error
 	return
 }
 
 // Update implements domain.UserRepository.
 func (r *userRepository) Update(ctx context.Context, data models.User) (result models.User, err error) {
-	err = r.db.WithContext(ctx).Save(&data).Error
+	err = databases.Conn(ctx, r.db).Save(&data).Error
 	return data, err
 }
 
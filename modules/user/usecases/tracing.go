@@ -0,0 +1,56 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
+	"github.com/Zeroaril7/perpustakaan-go/modules/user/models"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+// tracedUserUsecase runs every call of a domain.UserUsecase in a span, see tracing.Result.
+type tracedUserUsecase struct {
+	next domain.UserUsecase
+}
+
+// NewTracedUserUsecase returns next with every call traced.
+func NewTracedUserUsecase(next domain.UserUsecase) domain.UserUsecase {
+	return &tracedUserUsecase{next: next}
+}
+
+// Add implements domain.UserUsecase.
+func (u *tracedUserUsecase) Add(ctx context.Context, data models.User) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Add")
+	return tracing.Result(span, u.next.Add(ctx, data))
+}
+
+// ChangePassword implements domain.UserUsecase.
+func (u *tracedUserUsecase) ChangePassword(ctx context.Context, username string, data models.UserPassword) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "UserUsecase.ChangePassword")
+	return tracing.Result(span, u.next.ChangePassword(ctx, username, data))
+}
+
+// Delete implements domain.UserUsecase.
+func (u *tracedUserUsecase) Delete(ctx context.Context, username string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Delete")
+	return tracing.Result(span, u.next.Delete(ctx, username))
+}
+
+// Get implements domain.UserUsecase.
+func (u *tracedUserUsecase) Get(ctx context.Context, filter models.UserFilter) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Get")
+	return tracing.Result(span, u.next.Get(ctx, filter))
+}
+
+// GetByUsername implements domain.UserUsecase.
+func (u *tracedUserUsecase) GetByUsername(ctx context.Context, username string) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "UserUsecase.GetByUsername")
+	return tracing.Result(span, u.next.GetByUsername(ctx, username))
+}
+
+// Update implements domain.UserUsecase.
+func (u *tracedUserUsecase) Update(ctx context.Context, data models.User) <-chan utils.Result {
+	ctx, span := tracing.Start(ctx, "UserUsecase.Update")
+	return tracing.Result(span, u.next.Update(ctx, data))
+}
@@ -2,11 +2,13 @@ package usecases
 
 import (
 	"context"
+	"errors"
 
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/domain"
 	"github.com/Zeroaril7/perpustakaan-go/modules/user/models"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
 	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"gorm.io/gorm"
 )
 
 type userUsecase struct {
@@ -23,7 +25,7 @@ func (u *userUsecase) Add(ctx context.Context, data models.User) <-chan utils.Re
 		result, err := u.userRepository.Add(ctx, data)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -33,6 +35,48 @@ func (u *userUsecase) Add(ctx context.Context, data models.User) <-chan utils.Re
 	return output
 }
 
+// ChangePassword implements domain.UserUsecase.
+func (u *userUsecase) ChangePassword(ctx context.Context, username string, data models.UserPassword) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		user, err := u.userRepository.GetByUsername(ctx, username)
+
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		if user == (models.User{}) {
+			output <- utils.Result{Error: httperror.NotFound(httperror.NotFoundErrorMessage)}
+			return
+		}
+
+		if !utils.CheckPasswordHash(data.CurrentPassword, user.Password) {
+			output <- utils.Result{Error: httperror.BadRequest(httperror.InvalidPasswordMsg)}
+			return
+		}
+
+		if utils.CheckPasswordHash(data.NewPassword, user.Password) {
+			output <- utils.Result{Error: httperror.BadRequest(httperror.SamePasswordMsg)}
+			return
+		}
+
+		user.Password = utils.HashPassword(data.NewPassword)
+
+		if _, err = u.userRepository.Update(ctx, user); err != nil {
+			output <- utils.Result{Error: httperror.FromError(err)}
+			return
+		}
+
+		output <- utils.Result{Data: user.Username}
+	}()
+
+	return output
+}
+
 // Delete implements domain.UserUsecase.
 func (u *userUsecase) Delete(ctx context.Context, username string) <-chan utils.Result {
 	output := make(chan utils.Result)
@@ -43,7 +87,7 @@ func (u *userUsecase) Delete(ctx context.Context, username string) <-chan utils.
 		err := u.userRepository.Delete(ctx, username)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -63,7 +107,7 @@ func (u *userUsecase) Get(ctx context.Context, filter models.UserFilter) <-chan
 		result, total, err := u.userRepository.Get(ctx, filter)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 			return
 		}
 
@@ -83,7 +127,7 @@ func (u *userUsecase) GetByUsername(ctx context.Context, username string) <-chan
 		result, err := u.userRepository.GetByUsername(ctx, username)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 		}
 
 		output <- utils.Result{Data: result}
@@ -102,7 +146,7 @@ func (u *userUsecase) Update(ctx context.Context, data models.User) <-chan utils
 		result, err := u.userRepository.Update(ctx, data)
 
 		if err != nil {
-			output <- utils.Result{Error: httperror.InternalServerError(err.Error())}
+			output <- utils.Result{Error: httperror.FromError(err)}
 		}
 
 		output <- utils.Result{Data: result}
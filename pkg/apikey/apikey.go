@@ -0,0 +1,96 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+)
+
+var ErrInvalidKey = errors.New(httperror.InvalidAPIKeyMsg)
+
+// Principal is the machine client an API key signs in. It is granted its
+// scopes instead of the permissions of a role.
+type Principal struct {
+	Name   string
+	Prefix string
+	Scopes []string
+}
+
+// Username is the name requests made with the key act as. It can not be
+// mistaken for a user, so a key never acts on the records of one.
+func (p Principal) Username() string {
+	return constant.APIKeyUsernamePrefix + p.Prefix
+}
+
+// Resolver looks up the principal of a key. Keys that are unknown, expired
+// or revoked return ErrInvalidKey.
+type Resolver interface {
+	Resolve(ctx context.Context, key string) (Principal, error)
+}
+
+// Generate returns a new key and its prefix. Keys look like
+// "<scheme>_<prefix>_<secret>"; the prefix can be shown to tell keys apart,
+// the rest is only given out once.
+func Generate() (key, prefix string, err error) {
+	random := make([]byte, constant.APIKeyPrefixBytes)
+
+	if _, err = rand.Read(random); err != nil {
+		return
+	}
+
+	secret, err := utils.GenerateToken(32)
+
+	if err != nil {
+		return
+	}
+
+	prefix = hex.EncodeToString(random)
+
+	return constant.APIKeyScheme + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// PrefixOf returns the prefix of key, or "" when key is not one Generate
+// could have made.
+func PrefixOf(key string) string {
+	parts := strings.SplitN(key, "_", 3)
+
+	if len(parts) != 3 || parts[0] != constant.APIKeyScheme || len(parts[1]) != 2*constant.APIKeyPrefixBytes || parts[2] == "" {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// Hash is what a key is stored as.
+func Hash(key string) string {
+	return utils.HashToken(key)
+}
+
+var (
+	mu              sync.RWMutex
+	defaultResolver Resolver
+)
+
+// SetDefault sets the resolver consulted by middlewares.VerifyAPIKey.
+func SetDefault(r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultResolver = r
+}
+
+// Default returns the resolver set with SetDefault, or nil when API keys
+// are not enabled.
+func Default() Resolver {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultResolver
+}
@@ -0,0 +1,39 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, err := Generate()
+	require.NoError(t, err)
+	require.Equal(t, prefix, PrefixOf(key))
+	require.NotEqual(t, key, Hash(key))
+
+	other, otherPrefix, err := Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, key, other)
+	require.NotEqual(t, prefix, otherPrefix)
+}
+
+func TestPrefixOf(t *testing.T) {
+	key, prefix, err := Generate()
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		key    string
+		prefix string
+	}{
+		{key: key, prefix: prefix},
+		{key: "", prefix: ""},
+		{key: "secret", prefix: ""},
+		{key: strings.Replace(key, "_", "-", 1), prefix: ""},
+		{key: strings.SplitN(key, "_", 3)[0] + "_" + prefix + "_", prefix: ""},
+		{key: strings.SplitN(key, "_", 3)[0] + "_abc_secret", prefix: ""},
+	} {
+		require.Equal(t, tt.prefix, PrefixOf(tt.key), tt.key)
+	}
+}
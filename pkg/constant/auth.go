@@ -0,0 +1,49 @@
+package constant
+
+import "time"
+
+const (
+	AccessTokenTTL         = 2 * time.Hour
+	DefaultRefreshTokenTTL = 12 * time.Hour
+	TokenType              = "Bearer"
+
+	DefaultLoginMaxAttempts   = 5
+	DefaultLoginIPMaxAttempts = 20
+	DefaultLoginLockout       = 15 * time.Minute
+	LoginBackoffBase          = time.Second
+	DefaultLoginBackoffMax    = time.Minute
+
+	ChallengeTokenTTL    = 5 * time.Minute
+	MaxChallengeAttempts = 5
+	RecoveryCodeCount    = 10
+	TOTPSkew             = 1
+	DefaultTOTPIssuer    = "Perpustakaan"
+
+	DefaultPasswordMinLength  = 8
+	DefaultPasswordMinClasses = 3
+	DefaultBcryptCost         = 10
+
+	APIKeyHeader         = "X-API-Key"
+	APIKeyScheme         = "plib"
+	APIKeyPrefixBytes    = 6
+	APIKeyUsernamePrefix = "apikey:"
+	APIKeyTouchInterval  = time.Minute
+
+	JWKSCacheControl = "public, max-age=300"
+
+	OIDCStateCookie          = "oidc_state"
+	OIDCStateTTL             = 10 * time.Minute
+	OIDCClockSkew            = time.Minute
+	OIDCRequestTimeout       = 10 * time.Second
+	OIDCKeyRefetchInterval   = time.Minute
+	DefaultOIDCUsernameClaim = "preferred_username"
+	DefaultOIDCGroupsClaim   = "groups"
+)
+
+// DefaultOIDCScopes are requested from the OpenID Connect provider unless
+// OIDC_SCOPES says otherwise.
+var DefaultOIDCScopes = []string{"openid", "profile", "email"}
+
+// DefaultTwoFactorRoles must sign in with a second factor unless
+// TWO_FACTOR_ROLES says otherwise.
+var DefaultTwoFactorRoles = []string{Admin, SuperAdmin}
@@ -4,5 +4,6 @@ const (
 	Institute          = "FAKHRIL"
 	AvailableStatus    = "AVAILABLE"
 	NotAvailableStatus = "NOT AVAILABLE"
+	OnHoldStatus       = "ON HOLD"
 	Loan               = "LOAN"
 )
@@ -0,0 +1,11 @@
+package constant
+
+const (
+	MySQLDriver    = "mysql"
+	PostgresDriver = "postgres"
+	SQLiteDriver   = "sqlite"
+)
+
+// DefaultSQLiteDSN keeps the database next to the binary. Writers wait for
+// each other instead of failing with "database is locked".
+const DefaultSQLiteDSN = "file:perpustakaan.db?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate"
@@ -0,0 +1,9 @@
+package constant
+
+const (
+	FineAccruingStatus = "ACCRUING"
+	FineUnpaidStatus   = "UNPAID"
+	FinePaidStatus     = "PAID"
+	FineWaivedStatus   = "WAIVED"
+	DefaultFinePerDay  = 1000
+)
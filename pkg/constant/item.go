@@ -0,0 +1,12 @@
+package constant
+
+const (
+	ItemAvailableStatus = "AVAILABLE"
+	ItemOnLoanStatus    = "ON LOAN"
+	ItemOnHoldStatus    = "ON HOLD"
+	ItemLostStatus      = "LOST"
+	ItemRepairStatus    = "IN REPAIR"
+	ItemGoodCondition   = "GOOD"
+	ItemFairCondition   = "FAIR"
+	ItemPoorCondition   = "POOR"
+)
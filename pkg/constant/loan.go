@@ -5,4 +5,14 @@ const (
 	LoanEndDate        = "End Date"
 	LoanBorrowedStatus = "BORROWED"
 	LoanReturnedStatus = "RETURNED"
+	LoanDateLayout     = "2006-01-02"
+	DefaultRenewalDays = 7
+	DefaultMaxRenewals = 2
+)
+
+const (
+	BookNotAvailableReason = "BOOK_NOT_AVAILABLE"
+	LoanLimitReason        = "LOAN_LIMIT_REACHED"
+	UnpaidFinesReason      = "UNPAID_FINES"
+	OverdueLoansReason     = "OVERDUE_LOANS"
 )
@@ -0,0 +1,43 @@
+package constant
+
+const (
+	BookWrite         = "book:write"
+	BookDelete        = "book:delete"
+	ItemWrite         = "item:write"
+	ItemDelete        = "item:delete"
+	LoanRead          = "loan:read"
+	LoanWrite         = "loan:write"
+	LoanDelete        = "loan:delete"
+	LoanManage        = "loan:manage"
+	ReservationRead   = "reservation:read"
+	ReservationWrite  = "reservation:write"
+	ReservationManage = "reservation:manage"
+	FineRead          = "fine:read"
+	FineManage        = "fine:manage"
+	UserManage        = "user:manage"
+	APIKeyManage      = "apikey:manage"
+)
+
+// Permissions lists every permission, e.g. to check the scopes of an API
+// key.
+var Permissions = []string{
+	BookWrite, BookDelete,
+	ItemWrite, ItemDelete,
+	LoanRead, LoanWrite, LoanDelete, LoanManage,
+	ReservationRead, ReservationWrite, ReservationManage,
+	FineRead, FineManage,
+	UserManage,
+	APIKeyManage,
+}
+
+// AllPermissions grants every permission. A permission ending in ":*"
+// grants every permission on that resource, e.g. "book:*".
+const AllPermissions = "*"
+
+// RolePermissions is the default permissions of each role. Managing a
+// resource allows acting on records of other users.
+var RolePermissions = map[string][]string{
+	Karyawan:   {LoanRead, LoanWrite, ReservationRead, ReservationWrite},
+	Admin:      {"book:*", "item:*", "loan:*", "reservation:*", FineRead, FineManage},
+	SuperAdmin: {AllPermissions},
+}
@@ -0,0 +1,10 @@
+package constant
+
+const (
+	ReservationWaitingStatus   = "WAITING"
+	ReservationReadyStatus     = "READY"
+	ReservationFulfilledStatus = "FULFILLED"
+	ReservationCancelledStatus = "CANCELLED"
+	ReservationExpiredStatus   = "EXPIRED"
+	DefaultHoldPickupDays      = 3
+)
@@ -5,3 +5,15 @@ const (
 	Admin      = "ADMIN"
 	SuperAdmin = "SUPER ADMIN"
 )
+
+// Roles lists the roles from the most to the least privileged.
+var Roles = []string{SuperAdmin, Admin, Karyawan}
+
+const DefaultLoanLimit = 3
+
+// LoanLimits is the number of active loans each role may hold at once.
+var LoanLimits = map[string]int{
+	Karyawan:   DefaultLoanLimit,
+	Admin:      5,
+	SuperAdmin: 10,
+}
@@ -0,0 +1,14 @@
+package constant
+
+const (
+	DefaultBookIDFormat  = "{INSTITUTE}-{GENRE}-{SEQ}"
+	DefaultLoanIDFormat  = "LOAN-{USERNAME}-{SEQ}"
+	DefaultSequenceWidth = 4
+)
+
+// Fields that ID formats may refer to, e.g. {GENRE}.
+const (
+	InstituteField = "INSTITUTE"
+	GenreField     = "GENRE"
+	UsernameField  = "USERNAME"
+)
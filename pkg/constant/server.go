@@ -0,0 +1,40 @@
+package constant
+
+import "time"
+
+const (
+	DefaultHTTPReadTimeout       = 10 * time.Second
+	DefaultHTTPReadHeaderTimeout = 5 * time.Second
+	DefaultHTTPWriteTimeout      = 30 * time.Second
+	DefaultHTTPIdleTimeout       = 60 * time.Second
+	DefaultShutdownTimeout       = 30 * time.Second
+	DefaultShutdownDrain         = 5 * time.Second
+)
+
+const (
+	DefaultHealthCheckTimeout = 2 * time.Second
+	DefaultHealthMinFreeMB    = 100
+	DefaultLogDir             = "."
+)
+
+// MetricsQueryTimeout bounds the queries collectors run on a scrape.
+const MetricsQueryTimeout = 5 * time.Second
+
+const (
+	DefaultLogLevel  = "info"
+	DefaultLogFormat = "json"
+	SQLSlowThreshold = 5 * time.Second
+)
+
+// Request IDs of clients longer than MaxRequestIDLength are replaced with
+// one of RequestIDBytes random bytes.
+const (
+	MaxRequestIDLength = 128
+	RequestIDBytes     = 16
+)
+
+const (
+	DefaultServiceName      = "perpustakaan"
+	DefaultTraceSampleRatio = 1.0
+	TraceFlushTimeout       = 5 * time.Second
+)
@@ -1,14 +1,19 @@
 package databases
 
 import (
-	"log"
-	"os"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 type (
@@ -17,7 +22,8 @@ type (
 	}
 
 	Database struct {
-		Name string
+		Name   string
+		Driver string
 	}
 
 	DBInterface interface {
@@ -36,25 +42,27 @@ func (db *Database) Connect(dbname string) *DatabaseConnection {
 	master := db.Name
 
 	if master != "" {
-		newLogger := logger.New(
-			log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
-			logger.Config{
-				SlowThreshold:             time.Second * 5, // Slow SQL threshold
-				LogLevel:                  logger.Info,     // Log level
-				IgnoreRecordNotFoundError: true,            // Ignore ErrRecordNotFound error for logger
-				ParameterizedQueries:      true,            // Don't include params in the SQL log
-				Colorful:                  true,            // Disable color
-			},
-		)
-
-		db, err := gorm.Open(mysql.Open(master), &gorm.Config{Logger: newLogger})
+		dialector, err := Dialector(db.Driver, master)
 		if err != nil {
-			log.Fatal("mysql ", "can not connect MySQL", "connect", err)
+			logger.Default().Fatal("Can not connect to the database", zap.String("driver", db.Driver), zap.Error(err))
+		}
+
+		db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.NewGorm(constant.SQLSlowThreshold), TranslateError: true})
+		if err != nil {
+			logger.Default().Fatal("Can not connect to the database", zap.String("driver", dialector.Name()), zap.Error(err))
 		}
 
 		sqlDB, err := db.DB()
 		if err != nil {
-			log.Fatal("mysql ", "can not connect MySQL", "connect", err)
+			logger.Default().Fatal("Can not connect to the database", zap.String("driver", dialector.Name()), zap.Error(err))
+		}
+
+		if err = metrics.RegisterDB(dialector.Name(), db); err != nil {
+			logger.Default().Fatal("Can not export the database metrics", zap.String("driver", dialector.Name()), zap.Error(err))
+		}
+
+		if err = tracing.RegisterDB(db); err != nil {
+			logger.Default().Fatal("Can not trace the database queries", zap.String("driver", dialector.Name()), zap.Error(err))
 		}
 
 		sqlDB.SetConnMaxIdleTime(5)
@@ -69,14 +77,30 @@ func (db *Database) Connect(dbname string) *DatabaseConnection {
 	return DBConnect
 }
 
-func InitConnection(dns string, dbname string) DBInterface {
+// Dialector returns the gorm dialector of driver, which is one of
+// constant.MySQLDriver, constant.PostgresDriver or constant.SQLiteDriver.
+func Dialector(driver string, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case constant.MySQLDriver:
+		return mysql.Open(dsn), nil
+	case constant.PostgresDriver:
+		return postgres.Open(dsn), nil
+	case constant.SQLiteDriver:
+		return sqlite.Open(dsn), nil
+	}
+
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+func InitConnection(driver string, dns string) DBInterface {
 	if access != nil {
 		return access
 	}
 
 	accessOnce.Do(func() {
 		dbClient := NewDatabaseGorm(dns)
-		dbClient.Connect(dbname)
+		dbClient.Driver = driver
+		dbClient.Connect(dns)
 		access = dbClient
 	})
 
@@ -90,3 +114,19 @@ func NewDatabaseGorm(config interface{}) *Database {
 		Name: cfg,
 	}
 }
+
+// Close closes the connection pool, once requests and schedulers using it
+// have finished.
+func (d *DatabaseConnection) Close() error {
+	if d == nil || d.Connection == nil {
+		return nil
+	}
+
+	sqlDB, err := d.Connection.DB()
+
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
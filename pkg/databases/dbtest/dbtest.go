@@ -0,0 +1,39 @@
+// Package dbtest opens databases for tests.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/migration"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenSQLite returns a file backed SQLite database with the schema of the
+// embedded migrations. It is removed when the test ends.
+func OpenSQLite(t testing.TB) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_pragma=busy_timeout(5000)", filepath.Join(t.TempDir(), "test.db"))
+
+	dialector, err := databases.Dialector(constant.SQLiteDriver, dsn)
+	require.NoError(t, err)
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	files, err := migration.Files(constant.SQLiteDriver)
+	require.NoError(t, err)
+
+	migrator, err := migration.New(db, files)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	return db
+}
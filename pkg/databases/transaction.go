@@ -0,0 +1,52 @@
+package databases
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type txKey struct{}
+
+// UnitOfWork runs several repository calls in one database transaction.
+// Repositories join the transaction by resolving their connection with Conn.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+// Do runs fn in a transaction that is committed when fn returns nil and
+// rolled back otherwise. Calls nested in an outer Do join its transaction.
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+// Conn returns the transaction started by UnitOfWork.Do for ctx, or db when
+// ctx is not part of a transaction.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
+
+// ForUpdate locks the selected rows until the surrounding transaction ends.
+// Drivers without row locks, such as SQLite, ignore it.
+func ForUpdate(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: "UPDATE"})
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/migration"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"gorm.io/gorm"
+)
+
+// Database pings the connection pool of db.
+func Database(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+
+		if err != nil {
+			return err
+		}
+
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// Migrations fails while the database misses migrations this build
+// knows, e.g. when a deploy ran before `migrate up`.
+func Migrations(migrator *migration.Migrator) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migrations are pending, starting at %d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+
+		return nil
+	})
+}
+
+// SigningKey fails while ring has no key to sign access tokens with, as
+// nobody could sign in.
+func SigningKey(ring *jwtrsa.Ring) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		_, err := ring.Signing()
+		return err
+	})
+}
+
+// DiskSpace fails when the file system of dir has less than minFree bytes
+// available, e.g. for the logs written there.
+func DiskSpace(dir string, minFree uint64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		free, err := freeBytes(dir)
+
+		if err != nil {
+			return err
+		}
+
+		if free < minFree {
+			return fmt.Errorf("%s has %d MB free, less than %d MB", dir, free>>20, minFree>>20)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+var ready atomic.Bool
+
+// SetReady marks the service as ready to take traffic or not. It is not
+// ready until the server listens, and stops being ready as soon as it
+// starts shutting down.
+func SetReady(value bool) {
+	ready.Store(value)
+}
+
+// Ready reports whether the service takes traffic.
+func Ready() bool {
+	return ready.Load()
+}
+
+// Checker tells whether something the service depends on works.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is a function used as a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report is the outcome of every check, in the order they were
+// registered. It is up when every check is.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Up reports whether every check passed.
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Health runs the registered checks. Each check gets timeout to finish and
+// fails when it takes longer.
+type Health struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []namedChecker
+}
+
+// New returns a Health without checks.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Register adds a check, or replaces the check with the same name.
+func (h *Health) Register(name string, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := range h.checkers {
+		if h.checkers[i].name == name {
+			h.checkers[i].checker = checker
+			return
+		}
+	}
+
+	h.checkers = append(h.checkers, namedChecker{name: name, checker: checker})
+}
+
+// Run runs every check at the same time and reports how they went.
+func (h *Health) Run(ctx context.Context) Report {
+	h.mu.RLock()
+	checkers := append([]namedChecker(nil), h.checkers...)
+	h.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Result, len(checkers))}
+
+	var wg sync.WaitGroup
+
+	for i, named := range checkers {
+		wg.Add(1)
+
+		go func(i int, named namedChecker) {
+			defer wg.Done()
+
+			report.Checks[i] = h.run(ctx, named)
+		}(i, named)
+	}
+
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+// run runs one check. A check that ignores its context is given up on
+// once the timeout passed; it finishes in the background.
+func (h *Health) run(ctx context.Context, named namedChecker) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+
+		done <- named.checker.Check(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Name: named.name, Status: StatusUp, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	h := New(50 * time.Millisecond)
+
+	h.Register("up", CheckerFunc(func(ctx context.Context) error { return nil }))
+	h.Register("down", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+	h.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+	h.Register("panics", CheckerFunc(func(ctx context.Context) error { panic("boom") }))
+
+	start := time.Now()
+	report := h.Run(context.Background())
+
+	require.Less(t, time.Since(start), time.Second)
+	require.False(t, report.Up())
+	require.Len(t, report.Checks, 4)
+
+	for i, name := range []string{"up", "down", "slow", "panics"} {
+		require.Equal(t, name, report.Checks[i].Name)
+	}
+
+	require.Equal(t, StatusUp, report.Checks[0].Status)
+	require.Empty(t, report.Checks[0].Error)
+	require.Equal(t, "connection refused", report.Checks[1].Error)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks[2].Error)
+	require.GreaterOrEqual(t, report.Checks[2].LatencyMS, float64(50))
+	require.Contains(t, report.Checks[3].Error, "boom")
+
+	// A check registered again replaces the one before
+	h = New(time.Second)
+	h.Register("database", CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+	h.Register("database", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report = h.Run(context.Background())
+	require.True(t, report.Up())
+	require.Len(t, report.Checks, 1)
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, DiskSpace(dir, 0).Check(context.Background()))
+	require.Error(t, DiskSpace(dir, math.MaxUint64).Check(context.Background()))
+	require.Error(t, DiskSpace(dir+"/missing", 0).Check(context.Background()))
+}
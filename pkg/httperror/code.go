@@ -0,0 +1,146 @@
+package httperror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrorCode tells clients which failure an error is. Codes are stable,
+// unlike messages.
+type ErrorCode string
+
+const (
+	CodeBadRequest          ErrorCode = "BAD_REQUEST"
+	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidBody         ErrorCode = "INVALID_BODY"
+	CodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	CodeForbidden           ErrorCode = "FORBIDDEN"
+	CodeNotFound            ErrorCode = "NOT_FOUND"
+	CodeConflict            ErrorCode = "CONFLICT"
+	CodeDuplicate           ErrorCode = "DUPLICATE"
+	CodeLocked              ErrorCode = "LOCKED"
+	CodeTooManyRequests     ErrorCode = "TOO_MANY_REQUESTS"
+	CodeInternal            ErrorCode = "INTERNAL_ERROR"
+	CodeInvalidLogin        ErrorCode = "INVALID_LOGIN"
+	CodeTokenRevoked        ErrorCode = "TOKEN_REVOKED"
+	CodeInvalidRefreshToken ErrorCode = "INVALID_REFRESH_TOKEN"
+	CodeRefreshTokenReused  ErrorCode = "REFRESH_TOKEN_REUSED"
+	CodeInvalidPassword     ErrorCode = "INVALID_PASSWORD"
+	CodeSamePassword        ErrorCode = "SAME_PASSWORD"
+	CodeAccountLocked       ErrorCode = "ACCOUNT_LOCKED"
+	CodeTooManyAttempts     ErrorCode = "TOO_MANY_ATTEMPTS"
+	CodeInvalidChallenge    ErrorCode = "INVALID_CHALLENGE"
+	CodeInvalidTwoFactor    ErrorCode = "INVALID_TWO_FACTOR_CODE"
+	CodeTwoFactorEnabled    ErrorCode = "TWO_FACTOR_ENABLED"
+	CodeTwoFactorNotEnroll  ErrorCode = "TWO_FACTOR_NOT_ENROLLED"
+	CodeInvalidScope        ErrorCode = "INVALID_SCOPE"
+	CodeScopeNotGranted     ErrorCode = "SCOPE_NOT_GRANTED"
+	CodeInvalidAPIKey       ErrorCode = "INVALID_API_KEY"
+	CodeOIDCDisabled        ErrorCode = "OIDC_DISABLED"
+	CodeInvalidOIDCState    ErrorCode = "INVALID_OIDC_STATE"
+	CodeOIDCLoginFailed     ErrorCode = "OIDC_LOGIN_FAILED"
+	CodeOIDCNoRole          ErrorCode = "OIDC_NO_ROLE"
+	CodeOIDCAccountExists   ErrorCode = "OIDC_ACCOUNT_EXISTS"
+	CodeOIDCAlreadyLinked   ErrorCode = "OIDC_ALREADY_LINKED"
+)
+
+// messageCodes are the codes of errors made with one of the messages of
+// this package.
+var messageCodes = map[string]ErrorCode{
+	InvalidLoginMsg:          CodeInvalidLogin,
+	UnauthorizedErrorMessage: CodeUnauthorized,
+	ForbiddenErrorMessage:    CodeForbidden,
+	BindErrorMessage:         CodeInvalidBody,
+	NotFoundErrorMessage:     CodeNotFound,
+	RevokedTokenMsg:          CodeTokenRevoked,
+	InvalidRefreshTokenMsg:   CodeInvalidRefreshToken,
+	RefreshTokenReusedMsg:    CodeRefreshTokenReused,
+	InvalidPasswordMsg:       CodeInvalidPassword,
+	SamePasswordMsg:          CodeSamePassword,
+	AccountLockedMsg:         CodeAccountLocked,
+	TooManyAttemptsMsg:       CodeTooManyAttempts,
+	InvalidChallengeMsg:      CodeInvalidChallenge,
+	InvalidTwoFactorCodeMsg:  CodeInvalidTwoFactor,
+	TwoFactorEnabledMsg:      CodeTwoFactorEnabled,
+	TwoFactorNotEnrolledMsg:  CodeTwoFactorNotEnroll,
+	InvalidScopeMsg:          CodeInvalidScope,
+	ScopeNotGrantedMsg:       CodeScopeNotGranted,
+	InvalidAPIKeyMsg:         CodeInvalidAPIKey,
+	OIDCDisabledMsg:          CodeOIDCDisabled,
+	InvalidOIDCStateMsg:      CodeInvalidOIDCState,
+	OIDCLoginFailedMsg:       CodeOIDCLoginFailed,
+	OIDCNoRoleMsg:            CodeOIDCNoRole,
+	OIDCAccountExistsMsg:     CodeOIDCAccountExists,
+	OIDCAlreadyLinkedMsg:     CodeOIDCAlreadyLinked,
+	ValidationFailedMsg:      CodeValidationFailed,
+	DuplicateMsg:             CodeDuplicate,
+}
+
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusConflict:            CodeConflict,
+	http.StatusLocked:              CodeLocked,
+	http.StatusTooManyRequests:     CodeTooManyRequests,
+	http.StatusInternalServerError: CodeInternal,
+}
+
+// CodeOf returns the code of an error with message and HTTP status. A
+// message of this package, also when followed by ": <detail>", has its own
+// code; other errors get the code of their status.
+func CodeOf(message string, status int) ErrorCode {
+	known, _, _ := strings.Cut(message, ": ")
+
+	if code, ok := messageCodes[known]; ok {
+		return code
+	}
+
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+
+	return CodeBadRequest
+}
+
+// Detail is one field of a request that failed validation. Tag is the
+// validation rule and Param its parameter, e.g. "min" and "1".
+type Detail struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// FromError returns err as an error of this package. Database errors are
+// mapped to what they mean to the client: a missing record is not found
+// and a duplicate key is a conflict. Anything else is an internal server
+// error.
+func FromError(err error) error {
+	var httpErr interface {
+		error
+		Code() int
+	}
+
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFound(NotFoundErrorMessage)
+	}
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return Conflict(DuplicateMsg)
+	}
+
+	return InternalServerError(err.Error())
+}
@@ -0,0 +1,48 @@
+package httperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCodeOf(t *testing.T) {
+	require.Equal(t, CodeInvalidLogin, CodeOf(InvalidLoginMsg, http.StatusUnauthorized))
+	require.Equal(t, CodeInvalidScope, CodeOf(InvalidScopeMsg+": books:write", http.StatusBadRequest))
+	require.Equal(t, CodeConflict, CodeOf("book is not available", http.StatusConflict))
+	require.Equal(t, CodeInternal, CodeOf("bad gateway", http.StatusBadGateway))
+	require.Equal(t, CodeBadRequest, CodeOf("method not allowed", http.StatusMethodNotAllowed))
+
+	require.Equal(t, CodeValidationFailed, Validation(nil).(BadRequestData).ErrorCode())
+	require.Equal(t, CodeNotFound, NotFound("book not found").(NotFoundData).ErrorCode())
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   ErrorCode
+	}{
+		{name: "not found", err: gorm.ErrRecordNotFound, status: http.StatusNotFound, code: CodeNotFound},
+		{name: "duplicate", err: fmt.Errorf("insert: %w", gorm.ErrDuplicatedKey), status: http.StatusConflict, code: CodeDuplicate},
+		{name: "wrapped", err: fmt.Errorf("commit: %w", Forbidden(ForbiddenErrorMessage)), status: http.StatusForbidden, code: CodeForbidden},
+		{name: "other", err: errors.New("connection refused"), status: http.StatusInternalServerError, code: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		err := FromError(tt.err)
+		coded, ok := err.(interface {
+			Code() int
+			ErrorCode() ErrorCode
+		})
+
+		require.True(t, ok, tt.name)
+		require.Equal(t, tt.status, coded.Code(), tt.name)
+		require.Equal(t, tt.code, coded.ErrorCode(), tt.name)
+	}
+}
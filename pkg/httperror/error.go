@@ -1,11 +1,24 @@
 package httperror
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type CommonErrorData struct {
-	Code         int    `json:"code"`
-	ResponseCode int    `json:"responseCode,omitempty"`
-	Message      string `json:"message"`
+	Code         int           `json:"code"`
+	ResponseCode int           `json:"responseCode,omitempty"`
+	ErrorCode    ErrorCode     `json:"error_code"`
+	Message      string        `json:"message"`
+	Reasons      []Reason      `json:"reasons,omitempty"`
+	Details      []Detail      `json:"details,omitempty"`
+	RetryAfter   time.Duration `json:"-"`
+}
+
+// Reason is one machine readable cause of an error.
+type Reason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type ErrorString struct {
@@ -16,6 +29,7 @@ type ErrorString struct {
 type (
 	BadRequestData struct {
 		ErrorString
+		details []Detail
 	}
 
 	UnauthorizedData struct {
@@ -32,6 +46,17 @@ type (
 
 	ConflictData struct {
 		ErrorString
+		reasons []Reason
+	}
+
+	LockedData struct {
+		ErrorString
+		retryAfter time.Duration
+	}
+
+	TooManyRequestsData struct {
+		ErrorString
+		retryAfter time.Duration
 	}
 
 	InternalServerErrorData struct {
@@ -51,6 +76,30 @@ func (e ErrorString) Message() string {
 	return e.message
 }
 
+// ErrorCode is the code of the message of e, or of its status.
+func (e ErrorString) ErrorCode() ErrorCode {
+	return CodeOf(e.message, e.code)
+}
+
+// Details lists the fields that failed validation.
+func (e BadRequestData) Details() []Detail {
+	return e.details
+}
+
+func (e ConflictData) Reasons() []Reason {
+	return e.reasons
+}
+
+// RetryAfter is how long until the account is unlocked.
+func (e LockedData) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// RetryAfter is how long the client has to wait before trying again.
+func (e TooManyRequestsData) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
 func NewBadRequest(msg string) BadRequestData {
 	err := BadRequestData{}
 	if msg != "" {
@@ -120,6 +169,34 @@ func NewConflict(msg string) ConflictData {
 	return err
 }
 
+func NewLocked(msg string) LockedData {
+	err := LockedData{}
+
+	if msg != "" {
+		err.message = msg
+	} else {
+		err.message = "Locked"
+	}
+
+	err.code = http.StatusLocked
+
+	return err
+}
+
+func NewTooManyRequests(msg string) TooManyRequestsData {
+	err := TooManyRequestsData{}
+
+	if msg != "" {
+		err.message = msg
+	} else {
+		err.message = "Too Many Requests"
+	}
+
+	err.code = http.StatusTooManyRequests
+
+	return err
+}
+
 func NewInternalServerError(msg string) InternalServerErrorData {
 	err := InternalServerErrorData{}
 
@@ -154,6 +231,42 @@ func Conflict(msg string) error {
 	return NewConflict(msg)
 }
 
+// Validation returns a bad request error listing every field that failed
+// validation.
+func Validation(details []Detail) error {
+	err := NewBadRequest(ValidationFailedMsg)
+	err.details = details
+
+	return err
+}
+
+// ConflictWithReasons returns a conflict error listing every reason the
+// request was refused.
+func ConflictWithReasons(msg string, reasons []Reason) error {
+	err := NewConflict(msg)
+	err.reasons = reasons
+
+	return err
+}
+
+// Locked returns a locked error for a resource that unlocks after
+// retryAfter.
+func Locked(msg string, retryAfter time.Duration) error {
+	err := NewLocked(msg)
+	err.retryAfter = retryAfter
+
+	return err
+}
+
+// TooManyRequests returns a too many requests error for a client that may
+// try again after retryAfter.
+func TooManyRequests(msg string, retryAfter time.Duration) error {
+	err := NewTooManyRequests(msg)
+	err.retryAfter = retryAfter
+
+	return err
+}
+
 func InternalServerError(msg string) error {
 	return NewInternalServerError(msg)
 }
@@ -3,6 +3,29 @@ package httperror
 const (
 	InvalidLoginMsg          = "username or password is incorrect"
 	UnauthorizedErrorMessage = "you are not authorized to access this endpoint"
+	ForbiddenErrorMessage    = "your role does not have permission to access this endpoint"
 	BindErrorMessage         = "error binding request body"
+	ValidationFailedMsg      = "request validation failed"
+	DuplicateMsg             = "resource already exists"
 	NotFoundErrorMessage     = "resource not found"
+	RevokedTokenMsg          = "token has been revoked"
+	InvalidRefreshTokenMsg   = "refresh token is invalid or expired"
+	RefreshTokenReusedMsg    = "refresh token was already used, every session of this sign-in has been revoked"
+	InvalidPasswordMsg       = "current password is incorrect"
+	SamePasswordMsg          = "new password must be different from the current password"
+	AccountLockedMsg         = "account is locked after too many failed sign-ins"
+	TooManyAttemptsMsg       = "too many failed sign-ins, try again later"
+	InvalidChallengeMsg      = "sign-in challenge is invalid or expired"
+	InvalidTwoFactorCodeMsg  = "two-factor code is incorrect"
+	TwoFactorEnabledMsg      = "two-factor authentication is already enabled"
+	TwoFactorNotEnrolledMsg  = "two-factor authentication has not been enrolled"
+	InvalidScopeMsg          = "scope is not a known permission"
+	ScopeNotGrantedMsg       = "you can not grant a scope you do not have"
+	InvalidAPIKeyMsg         = "api key is invalid, expired or revoked"
+	OIDCDisabledMsg          = "sign-in with the identity provider is not enabled"
+	InvalidOIDCStateMsg      = "sign-in state is invalid or expired, start signing in again"
+	OIDCLoginFailedMsg       = "the identity provider could not sign you in"
+	OIDCNoRoleMsg            = "your groups at the identity provider do not grant access"
+	OIDCAccountExistsMsg     = "an account with your username exists, ask an administrator to link it to the identity provider"
+	OIDCAlreadyLinkedMsg     = "the account at the identity provider is already linked to a user"
 )
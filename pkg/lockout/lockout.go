@@ -0,0 +1,201 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy limits failed sign-ins. Every failure doubles the wait before the
+// next attempt, from BaseDelay up to MaxDelay. MaxAttempts failures lock the
+// username and MaxIPAttempts failures throttle the client IP, both for
+// Lockout. Failures are forgotten after Lockout without a new one.
+type Policy struct {
+	MaxAttempts   int
+	MaxIPAttempts int
+	Lockout       time.Duration
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+}
+
+// Decision tells whether a sign-in may be attempted. Locked means the
+// username is locked; otherwise a positive RetryAfter means the caller has
+// to slow down.
+type Decision struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+// Allowed reports whether the sign-in may go ahead.
+func (d Decision) Allowed() bool {
+	return !d.Locked && d.RetryAfter <= 0
+}
+
+// Tracker counts failed sign-ins per username and per client IP. An empty
+// IP is not tracked.
+type Tracker interface {
+	Check(username, ip string, now time.Time) Decision
+	Fail(username, ip string, now time.Time) Decision
+	Succeed(username string)
+	Unlock(username string) bool
+	Purge(now time.Time) int
+}
+
+type attempts struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+type memoryTracker struct {
+	mu     sync.Mutex
+	policy Policy
+	users  map[string]*attempts
+	ips    map[string]*attempts
+}
+
+// Check implements Tracker.
+func (t *memoryTracker) Check(username, ip string, now time.Time) Decision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.check(username, ip, now)
+}
+
+// Fail implements Tracker. The returned decision applies to the next
+// attempt, so it is locked when this failure locked the username.
+func (t *memoryTracker) Fail(username, ip string, now time.Time) Decision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.fail(t.users, username, t.policy.MaxAttempts, now)
+
+	if ip != "" {
+		t.fail(t.ips, ip, t.policy.MaxIPAttempts, now)
+	}
+
+	return t.check(username, ip, now)
+}
+
+// Succeed implements Tracker. The failures of the client IP are kept, so
+// one valid account does not lift the throttle on guessing others.
+func (t *memoryTracker) Succeed(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.users, username)
+}
+
+// Unlock implements Tracker. It forgets the failures of username and
+// reports whether there were any.
+func (t *memoryTracker) Unlock(username string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.users[username]
+	delete(t.users, username)
+
+	return ok
+}
+
+// Purge implements Tracker. It drops the records that would be forgotten
+// on the next attempt and returns how many it dropped.
+func (t *memoryTracker) Purge(now time.Time) (purged int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, records := range []map[string]*attempts{t.users, t.ips} {
+		for key, record := range records {
+			if t.stale(record, now) {
+				delete(records, key)
+				purged++
+			}
+		}
+	}
+
+	return
+}
+
+func (t *memoryTracker) check(username, ip string, now time.Time) (decision Decision) {
+	if record := t.users[username]; record != nil {
+		if now.Before(record.lockedUntil) {
+			return Decision{Locked: true, RetryAfter: record.lockedUntil.Sub(now)}
+		}
+
+		decision.RetryAfter = t.wait(record, now)
+	}
+
+	if record := t.ips[ip]; ip != "" && record != nil {
+		if wait := t.wait(record, now); wait > decision.RetryAfter {
+			decision.RetryAfter = wait
+		}
+	}
+
+	return
+}
+
+func (t *memoryTracker) fail(records map[string]*attempts, key string, limit int, now time.Time) {
+	record := records[key]
+
+	if record == nil || t.stale(record, now) {
+		record = &attempts{}
+		records[key] = record
+	}
+
+	record.failures++
+	record.last = now
+
+	if limit > 0 && record.failures >= limit {
+		record.lockedUntil = now.Add(t.policy.Lockout)
+	}
+}
+
+// wait is how long the next attempt has to wait for the backoff of record,
+// or for its lockout to end.
+func (t *memoryTracker) wait(record *attempts, now time.Time) time.Duration {
+	if now.Before(record.lockedUntil) {
+		return record.lockedUntil.Sub(now)
+	}
+
+	wait := record.last.Add(t.delay(record.failures)).Sub(now)
+
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+// delay is the backoff after failures failed attempts in a row.
+func (t *memoryTracker) delay(failures int) time.Duration {
+	if failures <= 0 || t.policy.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := t.policy.BaseDelay
+
+	for i := 1; i < failures && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if t.policy.MaxDelay > 0 && delay > t.policy.MaxDelay {
+		delay = t.policy.MaxDelay
+	}
+
+	return delay
+}
+
+// stale reports whether record is no longer locked and its last failure is
+// more than a lockout ago.
+func (t *memoryTracker) stale(record *attempts, now time.Time) bool {
+	return !now.Before(record.lockedUntil) && now.Sub(record.last) >= t.policy.Lockout
+}
+
+// New returns a Tracker kept in memory, so failures are counted per
+// process and forgotten on restart.
+func New(policy Policy) Tracker {
+	return &memoryTracker{
+		policy: policy,
+		users:  make(map[string]*attempts),
+		ips:    make(map[string]*attempts),
+	}
+}
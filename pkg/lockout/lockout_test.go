@@ -0,0 +1,78 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var policy = Policy{
+	MaxAttempts:   3,
+	MaxIPAttempts: 5,
+	Lockout:       15 * time.Minute,
+	BaseDelay:     time.Second,
+	MaxDelay:      4 * time.Second,
+}
+
+func TestBackoffAndLockout(t *testing.T) {
+	tracker := New(policy)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.True(t, tracker.Check("test", "192.0.2.1", now).Allowed())
+
+	decision := tracker.Fail("test", "192.0.2.1", now)
+	require.False(t, decision.Locked)
+	require.Equal(t, time.Second, decision.RetryAfter)
+	require.True(t, tracker.Check("test", "192.0.2.1", now.Add(time.Second)).Allowed())
+
+	now = now.Add(time.Second)
+	decision = tracker.Fail("test", "192.0.2.1", now)
+	require.Equal(t, 2*time.Second, decision.RetryAfter)
+	require.Equal(t, time.Second, tracker.Check("test", "192.0.2.1", now.Add(time.Second)).RetryAfter)
+
+	now = now.Add(2 * time.Second)
+	decision = tracker.Fail("test", "192.0.2.1", now)
+	require.True(t, decision.Locked)
+	require.Equal(t, policy.Lockout, decision.RetryAfter)
+	require.True(t, tracker.Check("test", "", now.Add(time.Minute)).Locked)
+
+	require.True(t, tracker.Check("other", "", now).Allowed())
+	require.True(t, tracker.Check("test", "", now.Add(policy.Lockout)).Allowed())
+
+	tracker.Fail("test", "", now.Add(policy.Lockout))
+	require.False(t, tracker.Check("test", "", now.Add(policy.Lockout+time.Second)).Locked)
+}
+
+func TestThrottleByIP(t *testing.T) {
+	tracker := New(policy)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < policy.MaxIPAttempts; i++ {
+		tracker.Fail(string(rune('a'+i)), "192.0.2.1", now)
+		tracker.Succeed(string(rune('a' + i)))
+	}
+
+	decision := tracker.Check("fresh", "192.0.2.1", now.Add(time.Minute))
+	require.False(t, decision.Locked)
+	require.Equal(t, policy.Lockout-time.Minute, decision.RetryAfter)
+	require.True(t, tracker.Check("fresh", "192.0.2.2", now.Add(time.Minute)).Allowed())
+}
+
+func TestUnlockAndPurge(t *testing.T) {
+	tracker := New(policy)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < policy.MaxAttempts; i++ {
+		tracker.Fail("test", "", now)
+	}
+
+	require.True(t, tracker.Check("test", "", now).Locked)
+	require.True(t, tracker.Unlock("test"))
+	require.True(t, tracker.Check("test", "", now).Allowed())
+	require.False(t, tracker.Unlock("test"))
+
+	tracker.Fail("test", "192.0.2.1", now)
+	require.Equal(t, 0, tracker.Purge(now.Add(time.Minute)))
+	require.Equal(t, 2, tracker.Purge(now.Add(policy.Lockout)))
+}
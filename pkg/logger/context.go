@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDKey is the field lines logged for a request name its ID with.
+const RequestIDKey = "request_id"
+
+type (
+	loggerKey    struct{}
+	requestIDKey struct{}
+)
+
+// NewContext returns a copy of ctx FromContext returns l for.
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of ctx, or Default when ctx has none.
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+
+	return Default()
+}
+
+// WithRequestID returns a copy of ctx carrying id, with a logger adding it
+// to every line.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	return NewContext(ctx, FromContext(ctx).With(zap.String(RequestIDKey, id)))
+}
+
+// RequestID returns the ID WithRequestID put in ctx, or "" when there is
+// none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Gorm logs the SQL gorm runs through the logger of the context of the
+// query, so the lines of a request carry its ID. Statements are logged at
+// debug level, those slower than SlowThreshold as warnings and failed ones
+// as errors. Parameters are left out, they may hold secrets.
+type Gorm struct {
+	SlowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+// NewGorm returns a gorm logger warning about statements slower than
+// slowThreshold.
+func NewGorm(slowThreshold time.Duration) gormlogger.Interface {
+	return Gorm{SlowThreshold: slowThreshold, level: gormlogger.Info}
+}
+
+// LogMode implements gormlogger.Interface.
+func (g Gorm) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	g.level = level
+	return g
+}
+
+// Info implements gormlogger.Interface.
+func (g Gorm) Info(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.logger(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn implements gormlogger.Interface.
+func (g Gorm) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.logger(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error implements gormlogger.Interface.
+func (g Gorm) Error(ctx context.Context, msg string, data ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.logger(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace implements gormlogger.Interface.
+func (g Gorm) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	fields := func() []zap.Field {
+		sql, rows := fc()
+
+		return []zap.Field{zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("elapsed_ms", elapsed)}
+	}
+
+	switch {
+	case err != nil && g.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		g.logger(ctx).Error("SQL failed", append(fields(), zap.Error(err))...)
+	case g.SlowThreshold > 0 && elapsed > g.SlowThreshold && g.level >= gormlogger.Warn:
+		g.logger(ctx).Warn("Slow SQL", fields()...)
+	case g.level >= gormlogger.Info && FromContext(ctx).Core().Enabled(zap.DebugLevel):
+		g.logger(ctx).Debug("SQL", fields()...)
+	}
+}
+
+// ParamsFilter implements gorm's ParamsFilter, leaving the parameters out
+// of the logged SQL.
+func (g Gorm) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// logger names the line gorm and replaces the caller, somewhere in gorm,
+// with the code that ran the query.
+func (g Gorm) logger(ctx context.Context) *zap.Logger {
+	return FromContext(ctx).Named("gorm").WithOptions(zap.WithCaller(false)).With(zap.String("source", source()))
+}
+
+// source returns the file and line of the first caller outside gorm once
+// the stack went through gorm.
+func source() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	inGorm := false
+
+	for {
+		frame, more := frames.Next()
+
+		if strings.Contains(frame.File, "/gorm.io/") {
+			inGorm = true
+		} else if inGorm {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
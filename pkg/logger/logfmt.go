@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder writes the fields of a JSON line as key=value pairs, in
+// the same order. Objects and arrays stay JSON, quoted as one value.
+type logfmtEncoder struct {
+	zapcore.Encoder
+	lineEnding string
+}
+
+func newLogfmtEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	lineEnding := config.LineEnding
+
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+
+	return logfmtEncoder{Encoder: zapcore.NewJSONEncoder(config), lineEnding: lineEnding}
+}
+
+// Clone implements zapcore.Encoder.
+func (e logfmtEncoder) Clone() zapcore.Encoder {
+	return logfmtEncoder{Encoder: e.Encoder.Clone(), lineEnding: e.lineEnding}
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line, err := e.Encoder.EncodeEntry(entry, fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer line.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(line.Bytes()))
+
+	// The opening brace of the object
+	if _, err = decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	out := logfmtPool.Get()
+
+	for decoder.More() {
+		key, err := decoder.Token()
+
+		if err != nil {
+			out.Free()
+			return nil, err
+		}
+
+		var value json.RawMessage
+
+		if err = decoder.Decode(&value); err != nil {
+			out.Free()
+			return nil, err
+		}
+
+		if out.Len() > 0 {
+			out.AppendByte(' ')
+		}
+
+		appendLogfmt(out, key.(string))
+		out.AppendByte('=')
+		appendLogfmtValue(out, value)
+	}
+
+	out.AppendString(e.lineEnding)
+
+	return out, nil
+}
+
+func appendLogfmtValue(out *buffer.Buffer, value json.RawMessage) {
+	var text string
+
+	if len(value) > 0 && value[0] == '"' && json.Unmarshal(value, &text) == nil {
+		appendLogfmt(out, text)
+		return
+	}
+
+	appendLogfmt(out, string(value))
+}
+
+// appendLogfmt quotes text when it is empty or would not read back as one
+// value.
+func appendLogfmt(out *buffer.Buffer, text string) {
+	needsQuotes := text == "" || strings.IndexFunc(text, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r)
+	}) >= 0
+
+	if needsQuotes {
+		out.AppendString(strconv.Quote(text))
+		return
+	}
+
+	out.AppendString(text)
+}
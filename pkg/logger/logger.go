@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Formats a logger writes its lines in.
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+var ErrUnknownFormat = errors.New("error in pkg logger, the log format is unknown")
+
+// Config is how a logger writes. Level is one of debug, info, warn or
+// error and Format one of FormatJSON or FormatLogfmt. Empty values mean
+// info and FormatJSON.
+type Config struct {
+	Level  string
+	Format string
+}
+
+// New returns a logger writing lines of at least the level of config to
+// out.
+func New(config Config, out io.Writer) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(config.Level)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in pkg logger, %w", err)
+	}
+
+	var encoder zapcore.Encoder
+
+	switch strings.ToLower(config.Format) {
+	case FormatJSON, "":
+		encoder = zapcore.NewJSONEncoder(encoderConfig())
+	case FormatLogfmt:
+		encoder = newLogfmtEncoder(encoderConfig())
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, config.Format)
+	}
+
+	return newLogger(encoder, out, level), nil
+}
+
+func newLogger(encoder zapcore.Encoder, out io.Writer, level zapcore.Level) *zap.Logger {
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(out), level), zap.AddCaller(), zap.ErrorOutput(zapcore.Lock(os.Stderr)))
+}
+
+func encoderConfig() zapcore.EncoderConfig {
+	config := zap.NewProductionEncoderConfig()
+	config.TimeKey = "time"
+	config.MessageKey = "msg"
+	config.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	config.EncodeDuration = millis
+
+	return config
+}
+
+// millis writes durations as fractional milliseconds; fields holding one
+// end in _ms.
+func millis(d time.Duration, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendFloat64(float64(d) / float64(time.Millisecond))
+}
+
+var (
+	mu            sync.RWMutex
+	defaultLogger = newLogger(zapcore.NewJSONEncoder(encoderConfig()), os.Stdout, zapcore.InfoLevel)
+)
+
+// SetDefault sets the logger of requests and jobs without one of their
+// own.
+func SetDefault(l *zap.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultLogger = l
+}
+
+// Default returns the logger set with SetDefault, or one writing JSON
+// lines of level info to stdout.
+func Default() *zap.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultLogger
+}
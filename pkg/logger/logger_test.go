@@ -0,0 +1,180 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/glebarez/sqlite"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// lines decodes the JSON lines written to out.
+func lines(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	var decoded []map[string]interface{}
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &fields), line)
+		decoded = append(decoded, fields)
+	}
+
+	return decoded
+}
+
+// useLogger makes a logger of config writing to the returned buffer the
+// default one for the test.
+func useLogger(t *testing.T, config logger.Config) *bytes.Buffer {
+	var out bytes.Buffer
+
+	l, err := logger.New(config, &out)
+	require.NoError(t, err)
+
+	previous := logger.Default()
+	logger.SetDefault(l)
+	t.Cleanup(func() { logger.SetDefault(previous) })
+
+	return &out
+}
+
+func TestNew(t *testing.T) {
+	var out bytes.Buffer
+
+	l, err := logger.New(logger.Config{Level: "warn", Format: logger.FormatLogfmt}, &out)
+	require.NoError(t, err)
+
+	l.Info("Not written")
+	l.Warn("Shelf is full", zap.String("shelf", "drama 1"), zap.Int("books", 40), zap.Strings("genres", []string{"drama"}))
+
+	line := strings.TrimSpace(out.String())
+	require.NotContains(t, line, "Not written")
+	require.Contains(t, line, `level=warn`)
+	require.Contains(t, line, `msg="Shelf is full" shelf="drama 1" books=40 genres="[\"drama\"]"`)
+
+	_, err = logger.New(logger.Config{Format: "xml"}, &out)
+	require.ErrorIs(t, err, logger.ErrUnknownFormat)
+
+	_, err = logger.New(logger.Config{Level: "loud"}, &out)
+	require.Error(t, err)
+}
+
+func TestRequestID(t *testing.T) {
+	out := useLogger(t, logger.Config{Level: "debug"})
+
+	e := echo.New()
+	e.HTTPErrorHandler = utils.HTTPErrorHandler
+	e.Use(middlewares.RequestID(), middlewares.AuditLog())
+	e.GET("/book/:book-id", func(c echo.Context) error {
+		require.NotEmpty(t, logger.RequestID(c.Request().Context()))
+		logger.FromContext(c.Request().Context()).Info("Looking up the book")
+
+		return utils.ResponseError(httperror.NotFound(httperror.NotFoundErrorMessage), c)
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return errors.New("shelf collapsed")
+	})
+
+	// A usable ID of the client is kept
+	req := httptest.NewRequest(http.MethodGet, "/book/1", nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-id-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "client-id-1", rec.Header().Get(echo.HeaderXRequestID))
+
+	logged := lines(t, out)
+	require.Len(t, logged, 2)
+	require.Equal(t, "Looking up the book", logged[0]["msg"])
+	require.Equal(t, "client-id-1", logged[0][logger.RequestIDKey])
+
+	// The audit line has the status that was sent
+	require.Equal(t, "audit", logged[1]["logger"])
+	require.Equal(t, "client-id-1", logged[1][logger.RequestIDKey])
+	require.Equal(t, "/book/:book-id", logged[1]["route"])
+	require.EqualValues(t, http.StatusNotFound, logged[1]["status"])
+	require.Contains(t, logged[1], "latency_ms")
+
+	// Other IDs are replaced
+	out.Reset()
+	req = httptest.NewRequest(http.MethodGet, "/broken", nil)
+	req.Header.Set(echo.HeaderXRequestID, "bad id\n")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NotEmpty(t, id)
+	require.NotEqual(t, "bad id\n", id)
+
+	logged = lines(t, out)
+	require.Len(t, logged, 2)
+	require.Equal(t, "shelf collapsed", logged[0]["error"])
+	require.Equal(t, "error", logged[1]["level"])
+	require.EqualValues(t, http.StatusInternalServerError, logged[1]["status"])
+
+	for _, fields := range logged {
+		require.Equal(t, id, fields[logger.RequestIDKey])
+	}
+}
+
+func TestGorm(t *testing.T) {
+	out := useLogger(t, logger.Config{Level: "debug"})
+
+	dsn := fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "logger.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.NewGorm(time.Minute)})
+	require.NoError(t, err)
+
+	type Shelf struct {
+		ID   int64
+		Name string
+	}
+
+	require.NoError(t, db.AutoMigrate(&Shelf{}))
+	out.Reset()
+
+	ctx := logger.WithRequestID(context.Background(), "query-1")
+	require.NoError(t, db.WithContext(ctx).Create(&Shelf{Name: "secret"}).Error)
+	require.ErrorIs(t, db.WithContext(ctx).First(&Shelf{}, 42).Error, gorm.ErrRecordNotFound)
+	require.Error(t, db.WithContext(ctx).Table("missing").Create(map[string]interface{}{"name": "x"}).Error)
+
+	logged := lines(t, out)
+	require.Len(t, logged, 3)
+
+	for _, fields := range logged {
+		require.Equal(t, "gorm", fields["logger"])
+		require.Equal(t, "query-1", fields[logger.RequestIDKey])
+		require.Contains(t, fields["source"], "logger_test.go")
+		require.NotContains(t, fields["sql"], "secret")
+	}
+
+	require.Equal(t, "debug", logged[0]["level"])
+	require.Contains(t, logged[0]["sql"], "INSERT INTO `shelves`")
+	require.Equal(t, "debug", logged[1]["level"])
+	require.Equal(t, "error", logged[2]["level"])
+	require.Contains(t, logged[2], "error")
+
+	// Statements are not logged above debug level
+	out = useLogger(t, logger.Config{Level: "info"})
+	require.NoError(t, db.Create(&Shelf{Name: "drama"}).Error)
+	require.Empty(t, out.String())
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the name of every metric of the service.
+const Namespace = "perpustakaan"
+
+// Registry holds the metrics served at /metrics.
+var Registry = prometheus.NewRegistry()
+
+var registerMu sync.Mutex
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "How long HTTP requests took by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		queryDuration,
+	)
+}
+
+// Register adds collector to the Registry. A collector with the same
+// metrics that was registered before is replaced, so usecases can be
+// built more than once, e.g. in tests.
+func Register(collector prometheus.Collector) {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+
+	Registry.Unregister(collector)
+	Registry.MustRegister(collector)
+}
+
+// Handler serves the Registry in the Prometheus text format. A collector
+// that fails leaves out its metrics instead of failing the scrape.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// StartRequest counts a request as in flight until the returned function
+// records how it went. Route is the template the request matched, e.g.
+// /book/:book-id, so the number of series stays bounded.
+func StartRequest() func(method, route string, status int) {
+	start := time.Now()
+	httpInFlight.Inc()
+
+	return func(method, route string, status int) {
+		httpInFlight.Dec()
+
+		labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,80 @@
+package metrics_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/metrics"
+	"github.com/glebarez/sqlite"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scrape returns the lines of /metrics starting with prefix.
+func scrape(t *testing.T, prefix string) []string {
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var lines []string
+
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(middlewares.Metrics())
+	e.GET("/book/:book-id", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/book/1", "/book/2", "/unknown/path"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Contains(t, scrape(t, "perpustakaan_http_requests_total"), `perpustakaan_http_requests_total{method="GET",route="/book/:book-id",status="204"} 2`)
+	require.Contains(t, scrape(t, "perpustakaan_http_requests_total"), `perpustakaan_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	require.Contains(t, scrape(t, "perpustakaan_http_request_duration_seconds_count"), `perpustakaan_http_request_duration_seconds_count{method="GET",route="/book/:book-id",status="204"} 2`)
+	require.Contains(t, scrape(t, "perpustakaan_http_requests_in_flight"), `perpustakaan_http_requests_in_flight 0`)
+}
+
+func TestDatabaseMetrics(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "metrics.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	type Shelf struct {
+		ID   int64
+		Name string
+	}
+
+	require.NoError(t, db.AutoMigrate(&Shelf{}))
+	require.NoError(t, metrics.RegisterDB("test", db))
+
+	// Registering again replaces the pool stats instead of panicking
+	require.NoError(t, metrics.RegisterDB("test", db))
+
+	require.NoError(t, db.Create(&Shelf{Name: "drama"}).Error)
+	require.ErrorIs(t, db.First(&Shelf{}, 42).Error, gorm.ErrRecordNotFound)
+	require.Error(t, db.Table("missing").Create(map[string]interface{}{"name": "x"}).Error)
+
+	durations := scrape(t, "perpustakaan_db_query_duration_seconds_count")
+	require.Contains(t, durations, `perpustakaan_db_query_duration_seconds_count{operation="create",status="ok",table="shelves"} 1`)
+	require.Contains(t, durations, `perpustakaan_db_query_duration_seconds_count{operation="query",status="ok",table="shelves"} 1`)
+	require.Contains(t, durations, `perpustakaan_db_query_duration_seconds_count{operation="create",status="error",table="missing"} 1`)
+
+	require.NotEmpty(t, scrape(t, `go_sql_open_connections{db_name="test"}`))
+}
@@ -0,0 +1,418 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+var (
+	fileName    = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	createTable = regexp.MustCompile("(?is)CREATE TABLE IF NOT EXISTS\\s+([`\"]?(\\w+)[`\"]?)\\s*\\((.*)\\)\\s*$")
+	columnLine  = regexp.MustCompile("^\\s*([`\"](\\w+)[`\"]\\s+.+?),?\\s*$")
+	indexLine   = regexp.MustCompile("(?i)^\\s*(UNIQUE\\s+)?(?:KEY|INDEX)\\s+([`\"]?(\\w+)[`\"]?)\\s*(\\(.*\\))\\s*,?\\s*$")
+)
+
+// Migration is one versioned schema change read from a pair of
+// <version>_<name>.up.sql and <version>_<name>.down.sql files.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int64     `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"size:191"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status is a known migration and when it was applied, if it was.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied. It stops at the first migration that fails.
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	done, err := m.applied(ctx)
+
+	if err != nil {
+		return
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Up); err != nil {
+				return err
+			}
+
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	done, err := m.applied(ctx)
+
+	if err != nil {
+		return
+	}
+
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+
+		if _, ok := done[migration.Version]; !ok {
+			continue
+		}
+
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Down); err != nil {
+				return err
+			}
+
+			return tx.Delete(&SchemaMigration{Version: migration.Version}).Error
+		})
+
+		if err != nil {
+			return reverted, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return
+}
+
+// Redo reverts the last applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) (redone []Migration, err error) {
+	if redone, err = m.Down(ctx, 1); err != nil || len(redone) == 0 {
+		return
+	}
+
+	return m.Up(ctx)
+}
+
+// Status lists every known migration in version order.
+func (m *Migrator) Status(ctx context.Context) (result []Status, err error) {
+	done, err := m.applied(ctx)
+
+	if err != nil {
+		return
+	}
+
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+
+		if record, ok := done[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+
+		result = append(result, status)
+	}
+
+	return
+}
+
+// Pending lists the known migrations that are not applied yet. Unlike the
+// other methods it does not create the schema_migrations table, so it can
+// be called often, e.g. by a health check.
+func (m *Migrator) Pending(ctx context.Context) (pending []Migration, err error) {
+	var records []SchemaMigration
+
+	if err = m.db.WithContext(ctx).Find(&records).Error; err != nil {
+		return
+	}
+
+	done := make(map[int64]bool, len(records))
+
+	for _, record := range records {
+		done[record.Version] = true
+	}
+
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]SchemaMigration, error) {
+	db := m.db.WithContext(ctx)
+
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var records []SchemaMigration
+
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[int64]SchemaMigration, len(records))
+
+	for _, record := range records {
+		done[record.Version] = record
+	}
+
+	return done, nil
+}
+
+// Files returns the embedded migrations written for driver. Every driver
+// has the same versions in its own SQL dialect.
+func Files(driver string) (fs.FS, error) {
+	switch driver {
+	case constant.MySQLDriver, constant.PostgresDriver, constant.SQLiteDriver:
+		return fs.Sub(files, path.Join("sql", driver))
+	}
+
+	return nil, fmt.Errorf("no migrations for database driver %q", driver)
+}
+
+// New reads the migrations in fsys. Every migration needs both an up and a
+// down file.
+func New(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrator := &Migrator{db: db}
+
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrator.migrations = append(migrator.migrations, *migration)
+	}
+
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].Version < migrator.migrations[j].Version
+	})
+
+	return migrator, nil
+}
+
+// exec runs each statement of script on its own, since drivers such as
+// MySQL refuse several statements in one call by default.
+func exec(tx *gorm.DB, script string) error {
+	for _, statement := range strings.Split(script, ";") {
+		if isBlank(statement) {
+			continue
+		}
+
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+
+		if err := addMissingColumns(tx, statement); err != nil {
+			return err
+		}
+
+		if err := addMissingIndexes(tx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addMissingColumns brings a table that existed before a CREATE TABLE IF NOT
+// EXISTS statement, e.g. one made by hand before migrations were introduced,
+// up to the columns the statement declares. A missing column that can not
+// be added to existing rows, because it is a key or NOT NULL without a
+// default, fails the migration instead.
+func addMissingColumns(tx *gorm.DB, statement string) error {
+	match := createTable.FindStringSubmatch(statement)
+
+	if match == nil {
+		return nil
+	}
+
+	quoted, table := match[1], match[2]
+
+	columns, err := tx.Migrator().ColumnTypes(table)
+
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(columns))
+
+	for _, column := range columns {
+		existing[strings.ToLower(column.Name())] = true
+	}
+
+	var missing []string
+
+	for _, line := range strings.Split(match[3], "\n") {
+		column := columnLine.FindStringSubmatch(line)
+
+		if column == nil || existing[strings.ToLower(column[2])] {
+			continue
+		}
+
+		definition := strings.ToUpper(column[1])
+
+		if strings.Contains(definition, "PRIMARY KEY") || (strings.Contains(definition, "NOT NULL") && !strings.Contains(definition, "DEFAULT")) {
+			missing = append(missing, column[2])
+			continue
+		}
+
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoted, column[1])).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("table %s predates the migrations and lacks %s; add these columns by hand or rename the table so it is created afresh, then migrate again", table, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// addMissingIndexes adds the indexes a CREATE TABLE IF NOT EXISTS statement
+// declares inline, as the MySQL files do, to a table that existed before
+// it. Indexes of their own statement are made with CREATE INDEX IF NOT
+// EXISTS instead.
+func addMissingIndexes(tx *gorm.DB, statement string) error {
+	match := createTable.FindStringSubmatch(statement)
+
+	if match == nil {
+		return nil
+	}
+
+	for _, index := range inlineIndexes(match[1], match[3]) {
+		if tx.Migrator().HasIndex(match[2], index.name) {
+			continue
+		}
+
+		if err := tx.Exec(index.create).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type inlineIndex struct {
+	name   string
+	create string
+}
+
+// inlineIndexes returns the KEY and UNIQUE KEY lines of the body of a CREATE
+// TABLE statement on table as CREATE INDEX statements.
+func inlineIndexes(table, body string) (result []inlineIndex) {
+	for _, line := range strings.Split(body, "\n") {
+		index := indexLine.FindStringSubmatch(line)
+
+		if index == nil {
+			continue
+		}
+
+		kind := "INDEX"
+
+		if index[1] != "" {
+			kind = "UNIQUE INDEX"
+		}
+
+		result = append(result, inlineIndex{
+			name:   index[3],
+			create: fmt.Sprintf("CREATE %s %s ON %s %s", kind, index[2], table, index[4]),
+		})
+	}
+
+	return
+}
+
+func isBlank(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,340 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_book.up.sql":   {Data: []byte("-- books\nCREATE TABLE book (id INTEGER PRIMARY KEY, book_id TEXT);\nCREATE INDEX idx_book_book_id ON book (book_id);")},
+	"0001_create_book.down.sql": {Data: []byte("DROP TABLE book;")},
+	"0002_create_loan.up.sql":   {Data: []byte("CREATE TABLE loan_book (id INTEGER PRIMARY KEY, loan_id TEXT);")},
+	"0002_create_loan.down.sql": {Data: []byte("DROP TABLE loan_book;")},
+	"README.md":                 {Data: []byte("not a migration")},
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "migration.db"))
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	return db
+}
+
+func versions(migrations []Migration) (result []int64) {
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+
+	return
+}
+
+func TestUpDownRedoStatus(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	migrator, err := New(db, testMigrations)
+	require.NoError(t, err)
+
+	// Nothing was ever migrated
+	_, err = migrator.Pending(ctx)
+	require.Error(t, err)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	require.Nil(t, status[0].AppliedAt)
+
+	pending, err := migrator.Pending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, versions(pending))
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, versions(applied))
+
+	pending, err = migrator.Pending(ctx)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	require.True(t, db.Migrator().HasTable("book"))
+	require.True(t, db.Migrator().HasIndex("book", "idx_book_book_id"))
+	require.True(t, db.Migrator().HasTable("loan_book"))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	redone, err := migrator.Redo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2}, versions(redone))
+	require.True(t, db.Migrator().HasTable("loan_book"))
+
+	reverted, err := migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2}, versions(reverted))
+	require.False(t, db.Migrator().HasTable("loan_book"))
+
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, status[0].AppliedAt)
+	require.Nil(t, status[1].AppliedAt)
+
+	reverted, err = migrator.Down(ctx, 5)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, versions(reverted))
+	require.False(t, db.Migrator().HasTable("book"))
+}
+
+func TestUpStopsAtFailedMigration(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	migrator, err := New(db, fstest.MapFS{
+		"0001_create_book.up.sql":   {Data: []byte("CREATE TABLE book (id INTEGER PRIMARY KEY);")},
+		"0001_create_book.down.sql": {Data: []byte("DROP TABLE book;")},
+		"0002_broken.up.sql":        {Data: []byte("CREATE TABLE broken (;")},
+		"0002_broken.down.sql":      {Data: []byte("DROP TABLE broken;")},
+	})
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.Error(t, err)
+	require.Equal(t, []int64{1}, versions(applied))
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, status[0].AppliedAt)
+	require.Nil(t, status[1].AppliedAt)
+}
+
+func TestNewRequiresUpAndDown(t *testing.T) {
+	_, err := New(openDB(t), fstest.MapFS{
+		"0001_create_book.up.sql": {Data: []byte("CREATE TABLE book (id INTEGER PRIMARY KEY);")},
+	})
+	require.Error(t, err)
+}
+
+func TestDriverMigrationsMatch(t *testing.T) {
+	var expected []Migration
+
+	for _, driver := range []string{constant.MySQLDriver, constant.PostgresDriver, constant.SQLiteDriver} {
+		files, err := Files(driver)
+		require.NoError(t, err)
+
+		migrator, err := New(openDB(t), files)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrator.migrations, driver)
+
+		for i := range migrator.migrations {
+			migrator.migrations[i].Up, migrator.migrations[i].Down = "", ""
+		}
+
+		if expected == nil {
+			expected = migrator.migrations
+		}
+
+		require.Equal(t, expected, migrator.migrations, driver)
+	}
+
+	_, err := Files("oracle")
+	require.Error(t, err)
+}
+
+func TestUpAddsMissingColumnsToExistingTables(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	// loan_book as it was before migrations were introduced
+	require.NoError(t, db.Exec(`CREATE TABLE loan_book (id INTEGER PRIMARY KEY AUTOINCREMENT, loan_id TEXT, book_id TEXT, title TEXT, username TEXT, loan_start_date TEXT, loan_end_date TEXT, status TEXT)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO loan_book (loan_id, book_id, username, status) VALUES ('LOAN-0001', 'BOOK-0001', 'test', 'BORROWED')`).Error)
+
+	files, err := Files(constant.SQLiteDriver)
+	require.NoError(t, err)
+
+	migrator, err := New(db, files)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	require.True(t, db.Migrator().HasColumn("loan_book", "barcode"))
+	require.True(t, db.Migrator().HasColumn("loan_book", "renewal_count"))
+
+	var renewals int64
+	require.NoError(t, db.Raw(`SELECT renewal_count FROM loan_book WHERE loan_id = 'LOAN-0001'`).Scan(&renewals).Error)
+	require.Equal(t, int64(0), renewals)
+}
+
+func TestAddMissingIndexesOfInlineKeys(t *testing.T) {
+	db := openDB(t)
+
+	// item as it was before migrations were introduced, without its indexes
+	require.NoError(t, db.Exec(`CREATE TABLE item (id INTEGER PRIMARY KEY AUTOINCREMENT, barcode TEXT, book_id TEXT)`).Error)
+
+	files, err := Files(constant.MySQLDriver)
+	require.NoError(t, err)
+
+	script, err := fs.ReadFile(files, "0001_create_initial_schema.up.sql")
+	require.NoError(t, err)
+
+	for _, statement := range strings.Split(string(script), ";") {
+		if strings.Contains(statement, "CREATE TABLE IF NOT EXISTS `item`") {
+			require.NoError(t, addMissingIndexes(db, statement))
+			require.NoError(t, addMissingIndexes(db, statement))
+		}
+	}
+
+	require.True(t, db.Migrator().HasIndex("item", "idx_item_barcode"))
+	require.True(t, db.Migrator().HasIndex("item", "idx_item_book_id"))
+	require.Error(t, db.Exec(`INSERT INTO item (barcode, book_id) VALUES ('ITEM-0001', 'BOOK-0001'), ('ITEM-0001', 'BOOK-0002')`).Error)
+}
+
+func TestUpBackfillsCopiesOfExistingBooks(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	// book and loan_book as they were before copies were introduced
+	require.NoError(t, db.Exec(`CREATE TABLE book (id INTEGER PRIMARY KEY AUTOINCREMENT, book_id TEXT, title TEXT, genre TEXT, author TEXT, publisher TEXT, publication_year TEXT, status TEXT, timestamp TEXT)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE loan_book (id INTEGER PRIMARY KEY AUTOINCREMENT, loan_id TEXT, book_id TEXT, title TEXT, username TEXT, loan_start_date TEXT, loan_end_date TEXT, status TEXT)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO book (book_id, status) VALUES ('BOOK-0001', 'AVAILABLE'), ('BOOK-0002', 'NOT AVAILABLE'), ('BOOK-0003', 'ON HOLD'), ('BOOK-0004', 'NOT AVAILABLE')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO loan_book (loan_id, book_id, username, status) VALUES ('LOAN-0001', 'BOOK-0002', 'test', 'BORROWED'), ('LOAN-0002', 'BOOK-0001', 'test', 'RETURNED')`).Error)
+
+	files, err := Files(constant.SQLiteDriver)
+	require.NoError(t, err)
+
+	migrator, err := New(db, files)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var items []struct {
+		Barcode string
+		BookID  string
+		Status  string
+	}
+
+	require.NoError(t, db.Raw(`SELECT barcode, book_id, status FROM item ORDER BY book_id`).Scan(&items).Error)
+	require.Len(t, items, 4)
+
+	for i, status := range []string{constant.ItemAvailableStatus, constant.ItemOnLoanStatus, constant.ItemOnHoldStatus, constant.ItemRepairStatus} {
+		require.Equal(t, items[i].BookID, items[i].Barcode)
+		require.Equal(t, status, items[i].Status, items[i].BookID)
+	}
+
+	var barcodes []string
+	require.NoError(t, db.Raw(`SELECT barcode FROM loan_book ORDER BY loan_id`).Scan(&barcodes).Error)
+	require.Equal(t, []string{"BOOK-0002", ""}, barcodes)
+
+	// Reverting past the backfill takes the copies away again
+	_, err = migrator.Down(ctx, 2)
+	require.NoError(t, err)
+
+	var count int64
+	require.NoError(t, db.Raw(`SELECT count(*) FROM item`).Scan(&count).Error)
+	require.Zero(t, count)
+}
+
+func TestUpKeepsOneActiveReservationPerUserAndBook(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	// Duplicates the unique index would refuse, as a race could leave them
+	require.NoError(t, db.Exec(`CREATE TABLE reservation (id INTEGER PRIMARY KEY AUTOINCREMENT, book_id VARCHAR(191) NOT NULL, username VARCHAR(191) NOT NULL, barcode VARCHAR(191) NOT NULL DEFAULT '', status VARCHAR(20) NOT NULL DEFAULT '', queued_at DATETIME NULL, ready_at DATETIME NULL, pickup_deadline DATETIME NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO reservation (book_id, username, status) VALUES ('BOOK-0001', 'ana', 'WAITING'), ('BOOK-0001', 'ana', 'WAITING'), ('BOOK-0002', 'ana', 'WAITING'), ('BOOK-0002', 'ana', 'READY'), ('BOOK-0002', 'ana', 'CANCELLED'), ('BOOK-0001', 'budi', 'WAITING')`).Error)
+
+	files, err := Files(constant.SQLiteDriver)
+	require.NoError(t, err)
+
+	migrator, err := New(db, files)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	var statuses []string
+	require.NoError(t, db.Raw(`SELECT status FROM reservation ORDER BY id`).Scan(&statuses).Error)
+	require.Equal(t, []string{
+		constant.ReservationWaitingStatus, constant.ReservationCancelledStatus,
+		constant.ReservationCancelledStatus, constant.ReservationReadyStatus, constant.ReservationCancelledStatus,
+		constant.ReservationWaitingStatus,
+	}, statuses)
+
+	// A second active reservation is refused, ended ones are not
+	require.Error(t, db.Exec(`INSERT INTO reservation (book_id, username, status) VALUES ('BOOK-0001', 'ana', 'READY')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO reservation (book_id, username, status) VALUES ('BOOK-0001', 'ana', 'EXPIRED')`).Error)
+}
+
+func TestUpRefusesExistingTablesWithoutRequiredColumns(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Exec(`CREATE TABLE book (id INTEGER PRIMARY KEY)`).Error)
+
+	migrator, err := New(db, fstest.MapFS{
+		"0001_create_book.up.sql":   {Data: []byte("CREATE TABLE IF NOT EXISTS \"book\" (\n    \"id\" INTEGER PRIMARY KEY,\n    \"book_id\" VARCHAR(191) NOT NULL\n);")},
+		"0001_create_book.down.sql": {Data: []byte("DROP TABLE book;")},
+	})
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(ctx)
+	require.ErrorContains(t, err, "book_id")
+	require.Empty(t, applied)
+}
+
+func TestSQLiteSchema(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	files, err := Files(constant.SQLiteDriver)
+	require.NoError(t, err)
+
+	migrator, err := New(db, files)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	for table, index := range map[string]string{
+		"book":            "idx_book_book_id",
+		"item":            "idx_item_book_id",
+		"user":            "idx_user_username",
+		"loan_book":       "idx_loan_book_loan_id",
+		"reservation":     "idx_reservation_username",
+		"fine":            "idx_fine_loan_id",
+		"refresh_token":   "idx_refresh_token_token_hash",
+		"revoked_token":   "idx_revoked_token_expires_at",
+		"recovery_code":   "idx_recovery_code_username",
+		"login_challenge": "idx_login_challenge_token_hash",
+		"api_key":         "idx_api_key_key_hash",
+		"user_identity":   "idx_user_identity_issuer_subject",
+	} {
+		require.True(t, db.Migrator().HasIndex(table, index), index)
+	}
+
+	require.True(t, db.Migrator().HasIndex("reservation", "idx_reservation_active"))
+
+	require.True(t, db.Migrator().HasTable("loan_renewal"))
+	require.True(t, db.Migrator().HasTable("sequence"))
+	require.True(t, db.Migrator().HasTable("user_totp"))
+
+	_, err = migrator.Down(ctx, len(migrator.migrations))
+	require.NoError(t, err)
+	require.False(t, db.Migrator().HasTable("book"))
+	require.False(t, db.Migrator().HasTable("refresh_token"))
+	require.False(t, db.Migrator().HasTable("user_totp"))
+	require.False(t, db.Migrator().HasTable("api_key"))
+	require.False(t, db.Migrator().HasTable("user_identity"))
+}
@@ -0,0 +1,104 @@
+-- Tables that existed before migrations were introduced are kept, and the
+-- migrator adds the columns and the KEY indexes they lack.
+
+CREATE TABLE IF NOT EXISTS `book` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `book_id` VARCHAR(191) NOT NULL,
+    `title` VARCHAR(255) NOT NULL DEFAULT '',
+    `genre` VARCHAR(100) NOT NULL DEFAULT '',
+    `author` VARCHAR(255) NOT NULL DEFAULT '',
+    `publisher` VARCHAR(255) NOT NULL DEFAULT '',
+    `publication_year` VARCHAR(10) NOT NULL DEFAULT '',
+    `status` VARCHAR(20) NOT NULL DEFAULT '',
+    `timestamp` VARCHAR(100) NOT NULL DEFAULT '',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_book_book_id` (`book_id`),
+    KEY `idx_book_genre` (`genre`)
+);
+
+CREATE TABLE IF NOT EXISTS `item` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `barcode` VARCHAR(191) NOT NULL,
+    `book_id` VARCHAR(191) NOT NULL,
+    `shelf_location` VARCHAR(100) NOT NULL DEFAULT '',
+    `condition` VARCHAR(20) NOT NULL DEFAULT '',
+    `status` VARCHAR(20) NOT NULL DEFAULT '',
+    `timestamp` VARCHAR(100) NOT NULL DEFAULT '',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_item_barcode` (`barcode`),
+    KEY `idx_item_book_id` (`book_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `username` VARCHAR(191) NOT NULL,
+    `password` VARCHAR(255) NOT NULL DEFAULT '',
+    `role` VARCHAR(20) NOT NULL DEFAULT '',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_username` (`username`)
+);
+
+CREATE TABLE IF NOT EXISTS `loan_book` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `loan_id` VARCHAR(191) NOT NULL,
+    `book_id` VARCHAR(191) NOT NULL,
+    `barcode` VARCHAR(191) NOT NULL DEFAULT '',
+    `title` VARCHAR(255) NOT NULL DEFAULT '',
+    `username` VARCHAR(191) NOT NULL,
+    `loan_start_date` VARCHAR(10) NOT NULL DEFAULT '',
+    `loan_end_date` VARCHAR(10) NOT NULL DEFAULT '',
+    `status` VARCHAR(20) NOT NULL DEFAULT '',
+    `renewal_count` INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_loan_book_loan_id` (`loan_id`),
+    KEY `idx_loan_book_book_id` (`book_id`),
+    KEY `idx_loan_book_username` (`username`),
+    KEY `idx_loan_book_status_end_date` (`status`, `loan_end_date`)
+);
+
+CREATE TABLE IF NOT EXISTS `loan_renewal` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `loan_id` VARCHAR(191) NOT NULL,
+    `previous_end_date` VARCHAR(10) NOT NULL DEFAULT '',
+    `new_end_date` VARCHAR(10) NOT NULL DEFAULT '',
+    `renewed_by` VARCHAR(191) NOT NULL DEFAULT '',
+    `renewed_at` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_loan_renewal_loan_id` (`loan_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `reservation` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `book_id` VARCHAR(191) NOT NULL,
+    `username` VARCHAR(191) NOT NULL,
+    `barcode` VARCHAR(191) NOT NULL DEFAULT '',
+    `status` VARCHAR(20) NOT NULL DEFAULT '',
+    `queued_at` DATETIME(3) NULL,
+    `ready_at` DATETIME(3) NULL,
+    `pickup_deadline` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_reservation_book_id_status` (`book_id`, `status`),
+    KEY `idx_reservation_username` (`username`)
+);
+
+CREATE TABLE IF NOT EXISTS `fine` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `loan_id` VARCHAR(191) NOT NULL,
+    `username` VARCHAR(191) NOT NULL,
+    `book_id` VARCHAR(191) NOT NULL,
+    `days_late` BIGINT NOT NULL DEFAULT 0,
+    `rate` BIGINT NOT NULL DEFAULT 0,
+    `amount` BIGINT NOT NULL DEFAULT 0,
+    `status` VARCHAR(20) NOT NULL DEFAULT '',
+    `updated_at` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_fine_loan_id` (`loan_id`),
+    KEY `idx_fine_username` (`username`),
+    KEY `idx_fine_book_id` (`book_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `sequence` (
+    `prefix` VARCHAR(191) NOT NULL,
+    `value` BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`prefix`)
+);
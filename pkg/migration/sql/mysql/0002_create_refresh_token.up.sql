@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS `refresh_token` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `token_hash` VARCHAR(64) NOT NULL,
+    `family` VARCHAR(64) NOT NULL,
+    `username` VARCHAR(191) NOT NULL,
+    `access_jti` VARCHAR(64) NOT NULL,
+    `access_expires_at` DATETIME(3) NOT NULL,
+    `expires_at` DATETIME(3) NOT NULL,
+    `revoked_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_refresh_token_token_hash` (`token_hash`),
+    KEY `idx_refresh_token_family` (`family`),
+    KEY `idx_refresh_token_username` (`username`),
+    KEY `idx_refresh_token_access_jti` (`access_jti`)
+);
+
+CREATE TABLE IF NOT EXISTS `revoked_token` (
+    `jti` VARCHAR(191) NOT NULL,
+    `expires_at` DATETIME(3) NOT NULL,
+    PRIMARY KEY (`jti`),
+    KEY `idx_revoked_token_expires_at` (`expires_at`)
+);
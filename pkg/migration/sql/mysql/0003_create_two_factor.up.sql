@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS `user_totp` (
+    `username` VARCHAR(191) NOT NULL,
+    `secret` VARCHAR(64) NOT NULL,
+    `last_step` BIGINT NOT NULL DEFAULT 0,
+    `confirmed_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NULL,
+    PRIMARY KEY (`username`)
+);
+
+CREATE TABLE IF NOT EXISTS `recovery_code` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `username` VARCHAR(191) NOT NULL,
+    `code_hash` VARCHAR(64) NOT NULL,
+    `used_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_recovery_code_username` (`username`)
+);
+
+CREATE TABLE IF NOT EXISTS `login_challenge` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `token_hash` VARCHAR(64) NOT NULL,
+    `username` VARCHAR(191) NOT NULL,
+    `attempts` INT NOT NULL DEFAULT 0,
+    `expires_at` DATETIME(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_login_challenge_token_hash` (`token_hash`),
+    KEY `idx_login_challenge_expires_at` (`expires_at`)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `api_key` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(191) NOT NULL,
+    `prefix` VARCHAR(32) NOT NULL,
+    `key_hash` VARCHAR(64) NOT NULL,
+    `scopes` TEXT NOT NULL,
+    `created_by` VARCHAR(191) NULL,
+    `expires_at` DATETIME(3) NULL,
+    `last_used_at` DATETIME(3) NULL,
+    `revoked_at` DATETIME(3) NULL,
+    `created_at` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_api_key_prefix` (`prefix`),
+    UNIQUE KEY `idx_api_key_key_hash` (`key_hash`)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `user_identity` (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `issuer` VARCHAR(191) NOT NULL,
+    `subject` VARCHAR(191) NOT NULL,
+    `user_id` BIGINT NOT NULL,
+    `username` VARCHAR(191) NOT NULL,
+    `provisioned` BOOLEAN NOT NULL DEFAULT FALSE,
+    `created_at` DATETIME(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_identity_issuer_subject` (`issuer`, `subject`),
+    KEY `idx_user_identity_username` (`username`)
+);
@@ -0,0 +1,3 @@
+UPDATE `loan_book` SET `barcode` = '' WHERE `barcode` = `book_id`;
+UPDATE `reservation` SET `barcode` = '' WHERE `barcode` = `book_id`;
+DELETE FROM `item` WHERE `barcode` = `book_id`;
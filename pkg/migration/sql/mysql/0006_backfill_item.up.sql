@@ -0,0 +1,25 @@
+-- Books lent before copies were introduced get one copy each, with the
+-- book_id as barcode. Its status follows the book: a copy is only on loan
+-- when an open loan is behind it, a book that was not available for
+-- another reason is left in repair for staff to check.
+INSERT INTO `item` (`barcode`, `book_id`, `shelf_location`, `condition`, `status`, `timestamp`)
+SELECT b.`book_id`, b.`book_id`, '', 'GOOD',
+    CASE
+        WHEN EXISTS (SELECT 1 FROM `loan_book` l WHERE l.`book_id` = b.`book_id` AND l.`status` = 'BORROWED') THEN 'ON LOAN'
+        WHEN b.`status` = 'ON HOLD' THEN 'ON HOLD'
+        WHEN b.`status` = 'NOT AVAILABLE' THEN 'IN REPAIR'
+        ELSE 'AVAILABLE'
+    END,
+    COALESCE(b.`timestamp`, '')
+FROM `book` b
+WHERE NOT EXISTS (SELECT 1 FROM `item` i WHERE i.`book_id` = b.`book_id` OR i.`barcode` = b.`book_id`);
+
+-- Open loans and ready holds made before copies name the copy they hold,
+-- so that returning or expiring them frees it.
+UPDATE `loan_book` SET `barcode` = `book_id`
+WHERE `barcode` = '' AND `status` = 'BORROWED'
+    AND EXISTS (SELECT 1 FROM `item` i WHERE i.`barcode` = `loan_book`.`book_id` AND i.`status` = 'ON LOAN');
+
+UPDATE `reservation` SET `barcode` = `book_id`
+WHERE `barcode` = '' AND `status` = 'READY'
+    AND EXISTS (SELECT 1 FROM `item` i WHERE i.`barcode` = `reservation`.`book_id` AND i.`status` = 'ON HOLD');
@@ -0,0 +1,3 @@
+ALTER TABLE `reservation`
+    DROP INDEX `idx_reservation_active`,
+    DROP COLUMN `active`;
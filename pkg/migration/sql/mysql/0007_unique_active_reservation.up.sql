@@ -0,0 +1,13 @@
+-- A user has one active reservation of a book. Duplicates that slipped in
+-- before are cancelled, keeping the ready hold or else the oldest.
+UPDATE `reservation` r
+JOIN `reservation` o ON o.`username` = r.`username` AND o.`book_id` = r.`book_id` AND o.`id` <> r.`id`
+    AND o.`status` IN ('WAITING', 'READY')
+SET r.`status` = 'CANCELLED'
+WHERE r.`status` = 'WAITING' AND (o.`status` = 'READY' OR o.`id` < r.`id`);
+
+-- MySQL has no partial indexes, so the index covers a column that is only
+-- set while the reservation is active. Rows where it is NULL never clash.
+ALTER TABLE `reservation`
+    ADD COLUMN `active` TINYINT AS (CASE WHEN `status` IN ('WAITING', 'READY') THEN 1 END) STORED,
+    ADD UNIQUE KEY `idx_reservation_active` (`username`, `book_id`, `active`);
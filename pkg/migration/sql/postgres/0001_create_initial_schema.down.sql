@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS "sequence";
+DROP TABLE IF EXISTS "fine";
+DROP TABLE IF EXISTS "reservation";
+DROP TABLE IF EXISTS "loan_renewal";
+DROP TABLE IF EXISTS "loan_book";
+DROP TABLE IF EXISTS "user";
+DROP TABLE IF EXISTS "item";
+DROP TABLE IF EXISTS "book";
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "api_key" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(191) NOT NULL,
+    "prefix" VARCHAR(32) NOT NULL,
+    "key_hash" VARCHAR(64) NOT NULL,
+    "scopes" TEXT NOT NULL,
+    "created_by" VARCHAR(191) NULL,
+    "expires_at" TIMESTAMPTZ NULL,
+    "last_used_at" TIMESTAMPTZ NULL,
+    "revoked_at" TIMESTAMPTZ NULL,
+    "created_at" TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_prefix" ON "api_key" ("prefix");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_key_hash" ON "api_key" ("key_hash");
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "user_identity" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "issuer" VARCHAR(191) NOT NULL,
+    "subject" VARCHAR(191) NOT NULL,
+    "user_id" BIGINT NOT NULL,
+    "username" VARCHAR(191) NOT NULL,
+    "provisioned" BOOLEAN NOT NULL DEFAULT FALSE,
+    "created_at" TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_identity_issuer_subject" ON "user_identity" ("issuer", "subject");
+CREATE INDEX IF NOT EXISTS "idx_user_identity_username" ON "user_identity" ("username");
@@ -0,0 +1,11 @@
+-- A user has one active reservation of a book. Duplicates that slipped in
+-- before are cancelled, keeping the ready hold or else the oldest.
+UPDATE "reservation" SET "status" = 'CANCELLED'
+WHERE "status" = 'WAITING'
+    AND EXISTS (
+        SELECT 1 FROM "reservation" o
+        WHERE o."username" = "reservation"."username" AND o."book_id" = "reservation"."book_id" AND o."id" <> "reservation"."id"
+            AND (o."status" = 'READY' OR (o."status" = 'WAITING' AND o."id" < "reservation"."id"))
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_reservation_active" ON "reservation" ("username", "book_id") WHERE "status" IN ('WAITING', 'READY');
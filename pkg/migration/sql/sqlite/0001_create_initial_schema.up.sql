@@ -0,0 +1,104 @@
+-- Tables that existed before migrations were introduced are kept, and the
+-- migrator adds the columns they lack. Indexes are created on their own so
+-- that existing tables get them too.
+
+CREATE TABLE IF NOT EXISTS "book" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "book_id" VARCHAR(191) NOT NULL,
+    "title" VARCHAR(255) NOT NULL DEFAULT '',
+    "genre" VARCHAR(100) NOT NULL DEFAULT '',
+    "author" VARCHAR(255) NOT NULL DEFAULT '',
+    "publisher" VARCHAR(255) NOT NULL DEFAULT '',
+    "publication_year" VARCHAR(10) NOT NULL DEFAULT '',
+    "status" VARCHAR(20) NOT NULL DEFAULT '',
+    "timestamp" VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_book_book_id" ON "book" ("book_id");
+CREATE INDEX IF NOT EXISTS "idx_book_genre" ON "book" ("genre");
+
+CREATE TABLE IF NOT EXISTS "item" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "barcode" VARCHAR(191) NOT NULL,
+    "book_id" VARCHAR(191) NOT NULL,
+    "shelf_location" VARCHAR(100) NOT NULL DEFAULT '',
+    "condition" VARCHAR(20) NOT NULL DEFAULT '',
+    "status" VARCHAR(20) NOT NULL DEFAULT '',
+    "timestamp" VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_item_barcode" ON "item" ("barcode");
+CREATE INDEX IF NOT EXISTS "idx_item_book_id" ON "item" ("book_id");
+
+CREATE TABLE IF NOT EXISTS "user" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "username" VARCHAR(191) NOT NULL,
+    "password" VARCHAR(255) NOT NULL DEFAULT '',
+    "role" VARCHAR(20) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_username" ON "user" ("username");
+
+CREATE TABLE IF NOT EXISTS "loan_book" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "loan_id" VARCHAR(191) NOT NULL,
+    "book_id" VARCHAR(191) NOT NULL,
+    "barcode" VARCHAR(191) NOT NULL DEFAULT '',
+    "title" VARCHAR(255) NOT NULL DEFAULT '',
+    "username" VARCHAR(191) NOT NULL,
+    "loan_start_date" VARCHAR(10) NOT NULL DEFAULT '',
+    "loan_end_date" VARCHAR(10) NOT NULL DEFAULT '',
+    "status" VARCHAR(20) NOT NULL DEFAULT '',
+    "renewal_count" INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_loan_book_loan_id" ON "loan_book" ("loan_id");
+CREATE INDEX IF NOT EXISTS "idx_loan_book_book_id" ON "loan_book" ("book_id");
+CREATE INDEX IF NOT EXISTS "idx_loan_book_username" ON "loan_book" ("username");
+CREATE INDEX IF NOT EXISTS "idx_loan_book_status_end_date" ON "loan_book" ("status", "loan_end_date");
+
+CREATE TABLE IF NOT EXISTS "loan_renewal" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "loan_id" VARCHAR(191) NOT NULL,
+    "previous_end_date" VARCHAR(10) NOT NULL DEFAULT '',
+    "new_end_date" VARCHAR(10) NOT NULL DEFAULT '',
+    "renewed_by" VARCHAR(191) NOT NULL DEFAULT '',
+    "renewed_at" DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS "idx_loan_renewal_loan_id" ON "loan_renewal" ("loan_id");
+
+CREATE TABLE IF NOT EXISTS "reservation" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "book_id" VARCHAR(191) NOT NULL,
+    "username" VARCHAR(191) NOT NULL,
+    "barcode" VARCHAR(191) NOT NULL DEFAULT '',
+    "status" VARCHAR(20) NOT NULL DEFAULT '',
+    "queued_at" DATETIME NULL,
+    "ready_at" DATETIME NULL,
+    "pickup_deadline" DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS "idx_reservation_book_id_status" ON "reservation" ("book_id", "status");
+CREATE INDEX IF NOT EXISTS "idx_reservation_username" ON "reservation" ("username");
+
+CREATE TABLE IF NOT EXISTS "fine" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "loan_id" VARCHAR(191) NOT NULL,
+    "username" VARCHAR(191) NOT NULL,
+    "book_id" VARCHAR(191) NOT NULL,
+    "days_late" BIGINT NOT NULL DEFAULT 0,
+    "rate" BIGINT NOT NULL DEFAULT 0,
+    "amount" BIGINT NOT NULL DEFAULT 0,
+    "status" VARCHAR(20) NOT NULL DEFAULT '',
+    "updated_at" DATETIME NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_fine_loan_id" ON "fine" ("loan_id");
+CREATE INDEX IF NOT EXISTS "idx_fine_username" ON "fine" ("username");
+CREATE INDEX IF NOT EXISTS "idx_fine_book_id" ON "fine" ("book_id");
+
+CREATE TABLE IF NOT EXISTS "sequence" (
+    "prefix" VARCHAR(191) PRIMARY KEY,
+    "value" BIGINT NOT NULL DEFAULT 0
+);
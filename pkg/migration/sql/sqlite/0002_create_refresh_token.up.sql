@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS "refresh_token" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "token_hash" VARCHAR(64) NOT NULL,
+    "family" VARCHAR(64) NOT NULL,
+    "username" VARCHAR(191) NOT NULL,
+    "access_jti" VARCHAR(64) NOT NULL,
+    "access_expires_at" DATETIME NOT NULL,
+    "expires_at" DATETIME NOT NULL,
+    "revoked_at" DATETIME NULL,
+    "created_at" DATETIME NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_token_token_hash" ON "refresh_token" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_refresh_token_family" ON "refresh_token" ("family");
+CREATE INDEX IF NOT EXISTS "idx_refresh_token_username" ON "refresh_token" ("username");
+CREATE INDEX IF NOT EXISTS "idx_refresh_token_access_jti" ON "refresh_token" ("access_jti");
+
+CREATE TABLE IF NOT EXISTS "revoked_token" (
+    "jti" VARCHAR(191) PRIMARY KEY,
+    "expires_at" DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "idx_revoked_token_expires_at" ON "revoked_token" ("expires_at");
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS "user_totp" (
+    "username" VARCHAR(191) PRIMARY KEY,
+    "secret" VARCHAR(64) NOT NULL,
+    "last_step" BIGINT NOT NULL DEFAULT 0,
+    "confirmed_at" DATETIME NULL,
+    "created_at" DATETIME NULL
+);
+
+CREATE TABLE IF NOT EXISTS "recovery_code" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "username" VARCHAR(191) NOT NULL,
+    "code_hash" VARCHAR(64) NOT NULL,
+    "used_at" DATETIME NULL,
+    "created_at" DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS "idx_recovery_code_username" ON "recovery_code" ("username");
+
+CREATE TABLE IF NOT EXISTS "login_challenge" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "token_hash" VARCHAR(64) NOT NULL,
+    "username" VARCHAR(191) NOT NULL,
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "expires_at" DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_challenge_token_hash" ON "login_challenge" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_login_challenge_expires_at" ON "login_challenge" ("expires_at");
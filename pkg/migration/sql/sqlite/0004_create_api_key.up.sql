@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "api_key" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "name" VARCHAR(191) NOT NULL,
+    "prefix" VARCHAR(32) NOT NULL,
+    "key_hash" VARCHAR(64) NOT NULL,
+    "scopes" TEXT NOT NULL,
+    "created_by" VARCHAR(191) NULL,
+    "expires_at" DATETIME NULL,
+    "last_used_at" DATETIME NULL,
+    "revoked_at" DATETIME NULL,
+    "created_at" DATETIME NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_prefix" ON "api_key" ("prefix");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_key_hash" ON "api_key" ("key_hash");
@@ -0,0 +1,125 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+)
+
+// MaxBytes is the longest password bcrypt accepts.
+const MaxBytes = 72
+
+var (
+	ErrSameAsUsername = errors.New("password must not be the same as the username")
+	ErrBreached       = errors.New("password is too common, it appears in a list of breached passwords")
+	ErrTooLong        = fmt.Errorf("password must be at most %d bytes", MaxBytes)
+)
+
+// Policy is what a new password must satisfy. Character classes are lower
+// case letters, upper case letters, digits and anything else. Breached holds
+// lower-cased passwords that are refused.
+type Policy struct {
+	MinLength  int
+	MinClasses int
+	Breached   map[string]struct{}
+}
+
+// Check returns why password can not be used by username, or nil.
+func (p Policy) Check(username, password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+
+	if len(password) > MaxBytes {
+		return ErrTooLong
+	}
+
+	if classes(password) < p.MinClasses {
+		return fmt.Errorf("password must use at least %d of lower case letters, upper case letters, digits and symbols", p.MinClasses)
+	}
+
+	if strings.EqualFold(strings.TrimSpace(password), username) {
+		return ErrSameAsUsername
+	}
+
+	if _, ok := p.Breached[strings.ToLower(password)]; ok {
+		return ErrBreached
+	}
+
+	return nil
+}
+
+func classes(password string) int {
+	var lower, upper, digit, other int
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+
+	return lower + upper + digit + other
+}
+
+// LoadBreached reads a list of breached passwords, one per line. Blank
+// lines and lines starting with # are skipped.
+func LoadBreached(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+
+	return breached, scanner.Err()
+}
+
+var (
+	mu            sync.RWMutex
+	defaultPolicy = Policy{MinLength: constant.DefaultPasswordMinLength, MinClasses: constant.DefaultPasswordMinClasses}
+)
+
+// SetDefault sets the policy new passwords are checked against.
+func SetDefault(p Policy) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultPolicy = p
+}
+
+// Default returns the policy set with SetDefault, which starts out with the
+// default length and classes and no breached passwords.
+func Default() Policy {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultPolicy
+}
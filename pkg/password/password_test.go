@@ -0,0 +1,48 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MinClasses: 3, Breached: map[string]struct{}{"password1!": {}}}
+
+	tests := []struct {
+		username string
+		password string
+		valid    bool
+	}{
+		{username: "test", password: "Perpus-2024", valid: true},
+		{username: "test", password: "Ab1-", valid: false},
+		{username: "test", password: "perpustakaan", valid: false},
+		{username: "test", password: "perpus2024", valid: false},
+		{username: "test", password: "perpus-2024", valid: true},
+		{username: "Budi.Santoso1", password: "budi.santoso1", valid: false},
+		{username: "test", password: "Password1!", valid: false},
+		{username: "test", password: "Aa1-" + strings.Repeat("x", MaxBytes), valid: false},
+		{username: "test", password: "Kata-sandi-ÄÖÜ", valid: true},
+	}
+
+	for _, tt := range tests {
+		err := policy.Check(tt.username, tt.password)
+		require.Equal(t, tt.valid, err == nil, tt.password)
+	}
+}
+
+func TestLoadBreached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# common passwords\n123456\n\nQwerty123\n"), 0o600))
+
+	breached, err := LoadBreached(path)
+	require.NoError(t, err)
+	require.Len(t, breached, 2)
+	require.Contains(t, breached, "qwerty123")
+
+	_, err = LoadBreached(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
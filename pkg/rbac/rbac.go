@@ -0,0 +1,138 @@
+package rbac
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+)
+
+// Policy maps roles to the permissions they are granted.
+type Policy struct {
+	roles map[string]map[string]struct{}
+}
+
+// Can reports whether role is granted every one of permissions. Roles are
+// matched case-insensitively; unknown roles are granted nothing.
+func (p *Policy) Can(role string, permissions ...string) bool {
+	granted := p.roles[strings.ToUpper(role)]
+
+	for _, permission := range permissions {
+		if !grants(granted, permission) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Permissions lists the permissions granted to role as configured,
+// wildcards included.
+func (p *Policy) Permissions(role string) []string {
+	result := make([]string, 0, len(p.roles[strings.ToUpper(role)]))
+
+	for permission := range p.roles[strings.ToUpper(role)] {
+		result = append(result, permission)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func grants(granted map[string]struct{}, permission string) bool {
+	if _, ok := granted[constant.AllPermissions]; ok {
+		return true
+	}
+
+	if _, ok := granted[permission]; ok {
+		return true
+	}
+
+	if i := strings.Index(permission, ":"); i > 0 {
+		_, ok := granted[permission[:i]+":*"]
+		return ok
+	}
+
+	return false
+}
+
+// Allows reports whether granted, a list of permissions such as the scopes
+// of an API key, grants every one of permissions. Wildcards work as they do
+// for roles.
+func Allows(granted []string, permissions ...string) bool {
+	set := make(map[string]struct{}, len(granted))
+
+	for _, permission := range granted {
+		set[strings.ToLower(strings.TrimSpace(permission))] = struct{}{}
+	}
+
+	for _, permission := range permissions {
+		if !grants(set, permission) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Valid reports whether permission is one of constant.Permissions or a
+// wildcard over them.
+func Valid(permission string) bool {
+	if permission == constant.AllPermissions {
+		return true
+	}
+
+	for _, known := range constant.Permissions {
+		if permission == known {
+			return true
+		}
+
+		if resource, _, _ := strings.Cut(known, ":"); permission == resource+":*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// New returns the policy granting each role of rolePermissions its
+// permissions.
+func New(rolePermissions map[string][]string) *Policy {
+	policy := &Policy{roles: make(map[string]map[string]struct{}, len(rolePermissions))}
+
+	for role, permissions := range rolePermissions {
+		granted := make(map[string]struct{}, len(permissions))
+
+		for _, permission := range permissions {
+			granted[strings.ToLower(strings.TrimSpace(permission))] = struct{}{}
+		}
+
+		policy.roles[strings.ToUpper(role)] = granted
+	}
+
+	return policy
+}
+
+var (
+	mu            sync.RWMutex
+	defaultPolicy = New(constant.RolePermissions)
+)
+
+// SetDefault sets the policy enforced by middlewares.RequirePermission.
+func SetDefault(p *Policy) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultPolicy = p
+}
+
+// Default returns the policy set with SetDefault, which starts out as
+// constant.RolePermissions.
+func Default() *Policy {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultPolicy
+}
@@ -0,0 +1,71 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCan(t *testing.T) {
+	policy := New(map[string][]string{
+		constant.Karyawan:   {constant.LoanRead, constant.LoanWrite},
+		constant.Admin:      {"book:*", constant.LoanDelete},
+		constant.SuperAdmin: {constant.AllPermissions},
+	})
+
+	tests := []struct {
+		role        string
+		permissions []string
+		expected    bool
+	}{
+		{role: constant.Karyawan, permissions: []string{constant.LoanWrite}, expected: true},
+		{role: "karyawan", permissions: []string{constant.LoanRead, constant.LoanWrite}, expected: true},
+		{role: constant.Karyawan, permissions: []string{constant.LoanWrite, constant.LoanDelete}, expected: false},
+		{role: constant.Admin, permissions: []string{constant.BookWrite, constant.BookDelete}, expected: true},
+		{role: constant.Admin, permissions: []string{constant.ItemWrite}, expected: false},
+		{role: constant.SuperAdmin, permissions: []string{constant.UserManage}, expected: true},
+		{role: "", permissions: []string{constant.LoanRead}, expected: false},
+		{role: "GUEST", permissions: nil, expected: true},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, policy.Can(tt.role, tt.permissions...), "%s %v", tt.role, tt.permissions)
+	}
+
+	require.Equal(t, []string{"book:*", constant.LoanDelete}, policy.Permissions("admin"))
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := Default()
+
+	require.True(t, policy.Can(constant.Karyawan, constant.LoanWrite))
+	require.False(t, policy.Can(constant.Karyawan, constant.LoanDelete))
+	require.False(t, policy.Can(constant.Karyawan, constant.BookWrite))
+	require.True(t, policy.Can(constant.Admin, constant.BookDelete, constant.LoanManage))
+	require.False(t, policy.Can(constant.Admin, constant.UserManage))
+	require.True(t, policy.Can(constant.SuperAdmin, constant.UserManage))
+}
+
+func TestAllows(t *testing.T) {
+	scopes := []string{constant.BookWrite, "loan:*"}
+
+	require.True(t, Allows(scopes, constant.BookWrite))
+	require.True(t, Allows(scopes, constant.LoanManage, constant.LoanRead))
+	require.False(t, Allows(scopes, constant.BookWrite, constant.BookDelete))
+	require.False(t, Allows(nil, constant.LoanRead))
+	require.True(t, Allows([]string{constant.AllPermissions}, constant.APIKeyManage))
+}
+
+func TestValid(t *testing.T) {
+	for permission, expected := range map[string]bool{
+		constant.LoanWrite:      true,
+		"loan:*":                true,
+		constant.AllPermissions: true,
+		"loan:approve":          false,
+		"library:*":             false,
+		"":                      false,
+	} {
+		require.Equal(t, expected, Valid(permission), permission)
+	}
+}
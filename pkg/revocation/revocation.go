@@ -0,0 +1,80 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken is an access token that may no longer be used, keyed by its
+// jti claim. It is kept until the token would have expired anyway.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"column:jti;primaryKey;size:191"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_token"
+}
+
+// List is the set of revoked access tokens.
+type List interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	Purge(ctx context.Context) (int64, error)
+}
+
+type list struct {
+	db *gorm.DB
+}
+
+// Revoke adds jti to the list. Revoking a token twice is not an error.
+func (l *list) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return databases.Conn(ctx, l.db).Clauses(clause.OnConflict{DoNothing: true}).Create(&RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// IsRevoked reports whether jti is on the list.
+func (l *list) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+
+	err := databases.Conn(ctx, l.db).Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+
+	return count > 0, err
+}
+
+// Purge drops the tokens that have expired, since they are rejected without
+// the list, and returns how many were dropped.
+func (l *list) Purge(ctx context.Context) (int64, error) {
+	result := databases.Conn(ctx, l.db).Where("expires_at < ?", time.Now()).Delete(&RevokedToken{})
+
+	return result.RowsAffected, result.Error
+}
+
+func New(db *gorm.DB) List {
+	return &list{db: db}
+}
+
+var (
+	mu          sync.RWMutex
+	defaultList List
+)
+
+// SetDefault sets the list consulted by middlewares.VerifyJWTRSA.
+func SetDefault(l List) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultList = l
+}
+
+// Default returns the list set with SetDefault, or nil when there is none.
+func Default() List {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultList
+}
@@ -0,0 +1,449 @@
+package jwtrsa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrNoSigningKey   = errors.New("error in pkg jwtrsa, there is no private key to sign with")
+	ErrUnknownKey     = errors.New("error in pkg jwtrsa, the token was signed with an unknown key")
+	ErrKeyAlgorithm   = errors.New("error in pkg jwtrsa, the token algorithm does not match its key")
+	ErrUnsupportedPEM = errors.New("error in pkg jwtrsa, the PEM block is not a supported key")
+	ErrUnsupportedJWK = errors.New("error in pkg jwtrsa, the JWK is not a supported key")
+)
+
+// Key is a signing key pair, or only the public half of one that tokens
+// are still verified with. ID is the RFC 7638 thumbprint of the public key,
+// so every instance given the same key agrees on its kid.
+type Key struct {
+	ID      string
+	Method  string
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// Source is where a Ring reads its keys from. PrivateKey and PublicKey are
+// PEM, the files hold PEM and Dir holds *.pem files of either half.
+//
+// The private key of PrivateKey or PrivateKeyFile signs new tokens; without
+// one the private key in Dir whose file name sorts last does, so a key is
+// rotated by adding a file with a later name. Every other key only
+// verifies tokens until it is removed.
+type Source struct {
+	PrivateKey     string
+	PublicKey      string
+	PrivateKeyFile string
+	PublicKeyFile  string
+	Dir            string
+}
+
+// Load reads the keys of s, the signing key first.
+func (s Source) Load() ([]Key, error) {
+	var (
+		signing *Key
+		keys    []Key
+	)
+
+	add := func(data []byte, name string) error {
+		key, err := ParseKey(data)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if key.Private != nil && signing == nil {
+			signing = &key
+			return nil
+		}
+
+		// Both halves of a key may be given, the kid is the same
+		for _, known := range keys {
+			if known.ID == key.ID {
+				return nil
+			}
+		}
+
+		keys = append(keys, key)
+
+		return nil
+	}
+
+	for _, value := range []string{s.PrivateKey, s.PublicKey} {
+		if value == "" {
+			continue
+		}
+
+		if err := add([]byte(value), "env"); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range []string{s.PrivateKeyFile, s.PublicKeyFile} {
+		if path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = add(data, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Dir != "" {
+		paths, err := filepath.Glob(filepath.Join(s.Dir, "*.pem"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The last private key signs, so read the files newest name first
+		sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err = add(data, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if signing == nil {
+		return keys, nil
+	}
+
+	result := []Key{*signing}
+
+	for _, key := range keys {
+		if key.ID != signing.ID {
+			result = append(result, key)
+		}
+	}
+
+	return result, nil
+}
+
+// ParseKey parses a PEM encoded RSA, ECDSA or Ed25519 key. A private key
+// carries its public half.
+func ParseKey(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return Key{}, ErrUnsupportedPEM
+	}
+
+	var (
+		private crypto.PrivateKey
+		public  crypto.PublicKey
+		err     error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, ErrUnsupportedPEM
+	}
+
+	if err != nil {
+		return Key{}, err
+	}
+
+	if signer, ok := private.(crypto.Signer); ok {
+		public = signer.Public()
+	}
+
+	if public == nil {
+		return Key{}, ErrUnsupportedPEM
+	}
+
+	key := Key{Private: private, Public: public}
+
+	jwk, err := ToJWK(key.Public)
+
+	if err != nil {
+		return Key{}, err
+	}
+
+	key.ID = jwk.thumbprint()
+	key.Method = jwk.Alg
+
+	return key, nil
+}
+
+// Ring holds the keys tokens are signed and verified with. Reload swaps in
+// the keys of its source, e.g. on SIGHUP, without dropping tokens signed
+// by keys that are still there.
+type Ring struct {
+	mu     sync.RWMutex
+	source Source
+	keys   []Key
+}
+
+// NewRing returns a ring with the keys of source.
+func NewRing(source Source) (*Ring, error) {
+	ring := &Ring{source: source}
+
+	return ring, ring.Reload()
+}
+
+// Reload reads the keys of the source again. The keys are kept as they
+// were when they can not be read.
+func (r *Ring) Reload() error {
+	keys, err := r.source.Load()
+
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys = keys
+
+	return nil
+}
+
+// Signing returns the key new tokens are signed with.
+func (r *Ring) Signing() (Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.keys) == 0 || r.keys[0].Private == nil {
+		return Key{}, ErrNoSigningKey
+	}
+
+	return r.keys[0], nil
+}
+
+// Verifier returns the key a token with kid was signed with. Tokens
+// without a kid predate the ring and are verified with its first key.
+func (r *Ring) Verifier(kid string) (Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if kid == "" || key.ID == kid {
+			return key, nil
+		}
+	}
+
+	return Key{}, ErrUnknownKey
+}
+
+// JWKS returns the public keys of the ring.
+func (r *Ring) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(r.keys))}
+
+	for _, key := range r.keys {
+		jwk, _ := ToJWK(key.Public)
+		jwk.Kid = key.ID
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// GenerateJWT signs a token with the signing key and names it in the kid
+// header. input.PrivateKey is not used.
+func (r *Ring) GenerateJWT(input GenerateInputJWT) (tokenStr string, expiresIn time.Time, err error) {
+	key, err := r.Signing()
+
+	if err != nil {
+		return tokenStr, expiresIn, err
+	}
+
+	token := jwt.New(jwt.GetSigningMethod(key.Method))
+	token.Header["kid"] = key.ID
+
+	tokenClaims := token.Claims.(jwt.MapClaims)
+
+	for k, v := range input.Claims {
+		tokenClaims[k] = v
+	}
+
+	exp := time.Now().Add(input.TimeExpire)
+	tokenClaims["exp"] = exp.Unix()
+
+	tkn, err := token.SignedString(key.Private)
+	return tkn, exp, err
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ToJWK returns public as a JWK, without a kid.
+func ToJWK(public crypto.PublicKey) (JWK, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Use: "sig", Alg: "RS256", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk := JWK{Kty: "EC", Use: "sig", Crv: key.Curve.Params().Name, X: encode(key.X.FillBytes(make([]byte, size))), Y: encode(key.Y.FillBytes(make([]byte, size)))}
+
+		switch key.Curve {
+		case elliptic.P256():
+			jwk.Alg = "ES256"
+		case elliptic.P384():
+			jwk.Alg = "ES384"
+		case elliptic.P521():
+			jwk.Alg = "ES512"
+		default:
+			return JWK{}, ErrUnsupportedPEM
+		}
+
+		return jwk, nil
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: encode(key)}, nil
+	}
+
+	return JWK{}, ErrUnsupportedPEM
+}
+
+// PublicKey returns the key j describes.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decode(j.N)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decode(j.E)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[j.Crv]
+
+		if !ok {
+			return nil, ErrUnsupportedJWK
+		}
+
+		x, err := decode(j.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decode(j.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(j.X)
+
+		if err != nil || j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedJWK
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, ErrUnsupportedJWK
+}
+
+// thumbprint is the RFC 7638 thumbprint of j: the hash of its required
+// members in lexicographic order.
+func (j JWK) thumbprint() string {
+	var members string
+
+	switch j.Kty {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, j.E, j.Kty, j.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, j.Crv, j.Kty, j.X, j.Y)
+	default:
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, j.Crv, j.Kty, j.X)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+var (
+	mu          sync.RWMutex
+	defaultRing *Ring
+)
+
+// SetDefault sets the ring access tokens are signed and verified with.
+func SetDefault(r *Ring) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultRing = r
+}
+
+// Default returns the ring set with SetDefault, or nil.
+func Default() *Ring {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultRing
+}
@@ -0,0 +1,443 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("error in pkg oidc, the ID token is invalid")
+	ErrNoUsername   = errors.New("error in pkg oidc, the ID token has no username claim")
+	ErrNoRole       = errors.New("error in pkg oidc, the groups of the user do not map to a role")
+	ErrCodeRefused  = errors.New("error in pkg oidc, the provider refused the code")
+)
+
+// signingMethods are the algorithms ID tokens may be signed with. HMAC is
+// left out, it would use the client secret as the key.
+var signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Config is a client registered with an OpenID Connect provider.
+// UsernameClaim names the claim that becomes the username and GroupsClaim
+// the claim listing the groups RoleMap maps to roles. Users in no mapped
+// group get DefaultRole, or are refused when it is empty.
+type Config struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMap       map[string]string
+	DefaultRole   string
+}
+
+// Provider is the part of the discovery document of the issuer the
+// authorization code flow uses.
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// State is kept by the browser between the redirect to the provider and
+// the callback. State guards against forged callbacks, Nonce ties the ID
+// token to the sign-in and Verifier is the PKCE code verifier.
+type State struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// String encodes s to be kept in a cookie.
+func (s State) String() string {
+	return s.State + "." + s.Nonce + "." + s.Verifier
+}
+
+// ParseState decodes a State encoded with String.
+func ParseState(value string) (State, bool) {
+	parts := strings.Split(value, ".")
+
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return State{}, false
+	}
+
+	return State{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, true
+}
+
+// Identity is who the provider signed in. Issuer and Subject name the
+// account at the provider for good, the username may change.
+type Identity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Role     string
+}
+
+// Client runs the authorization code flow against the provider of Config.
+// The provider is discovered on first use and its keys are fetched again
+// when a token names a key that is not known yet, at most once every
+// constant.OIDCKeyRefetchInterval.
+type Client struct {
+	config Config
+	http   *http.Client
+
+	mu       sync.Mutex
+	provider *Provider
+	keys     map[string]jwtrsa.JWK
+
+	// fetchMu is held while fetching keys, so one sign-in fetches them and
+	// the others wait for its result
+	fetchMu   sync.Mutex
+	fetchedAt time.Time
+}
+
+// New returns a client for config. Requests to the provider use
+// httpClient, or http.DefaultClient when it is nil.
+func New(config Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	return &Client{config: config, http: httpClient}
+}
+
+// Issuer returns the issuer the client trusts.
+func (c *Client) Issuer() string {
+	return c.config.Issuer
+}
+
+// NewState returns random values for a new sign-in.
+func NewState() (State, error) {
+	var (
+		state State
+		err   error
+	)
+
+	if state.State, err = utils.GenerateToken(16); err != nil {
+		return state, err
+	}
+
+	if state.Nonce, err = utils.GenerateToken(16); err != nil {
+		return state, err
+	}
+
+	state.Verifier, err = utils.GenerateToken(32)
+
+	return state, err
+}
+
+// Provider returns the discovered provider.
+func (c *Client) Provider(ctx context.Context) (Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return *c.provider, nil
+	}
+
+	var provider Provider
+
+	if err := c.getJSON(ctx, c.config.Issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return provider, err
+	}
+
+	if strings.TrimSuffix(provider.Issuer, "/") != c.config.Issuer {
+		return provider, fmt.Errorf("error in pkg oidc, the provider says it is %q instead of %q", provider.Issuer, c.config.Issuer)
+	}
+
+	c.provider = &provider
+
+	return provider, nil
+}
+
+// AuthCodeURL returns where to send the browser to sign in.
+func (c *Client) AuthCodeURL(ctx context.Context, state State) (string, error) {
+	provider, err := c.Provider(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {c.config.RedirectURL},
+		"scope":                 {strings.Join(c.config.Scopes, " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return provider.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange trades the code of the callback for the claims of a verified ID
+// token.
+func (c *Client) Exchange(ctx context.Context, code string, state State) (jwt.MapClaims, error) {
+	provider, err := c.Provider(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeCtx, cancel := context.WithTimeout(ctx, constant.OIDCRequestTimeout)
+	defer cancel()
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectURL},
+		"code_verifier": {state.Verifier},
+	}
+
+	req, err := http.NewRequestWithContext(exchangeCtx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+
+	res, err := c.http.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil && res.StatusCode == http.StatusOK {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK || body.IDToken == "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrCodeRefused, body.Error, body.ErrorDescription)
+	}
+
+	return c.Verify(ctx, body.IDToken, state.Nonce)
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID
+// token and returns its claims.
+func (c *Client) Verify(ctx context.Context, idToken, nonce string) (jwt.MapClaims, error) {
+	provider, err := c.Provider(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err = jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, provider, kid)
+	}, jwt.WithValidMethods(signingMethods), jwt.WithIssuer(provider.Issuer), jwt.WithAudience(c.config.ClientID), jwt.WithLeeway(constant.OIDCClockSkew))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: exp is missing", ErrInvalidToken)
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// Identify maps the claims of an ID token to the user they sign in. Of
+// the roles the groups map to, the one with the most privileges wins.
+func (c *Client) Identify(claims jwt.MapClaims) (Identity, error) {
+	identity := Identity{Issuer: c.config.Issuer, Role: c.config.DefaultRole}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Username, _ = claims[c.config.UsernameClaim].(string)
+	identity.Username = strings.TrimSpace(identity.Username)
+
+	if identity.Username == "" {
+		return identity, ErrNoUsername
+	}
+
+	if identity.Subject == "" {
+		return identity, ErrInvalidToken
+	}
+
+	granted := map[string]bool{}
+
+	switch groups := claims[c.config.GroupsClaim].(type) {
+	case string:
+		granted[c.config.RoleMap[groups]] = true
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				granted[c.config.RoleMap[name]] = true
+			}
+		}
+	}
+
+	for _, role := range constant.Roles {
+		if granted[role] {
+			identity.Role = role
+			break
+		}
+	}
+
+	if identity.Role == "" {
+		return identity, ErrNoRole
+	}
+
+	return identity, nil
+}
+
+// key returns the public key kid names. The keys of the provider are
+// fetched again when it is not known and the last fetch is more than
+// constant.OIDCKeyRefetchInterval ago; the keys fetched before are kept
+// when the fetch fails.
+func (c *Client) key(ctx context.Context, provider Provider, kid string) (interface{}, error) {
+	if jwk, ok := c.knownKey(kid); ok {
+		return jwk.PublicKey()
+	}
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	// Another sign-in may have fetched it while this one waited
+	if jwk, ok := c.knownKey(kid); ok {
+		return jwk.PublicKey()
+	}
+
+	if time.Since(c.fetchedAt) < constant.OIDCKeyRefetchInterval {
+		return nil, jwtrsa.ErrUnknownKey
+	}
+
+	c.fetchedAt = time.Now()
+
+	var set jwtrsa.JWKSet
+
+	if err := c.getJSON(ctx, provider.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jwtrsa.JWK, len(set.Keys))
+
+	for _, key := range set.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			keys[key.Kid] = key
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	jwk, ok := c.knownKey(kid)
+
+	if !ok {
+		return nil, jwtrsa.ErrUnknownKey
+	}
+
+	return jwk.PublicKey()
+}
+
+// knownKey returns the fetched key kid names. A provider with one key may
+// leave out the kid.
+func (c *Client) knownKey(kid string) (jwtrsa.JWK, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jwk, ok := c.keys[kid]
+
+	if !ok && kid == "" && len(c.keys) == 1 {
+		for _, jwk = range c.keys {
+			ok = true
+		}
+	}
+
+	return jwk, ok
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, constant.OIDCRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	res, err := c.http.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("error in pkg oidc, GET %s returned %s", url, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+var (
+	mu            sync.RWMutex
+	defaultClient *Client
+)
+
+// SetDefault sets the client of the /auth/oidc endpoints.
+func SetDefault(c *Client) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultClient = c
+}
+
+// Default returns the client set with SetDefault, or nil when OpenID
+// Connect login is not enabled.
+func Default() *Client {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return defaultClient
+}
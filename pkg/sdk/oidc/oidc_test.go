@@ -0,0 +1,140 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/sdk/jwtrsa"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentify(t *testing.T) {
+	client := New(Config{
+		Issuer:        "https://idp.test/",
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+		RoleMap:       map[string]string{"staff": constant.Karyawan, "admins": constant.Admin, "root": constant.SuperAdmin},
+		DefaultRole:   constant.Karyawan,
+	}, nil)
+
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		role     string
+		username string
+		err      error
+	}{
+		{name: "most privileged group wins", claims: jwt.MapClaims{"sub": "ana-1", "preferred_username": " ana ", "groups": []interface{}{"staff", "root", "admins"}}, role: constant.SuperAdmin, username: "ana"},
+		{name: "single group", claims: jwt.MapClaims{"sub": "ana-1", "preferred_username": "ana", "groups": "admins"}, role: constant.Admin, username: "ana"},
+		{name: "default role", claims: jwt.MapClaims{"sub": "ana-1", "preferred_username": "ana", "groups": []interface{}{"other", 1}}, role: constant.Karyawan, username: "ana"},
+		{name: "no groups claim", claims: jwt.MapClaims{"sub": "ana-1", "preferred_username": "ana"}, role: constant.Karyawan, username: "ana"},
+		{name: "no username", claims: jwt.MapClaims{"groups": "admins"}, err: ErrNoUsername},
+		{name: "username not a string", claims: jwt.MapClaims{"preferred_username": 7}, err: ErrNoUsername},
+		{name: "no subject", claims: jwt.MapClaims{"preferred_username": "ana", "groups": "admins"}, err: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		identity, err := client.Identify(tt.claims)
+
+		if tt.err != nil {
+			require.ErrorIs(t, err, tt.err, tt.name)
+			continue
+		}
+
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.role, identity.Role, tt.name)
+		require.Equal(t, tt.username, identity.Username, tt.name)
+		require.Equal(t, "https://idp.test", identity.Issuer, tt.name)
+		require.Equal(t, "ana-1", identity.Subject, tt.name)
+	}
+
+	client.config.DefaultRole = ""
+	_, err := client.Identify(jwt.MapClaims{"sub": "ana-1", "preferred_username": "ana", "groups": "other"})
+	require.ErrorIs(t, err, ErrNoRole)
+}
+
+func TestState(t *testing.T) {
+	state, err := NewState()
+	require.NoError(t, err)
+	require.NotEqual(t, state.State, state.Nonce)
+
+	parsed, ok := ParseState(state.String())
+	require.True(t, ok)
+	require.Equal(t, state, parsed)
+
+	for _, value := range []string{"", "a.b", "a..c", "a.b.c.d"} {
+		_, ok = ParseState(value)
+		require.False(t, ok, value)
+	}
+}
+
+func TestKeyRefetch(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwk, err := jwtrsa.ToJWK(private.Public())
+	require.NoError(t, err)
+	jwk.Kid = "current"
+
+	var (
+		fetches atomic.Int32
+		down    atomic.Bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(jwtrsa.JWKSet{Keys: []jwtrsa.JWK{jwk}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := New(Config{Issuer: server.URL}, server.Client())
+	provider := Provider{JWKSURI: server.URL}
+	ctx := context.Background()
+
+	_, err = client.key(ctx, provider, "current")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, fetches.Load())
+
+	// Unknown keys do not fetch the keys again until the interval passed
+	for i := 0; i < 3; i++ {
+		_, err = client.key(ctx, provider, "unknown")
+		require.ErrorIs(t, err, jwtrsa.ErrUnknownKey)
+	}
+
+	require.EqualValues(t, 1, fetches.Load())
+
+	// A failed fetch keeps the keys fetched before
+	client.fetchedAt = client.fetchedAt.Add(-constant.OIDCKeyRefetchInterval)
+	down.Store(true)
+
+	_, err = client.key(ctx, provider, "unknown")
+	require.Error(t, err)
+	require.EqualValues(t, 2, fetches.Load())
+
+	_, err = client.key(ctx, provider, "current")
+	require.NoError(t, err)
+
+	_, err = client.key(ctx, provider, "")
+	require.NoError(t, err)
+
+	_, err = client.key(ctx, provider, "unknown")
+	require.ErrorIs(t, err, jwtrsa.ErrUnknownKey)
+	require.EqualValues(t, 2, fetches.Load())
+	require.WithinDuration(t, time.Now(), client.fetchedAt, time.Second)
+}
@@ -0,0 +1,122 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Codes are RFC 6238 time-based one-time passwords with the defaults every
+// authenticator app supports: HMAC-SHA1, 6 digits and a 30 second period.
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random secret, base32 encoded as authenticator
+// apps expect it.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.New("error in pkg totp, when rand.Read at GenerateSecret")
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// Step is the counter of the period t falls in.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code of secret for the period t falls in.
+func Code(secret string, t time.Time) (string, error) {
+	return code(secret, Step(t))
+}
+
+// Validate checks value against the periods up to skew steps around t, to
+// allow for clock drift. Steps up to and including after were already used
+// and are refused, so a code can not be replayed. It returns the matching
+// step.
+func Validate(secret, value string, t time.Time, skew int, after int64) (int64, bool) {
+	value = strings.TrimSpace(value)
+
+	if len(value) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+
+	for i := -int64(skew); i <= int64(skew); i++ {
+		step := current + i
+
+		if step <= after {
+			continue
+		}
+
+		expected, err := code(secret, step)
+
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(value)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth:// URI of secret, usually shown as a QR code for
+// authenticator apps to scan.
+func URI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// code is the HOTP value (RFC 4226) of secret for counter.
+func code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+
+	if err != nil {
+		return "", errors.New("error in pkg totp, when DecodeString at code")
+	}
+
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%modulo), nil
+}
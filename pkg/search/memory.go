@@ -0,0 +1,274 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	prefixQuality = 0.7
+	typoQuality   = 0.5
+	phraseBoost   = 2
+)
+
+type position struct {
+	field  string
+	offset int
+}
+
+type memoryIndex struct {
+	mu       sync.RWMutex
+	weights  map[string]float64
+	postings map[string]map[string][]position
+	docs     map[string]map[string][]string
+}
+
+// Index implements Index. A document with the same ID is replaced.
+func (m *memoryIndex) Index(doc Document) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.ID)
+
+	fields := make(map[string][]string, len(doc.Fields))
+
+	for field, text := range doc.Fields {
+		tokens := Tokenize(text)
+		fields[field] = tokens
+
+		for offset, token := range tokens {
+			if m.postings[token] == nil {
+				m.postings[token] = make(map[string][]position)
+			}
+
+			m.postings[token][doc.ID] = append(m.postings[token][doc.ID], position{field: field, offset: offset})
+		}
+	}
+
+	m.docs[doc.ID] = fields
+}
+
+// Delete implements Index.
+func (m *memoryIndex) Delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(id)
+}
+
+// Search implements Index. Hits are ordered by score, then by ID, and a
+// limit of zero or less returns every hit from offset on.
+func (m *memoryIndex) Search(query string, limit, offset int) (hits []Hit, total int) {
+	parsed := ParseQuery(query)
+
+	if len(parsed.Terms) == 0 && len(parsed.Phrases) == 0 {
+		return
+	}
+
+	m.mu.RLock()
+
+	var scores map[string]float64
+
+	for _, term := range parsed.Terms {
+		scores = intersect(scores, m.matchTerm(term))
+	}
+
+	for _, phrase := range parsed.Phrases {
+		scores = intersect(scores, m.matchPhrase(phrase))
+	}
+
+	m.mu.RUnlock()
+
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+
+		return hits[i].ID < hits[j].ID
+	})
+
+	total = len(hits)
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset >= total {
+		return nil, total
+	}
+
+	hits = hits[offset:]
+
+	if limit > 0 && limit < len(hits) {
+		hits = hits[:limit]
+	}
+
+	return hits, total
+}
+
+func (m *memoryIndex) remove(id string) {
+	for _, tokens := range m.docs[id] {
+		for _, token := range tokens {
+			delete(m.postings[token], id)
+
+			if len(m.postings[token]) == 0 {
+				delete(m.postings, token)
+			}
+		}
+	}
+
+	delete(m.docs, id)
+}
+
+// matchTerm scores the documents containing term, a word it prefixes or,
+// for longer terms, a word with a typo. Each document keeps the score of its
+// best matching word.
+func (m *memoryIndex) matchTerm(term string) map[string]float64 {
+	scores := make(map[string]float64)
+	runes := []rune(term)
+	typos := maxTypos(len(runes))
+
+	for word, docs := range m.postings {
+		var quality float64
+
+		switch {
+		case word == term:
+			quality = 1
+		case len(runes) > 1 && strings.HasPrefix(word, term):
+			quality = prefixQuality
+		case typos > 0 && abs(len([]rune(word))-len(runes)) <= typos && distance([]rune(word), runes) <= typos:
+			quality = typoQuality
+		default:
+			continue
+		}
+
+		idf := m.idf(len(docs))
+
+		for id, positions := range docs {
+			score := quality * idf * m.weight(positions)
+
+			if score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+
+	return scores
+}
+
+// matchPhrase scores the documents containing every word of phrase in order
+// and next to each other in the same field.
+func (m *memoryIndex) matchPhrase(phrase []string) map[string]float64 {
+	scores := make(map[string]float64)
+
+	var idf float64
+
+	for _, word := range phrase {
+		idf += m.idf(len(m.postings[word]))
+	}
+
+	for id, starts := range m.postings[phrase[0]] {
+		var matches []position
+
+		for _, start := range starts {
+			if m.follows(id, start, phrase[1:]) {
+				matches = append(matches, start)
+			}
+		}
+
+		if len(matches) > 0 {
+			scores[id] = phraseBoost * idf * m.weight(matches)
+		}
+	}
+
+	return scores
+}
+
+func (m *memoryIndex) follows(id string, start position, rest []string) bool {
+	for i, word := range rest {
+		found := false
+
+		for _, p := range m.postings[word][id] {
+			if p.field == start.field && p.offset == start.offset+i+1 {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// weight sums the field weights of positions, dampening repeats within a
+// field.
+func (m *memoryIndex) weight(positions []position) float64 {
+	counts := make(map[string]int)
+
+	for _, p := range positions {
+		counts[p.field]++
+	}
+
+	var total float64
+
+	for field, count := range counts {
+		weight, ok := m.weights[field]
+
+		if !ok {
+			weight = 1
+		}
+
+		total += weight * (1 + math.Log(float64(count)))
+	}
+
+	return total
+}
+
+func (m *memoryIndex) idf(docs int) float64 {
+	return math.Log(1 + float64(len(m.docs))/float64(docs+1))
+}
+
+// intersect keeps the documents found in both a and b and adds up their
+// scores. A nil a stands for every document.
+func intersect(a, b map[string]float64) map[string]float64 {
+	if a == nil {
+		return b
+	}
+
+	result := make(map[string]float64)
+
+	for id, score := range a {
+		if other, ok := b[id]; ok {
+			result[id] = score + other
+		}
+	}
+
+	return result
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// NewMemoryIndex returns an in-process inverted index. weights boosts the
+// score of matches in a field; fields without a weight count as 1.
+func NewMemoryIndex(weights map[string]float64) Index {
+	return &memoryIndex{
+		weights:  weights,
+		postings: make(map[string]map[string][]position),
+		docs:     make(map[string]map[string][]string),
+	}
+}
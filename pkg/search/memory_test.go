@@ -0,0 +1,107 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex() Index {
+	index := NewMemoryIndex(map[string]float64{"title": 3, "author": 2})
+
+	index.Index(Document{ID: "1", Fields: map[string]string{"title": "Harry Potter and the Philosopher's Stone", "author": "J.K. Rowling", "genre": "Fantasy"}})
+	index.Index(Document{ID: "2", Fields: map[string]string{"title": "The Stone Diaries", "author": "Carol Shields", "genre": "Drama"}})
+	index.Index(Document{ID: "3", Fields: map[string]string{"title": "Potter's Field", "author": "Ellis Peters", "genre": "Mystery"}})
+	index.Index(Document{ID: "4", Fields: map[string]string{"title": "Programming Pearls", "author": "Jon Bentley", "genre": "Computer Science"}})
+
+	return index
+}
+
+func ids(hits []Hit) (result []string) {
+	for _, hit := range hits {
+		result = append(result, hit.ID)
+	}
+
+	return
+}
+
+func TestSearch(t *testing.T) {
+	index := newTestIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		ids   []string
+	}{
+		{name: "word", query: "stone", ids: []string{"1", "2"}},
+		{name: "every word must match", query: "potter stone", ids: []string{"1"}},
+		{name: "case and punctuation", query: "ROWLING!", ids: []string{"1"}},
+		{name: "prefix", query: "progr", ids: []string{"4"}},
+		{name: "typo", query: "rowlnig", ids: []string{"1"}},
+		{name: "two typos in a long word", query: "programing perls", ids: []string{"4"}},
+		{name: "short words need exact spelling", query: "jom", ids: nil},
+		{name: "phrase", query: `"the stone"`, ids: []string{"2"}},
+		{name: "phrase across fields does not match", query: `"stone carol"`, ids: nil},
+		{name: "phrase and word", query: `"harry potter" fantasy`, ids: []string{"1"}},
+		{name: "empty", query: ` "" `, ids: nil},
+	}
+
+	for _, tt := range tests {
+		hits, total := index.Search(tt.query, 0, 0)
+
+		require.Equal(t, tt.ids, ids(hits), tt.name)
+		require.Equal(t, len(tt.ids), total, tt.name)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	index := NewMemoryIndex(map[string]float64{"title": 3, "author": 2})
+
+	index.Index(Document{ID: "a", Fields: map[string]string{"title": "Night Train", "genre": "Mystery"}})
+	index.Index(Document{ID: "b", Fields: map[string]string{"title": "Mystery of the Moon"}})
+	index.Index(Document{ID: "c", Fields: map[string]string{"title": "Mystery Mood"}})
+
+	hits, _ := index.Search("mystery", 0, 0)
+	require.Equal(t, []string{"b", "c", "a"}, ids(hits))
+	require.Greater(t, hits[1].Score, hits[2].Score)
+
+	hits, _ = index.Search("mystery moon", 0, 0)
+	require.Equal(t, []string{"b", "c"}, ids(hits))
+	require.Greater(t, hits[0].Score, hits[1].Score)
+}
+
+func TestSearchPagination(t *testing.T) {
+	index := newTestIndex()
+
+	hits, total := index.Search("the", 1, 1)
+	require.Equal(t, 2, total)
+	require.Len(t, hits, 1)
+
+	hits, total = index.Search("the", 10, 5)
+	require.Equal(t, 2, total)
+	require.Empty(t, hits)
+}
+
+func TestIndexUpdateAndDelete(t *testing.T) {
+	index := newTestIndex()
+
+	index.Index(Document{ID: "2", Fields: map[string]string{"title": "Larry's Party", "author": "Carol Shields"}})
+
+	hits, _ := index.Search("diaries", 0, 0)
+	require.Empty(t, hits)
+
+	hits, _ = index.Search("party", 0, 0)
+	require.Equal(t, []string{"2"}, ids(hits))
+
+	index.Delete("2")
+
+	hits, _ = index.Search("shields", 0, 0)
+	require.Empty(t, hits)
+}
+
+func TestParseQuery(t *testing.T) {
+	query := ParseQuery(`harry "philosopher's stone" "rowling`)
+
+	require.Equal(t, []string{"harry", "rowling"}, query.Terms)
+	require.Equal(t, [][]string{{"philosopher", "s", "stone"}}, query.Phrases)
+}
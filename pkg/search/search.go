@@ -0,0 +1,115 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Document is a record to index. Fields are named pieces of text, e.g.
+// "title" or "author", whose weight is set when the index is created.
+type Document struct {
+	ID     string
+	Fields map[string]string
+}
+
+// Hit is a document matching a query. A higher score is more relevant.
+type Hit struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Index is a full-text index over documents. Queries are made of words and
+// "quoted phrases"; every word and phrase must match. Words also match
+// indexed words they are a prefix of and, when long enough, words within a
+// small edit distance.
+type Index interface {
+	Index(doc Document)
+	Delete(id string)
+	Search(query string, limit, offset int) (hits []Hit, total int)
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Terms   []string
+	Phrases [][]string
+}
+
+// ParseQuery splits query into words and quoted phrases. An unterminated
+// quote runs to the end of the query.
+func ParseQuery(query string) Query {
+	var result Query
+
+	for i, part := range strings.Split(query, `"`) {
+		tokens := Tokenize(part)
+
+		if i%2 == 1 && len(tokens) > 1 {
+			result.Phrases = append(result.Phrases, tokens)
+			continue
+		}
+
+		result.Terms = append(result.Terms, tokens...)
+	}
+
+	return result
+}
+
+// Tokenize lower-cases text and splits it into words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// distance is the Damerau-Levenshtein (optimal string alignment) distance
+// between a and b, counting adjacent transpositions as one edit.
+func distance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			rows[i][j] = minInt(rows[i-1][j]+1, minInt(rows[i][j-1]+1, rows[i-1][j-1]+cost))
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = minInt(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(a)][len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxTypos is how many edits a query word of length n may be away from an
+// indexed word. Short words must be spelled right.
+func maxTypos(n int) int {
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,72 @@
+package sequence
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Source is a table column holding IDs generated from Template.
+type Source struct {
+	Table    string
+	Column   string
+	Template string
+}
+
+// Report is the outcome of Migrate. Invalid lists the IDs, per table, that
+// do not match their template; Counters is the highest value found for each
+// prefix.
+type Report struct {
+	Checked  int                 `json:"checked"`
+	Invalid  map[string][]string `json:"invalid"`
+	Counters map[string]int64    `json:"counters"`
+}
+
+// Migrate checks every existing ID in sources against its template and,
+// unless dryRun is set, seeds the counters so that new IDs continue after
+// the highest one in use. IDs that do not match are reported and left as is.
+func Migrate(ctx context.Context, db *gorm.DB, seq Sequence, dryRun bool, sources ...Source) (report Report, err error) {
+	report.Invalid = make(map[string][]string)
+	report.Counters = make(map[string]int64)
+
+	for _, source := range sources {
+		if strings.Count(source.Template, Placeholder) != 1 {
+			err = ErrInvalidTemplate
+			return
+		}
+
+		var ids []string
+
+		if err = db.WithContext(ctx).Table(source.Table).Pluck(source.Column, &ids).Error; err != nil {
+			return
+		}
+
+		for _, id := range ids {
+			report.Checked++
+
+			prefix, value, ok := Parse(source.Template, id)
+
+			if !ok {
+				report.Invalid[source.Table] = append(report.Invalid[source.Table], id)
+				continue
+			}
+
+			if value > report.Counters[prefix] {
+				report.Counters[prefix] = value
+			}
+		}
+	}
+
+	if dryRun {
+		return
+	}
+
+	for prefix, value := range report.Counters {
+		if err = seq.Seed(ctx, prefix, value); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,190 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/databases"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Placeholder marks where the counter value goes in an ID template, e.g.
+// "FAKHRIL-{GENRE}-{SEQ}". Every other {NAME} is filled from the fields
+// given to NextID.
+const Placeholder = "{SEQ}"
+
+var (
+	ErrInvalidTemplate = errors.New("id template must contain {SEQ} exactly once")
+	fieldPattern       = regexp.MustCompile(`\{[A-Z_]+\}`)
+)
+
+// Counter is the last value handed out for one ID prefix. The prefix is the
+// rendered template with the {SEQ} placeholder left in place.
+type Counter struct {
+	Prefix string `json:"prefix" gorm:"primaryKey;size:191"`
+	Value  int64  `json:"value"`
+}
+
+func (Counter) TableName() string {
+	return "sequence"
+}
+
+// Sequence hands out IDs from per-prefix counters stored in the database.
+type Sequence interface {
+	NextID(ctx context.Context, template string, fields map[string]string) (string, error)
+	Seed(ctx context.Context, prefix string, value int64) error
+}
+
+type sequence struct {
+	db         *gorm.DB
+	unitOfWork databases.UnitOfWork
+	width      int
+}
+
+// NextID renders template with fields and the next value of its counter.
+// The counter is incremented with a single upsert, so concurrent callers
+// never receive the same value. Inside UnitOfWork.Do the increment is part
+// of the caller's transaction.
+func (s *sequence) NextID(ctx context.Context, template string, fields map[string]string) (id string, err error) {
+	prefix, err := Render(template, fields)
+
+	if err != nil {
+		return
+	}
+
+	var value int64
+
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		db := databases.Conn(ctx, s.db)
+
+		err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "prefix"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"value": gorm.Expr("? + 1", clause.Column{Table: Counter{}.TableName(), Name: "value"})}),
+		}).Create(&Counter{Prefix: prefix, Value: 1}).Error
+
+		if err != nil {
+			return err
+		}
+
+		return db.Model(&Counter{}).Where("prefix = ?", prefix).Pluck("value", &value).Error
+	})
+
+	if err != nil {
+		return
+	}
+
+	return Format(prefix, s.width, value), nil
+}
+
+// Seed raises the counter of prefix to value. Counters are never lowered,
+// so seeding cannot make the sequence hand out an ID twice.
+func (s *sequence) Seed(ctx context.Context, prefix string, value int64) error {
+	return s.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		db := databases.Conn(ctx, s.db)
+
+		var counters []Counter
+
+		if err := databases.ForUpdate(db).Where("prefix = ?", prefix).Find(&counters).Error; err != nil {
+			return err
+		}
+
+		if len(counters) > 0 && counters[0].Value >= value {
+			return nil
+		}
+
+		return db.Save(&Counter{Prefix: prefix, Value: value}).Error
+	})
+}
+
+func New(db *gorm.DB, width int) Sequence {
+	return &sequence{db: db, unitOfWork: databases.NewUnitOfWork(db), width: width}
+}
+
+// Render fills every field of template except {SEQ}, which is kept as the
+// counter prefix.
+func Render(template string, fields map[string]string) (string, error) {
+	if strings.Count(template, Placeholder) != 1 {
+		return "", ErrInvalidTemplate
+	}
+
+	var missing []string
+
+	prefix := fieldPattern.ReplaceAllStringFunc(template, func(field string) string {
+		if field == Placeholder {
+			return field
+		}
+
+		value, ok := fields[strings.Trim(field, "{}")]
+
+		if !ok {
+			missing = append(missing, field)
+		}
+
+		return value
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("id template field %s has no value", strings.Join(missing, ", "))
+	}
+
+	return prefix, nil
+}
+
+// Format puts value into prefix, zero padded to at least width digits.
+// Values wider than width are kept whole.
+func Format(prefix string, width int, value int64) string {
+	return strings.Replace(prefix, Placeholder, fmt.Sprintf("%0*d", width, value), 1)
+}
+
+// Parse matches id against template and returns its counter prefix and
+// value. Fields may contain any character, including the separators of the
+// template.
+func Parse(template, id string) (prefix string, value int64, ok bool) {
+	if strings.Count(template, Placeholder) != 1 {
+		return
+	}
+
+	match := compile(template).FindStringSubmatchIndex(id)
+
+	if match == nil {
+		return
+	}
+
+	value, err := strconv.ParseInt(id[match[2]:match[3]], 10, 64)
+
+	if err != nil {
+		return "", 0, false
+	}
+
+	return id[:match[2]] + Placeholder + id[match[3]:], value, true
+}
+
+func compile(template string) *regexp.Regexp {
+	var pattern strings.Builder
+
+	pattern.WriteString("^")
+
+	last := 0
+
+	for _, loc := range fieldPattern.FindAllStringIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+
+		if template[loc[0]:loc[1]] == Placeholder {
+			pattern.WriteString(`([0-9]+)`)
+		} else {
+			pattern.WriteString(`.+`)
+		}
+
+		last = loc[1]
+	}
+
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+
+	return regexp.MustCompile(pattern.String())
+}
@@ -0,0 +1,155 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const bookTemplate = "FAKHRIL-{GENRE}-{SEQ}"
+
+type identifier struct {
+	ID     uint   `gorm:"primaryKey"`
+	BookID string `gorm:"unique"`
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_pragma=busy_timeout(5000)", filepath.Join(t.TempDir(), "sequence.db"))
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Counter{}, &identifier{}))
+
+	return db
+}
+
+func TestNextIDIsUniqueUnderConcurrency(t *testing.T) {
+	seq := New(openDB(t), 4)
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids = make(map[string]bool)
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			id, err := seq.NextID(context.Background(), bookTemplate, map[string]string{"GENRE": "Drama"})
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			require.False(t, ids[id], id)
+			ids[id] = true
+		}()
+	}
+
+	wg.Wait()
+
+	require.Len(t, ids, 20)
+	require.True(t, ids["FAKHRIL-Drama-0001"])
+	require.True(t, ids["FAKHRIL-Drama-0020"])
+}
+
+func TestNextIDKeepsCountersPerPrefix(t *testing.T) {
+	seq := New(openDB(t), 4)
+	ctx := context.Background()
+
+	id, err := seq.NextID(ctx, bookTemplate, map[string]string{"GENRE": "Sci-Fi"})
+	require.NoError(t, err)
+	require.Equal(t, "FAKHRIL-Sci-Fi-0001", id)
+
+	id, err = seq.NextID(ctx, bookTemplate, map[string]string{"GENRE": "Drama"})
+	require.NoError(t, err)
+	require.Equal(t, "FAKHRIL-Drama-0001", id)
+
+	_, err = seq.NextID(ctx, bookTemplate, nil)
+	require.Error(t, err)
+
+	_, err = seq.NextID(ctx, "FAKHRIL-{GENRE}", map[string]string{"GENRE": "Drama"})
+	require.ErrorIs(t, err, ErrInvalidTemplate)
+}
+
+func TestNextIDGrowsPastWidth(t *testing.T) {
+	seq := New(openDB(t), 4)
+	ctx := context.Background()
+
+	require.NoError(t, seq.Seed(ctx, "FAKHRIL-Drama-{SEQ}", 9999))
+
+	id, err := seq.NextID(ctx, bookTemplate, map[string]string{"GENRE": "Drama"})
+	require.NoError(t, err)
+	require.Equal(t, "FAKHRIL-Drama-10000", id)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		template string
+		id       string
+		prefix   string
+		value    int64
+		ok       bool
+	}{
+		{template: bookTemplate, id: "FAKHRIL-Drama-0042", prefix: "FAKHRIL-Drama-{SEQ}", value: 42, ok: true},
+		{template: bookTemplate, id: "FAKHRIL-Sci-Fi-12345", prefix: "FAKHRIL-Sci-Fi-{SEQ}", value: 12345, ok: true},
+		{template: "LOAN-{USERNAME}-{SEQ}", id: "LOAN-jean-luc-0003", prefix: "LOAN-jean-luc-{SEQ}", value: 3, ok: true},
+		{template: bookTemplate, id: "FAKHRIL-Drama-", ok: false},
+		{template: bookTemplate, id: "OTHER-Drama-0001", ok: false},
+		{template: bookTemplate, id: "FAKHRIL-Drama-00x1", ok: false},
+	}
+
+	for _, tt := range tests {
+		prefix, value, ok := Parse(tt.template, tt.id)
+
+		require.Equal(t, tt.ok, ok, tt.id)
+		require.Equal(t, tt.prefix, prefix, tt.id)
+		require.Equal(t, tt.value, value, tt.id)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := openDB(t)
+	seq := New(db, 4)
+	ctx := context.Background()
+
+	for _, id := range []string{"FAKHRIL-Drama-0002", "FAKHRIL-Drama-0007", "FAKHRIL-Sci-Fi-0003", "broken"} {
+		require.NoError(t, db.Create(&identifier{BookID: id}).Error)
+	}
+
+	source := Source{Table: "identifiers", Column: "book_id", Template: bookTemplate}
+
+	report, err := Migrate(ctx, db, seq, true, source)
+	require.NoError(t, err)
+	require.Equal(t, 4, report.Checked)
+	require.Equal(t, []string{"broken"}, report.Invalid["identifiers"])
+	require.Equal(t, map[string]int64{"FAKHRIL-Drama-{SEQ}": 7, "FAKHRIL-Sci-Fi-{SEQ}": 3}, report.Counters)
+
+	var counters int64
+	require.NoError(t, db.Model(&Counter{}).Count(&counters).Error)
+	require.Zero(t, counters)
+
+	_, err = Migrate(ctx, db, seq, false, source)
+	require.NoError(t, err)
+
+	id, err := seq.NextID(ctx, bookTemplate, map[string]string{"GENRE": "Drama"})
+	require.NoError(t, err)
+	require.Equal(t, "FAKHRIL-Drama-0008", id)
+
+	// Seeding never moves a counter back.
+	require.NoError(t, seq.Seed(ctx, "FAKHRIL-Drama-{SEQ}", 1))
+
+	id, err = seq.NextID(ctx, bookTemplate, map[string]string{"GENRE": "Drama"})
+	require.NoError(t, err)
+	require.Equal(t, "FAKHRIL-Drama-0009", id)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/health"
+	"github.com/labstack/echo/v4"
+)
+
+// Config is how the HTTP server listens and shuts down. TLS is served when
+// CertFile and KeyFile are set. TrustedProxies are the networks of the
+// reverse proxies allowed to report the client IP.
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	Drain             time.Duration
+	CertFile          string
+	KeyFile           string
+	TrustedProxies    []*net.IPNet
+}
+
+// TLS reports whether the server serves HTTPS.
+func (c Config) TLS() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// IPExtractor reads the client IP of a request. Without trusted proxies it
+// is the peer address and X-Forwarded-For is ignored; otherwise the header
+// is followed back through the trusted proxies only.
+func (c Config) IPExtractor() echo.IPExtractor {
+	if len(c.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+
+	for _, proxy := range c.TrustedProxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// Run serves handler until ctx is done, e.g. on SIGTERM. The service then
+// reports itself not ready and keeps serving for the drain period, so load
+// balancers stop sending it requests, before requests in flight are given
+// the shutdown timeout to finish.
+func Run(ctx context.Context, config Config, handler http.Handler) error {
+	listener, err := net.Listen("tcp", config.Addr)
+
+	if err != nil {
+		return err
+	}
+
+	return serve(ctx, listener, config, handler)
+}
+
+func serve(ctx context.Context, listener net.Listener, config Config, handler http.Handler) error {
+	server := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		if config.TLS() {
+			errs <- server.ServeTLS(listener, config.CertFile, config.KeyFile)
+			return
+		}
+
+		errs <- server.Serve(listener)
+	}()
+
+	health.SetReady(true)
+	defer health.SetReady(false)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	health.SetReady(false)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(config.Drain):
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/health"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDrainsRequestsInFlight(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "returned")
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- serve(ctx, listener, Config{Drain: 50 * time.Millisecond, ShutdownTimeout: 5 * time.Second}, handler)
+	}()
+
+	responses := make(chan string, 1)
+
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	require.True(t, health.Ready())
+
+	// The request in flight finishes after the shutdown started
+	stop()
+	require.Eventually(t, func() bool { return !health.Ready() }, time.Second, 10*time.Millisecond)
+	close(release)
+
+	require.Equal(t, "returned", <-responses)
+	require.NoError(t, <-done)
+
+	_, err = http.Get("http://" + listener.Addr().String())
+	require.Error(t, err)
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- serve(ctx, listener, Config{ShutdownTimeout: 50 * time.Millisecond}, handler)
+	}()
+
+	go func() {
+		_, _ = http.Get("http://" + listener.Addr().String())
+	}()
+
+	<-started
+	stop()
+
+	require.ErrorIs(t, <-done, context.DeadlineExceeded)
+}
+
+func TestIPExtractor(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		proxies  []*net.IPNet
+		peer     string
+		expected string
+	}{
+		{name: "no proxies ignores the header", peer: "192.0.2.1:1234", expected: "192.0.2.1"},
+		{name: "untrusted peer ignores the header", proxies: []*net.IPNet{proxies}, peer: "192.0.2.1:1234", expected: "192.0.2.1"},
+		{name: "trusted proxy", proxies: []*net.IPNet{proxies}, peer: "10.0.0.1:1234", expected: "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.peer
+		req.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+		require.Equal(t, tt.expected, Config{TrustedProxies: tt.proxies}.IPExtractor()(req), tt.name)
+	}
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"errors"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/constant"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// dbSystems are the database systems of the drivers of pkg databases.
+var dbSystems = map[string]attribute.KeyValue{
+	constant.MySQLDriver:    semconv.DBSystemMySQL,
+	constant.PostgresDriver: semconv.DBSystemPostgreSQL,
+	constant.SQLiteDriver:   semconv.DBSystemSqlite,
+}
+
+// GormPlugin traces every query of a gorm.DB as a child of the span of the
+// context of the query. The SQL is recorded without its parameters.
+type GormPlugin struct{}
+
+// Name implements gorm.Plugin.
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin.
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", startQuery("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", endQuery),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", startQuery("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", endQuery),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", startQuery("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", endQuery),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuery("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", endQuery),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", startQuery("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", endQuery),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", startQuery("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", endQuery),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startQuery(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+
+		attributes := []attribute.KeyValue{semconv.DBOperation(operation)}
+
+		if system, ok := dbSystems[db.Dialector.Name()]; ok {
+			attributes = append(attributes, system)
+		}
+
+		_, span := otel.Tracer(TracerName).Start(db.Statement.Context, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+
+	if !ok {
+		return
+	}
+
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBSQLTable(db.Statement.Table),
+		semconv.DBStatement(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	// Looking up a record that is not there is no failure of the database
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// RegisterDB traces the queries of db.
+func RegisterDB(db *gorm.DB) error {
+	if err := db.Use(GormPlugin{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters spans can be sent with.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// TracerName is the instrumentation scope of the spans of this service.
+const TracerName = "github.com/Zeroaril7/perpustakaan-go"
+
+var ErrUnknownExporter = errors.New("error in pkg tracing, the trace exporter is unknown")
+
+// Config is where spans go. The OTLP exporter takes its endpoint, headers
+// and TLS settings from the standard OTEL_EXPORTER_OTLP_* variables.
+// SampleRatio is the share of the traces started here that are kept;
+// traces started by a caller follow the decision of the caller.
+type Config struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Setup sends spans as config says and propagates trace context in W3C
+// traceparent and tracestate headers. Shutdown sends the spans that were
+// not sent yet; spans written to stdout go to out.
+func Setup(ctx context.Context, config Config, out io.Writer) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+
+	switch strings.ToLower(config.Exporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, config.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error in pkg tracing, %w", err)
+	}
+
+	service, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName)))
+
+	if err != nil {
+		return nil, fmt.Errorf("error in pkg tracing, %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(service),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of this service named name, as a child of the span
+// of ctx.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends span, recording err when it is not nil. Errors sent to the
+// client as server errors, or that would be, mark the span as failed;
+// refusals such as not found are only recorded.
+func End(span trace.Span, err interface{}) {
+	defer span.End()
+
+	if err == nil {
+		return
+	}
+
+	e, ok := err.(error)
+
+	if !ok {
+		e = fmt.Errorf("%v", err)
+	}
+
+	span.RecordError(e)
+
+	if status, ok := httperror.FromError(e).(interface{ Code() int }); ok && status.Code() < http.StatusInternalServerError {
+		return
+	}
+
+	span.SetStatus(codes.Error, e.Error())
+}
+
+// Result ends span once results delivers, passing the result on. Usecases
+// do their work in a goroutine of their own and return at once, so a span
+// ended when the call returns would miss the work; ended here it covers it.
+func Result(span trace.Span, results <-chan utils.Result) <-chan utils.Result {
+	output := make(chan utils.Result)
+
+	go func() {
+		defer close(output)
+
+		result, ok := <-results
+
+		if !ok {
+			End(span, nil)
+			return
+		}
+
+		End(span, result.Error)
+
+		output <- result
+	}()
+
+	return output
+}
@@ -0,0 +1,162 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/middlewares"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/tracing"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/utils"
+	"github.com/glebarez/sqlite"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// record makes the spans of the test go to the returned recorder.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+// byName returns the ended spans of recorder by name.
+func byName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := map[string]sdktrace.ReadOnlySpan{}
+
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	return spans
+}
+
+func TestRequestSpans(t *testing.T) {
+	recorder := record(t)
+
+	dsn := fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "tracing.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	type Shelf struct {
+		ID   int64
+		Name string
+	}
+
+	require.NoError(t, db.AutoMigrate(&Shelf{}))
+	require.NoError(t, tracing.RegisterDB(db))
+
+	// Registering again is not an error
+	require.NoError(t, tracing.RegisterDB(db))
+
+	e := echo.New()
+	e.HTTPErrorHandler = utils.HTTPErrorHandler
+	e.Use(middlewares.RequestID(), middlewares.Tracing())
+	e.GET("/shelf/:id", func(c echo.Context) error {
+		ctx, span := tracing.Start(c.Request().Context(), "ShelfUsecase.Get")
+		result := <-tracing.Result(span, func() <-chan utils.Result {
+			output := make(chan utils.Result, 1)
+			defer close(output)
+
+			var shelf Shelf
+			err := db.WithContext(ctx).First(&shelf, c.Param("id")).Error
+
+			if err != nil {
+				output <- utils.Result{Error: httperror.FromError(err)}
+				return output
+			}
+
+			output <- utils.Result{Data: shelf}
+			return output
+		}())
+
+		if result.Error != nil {
+			return utils.ResponseError(result.Error, c)
+		}
+
+		return utils.Response(result.Data, "Shelf", http.StatusOK, c)
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return errors.New("shelf collapsed")
+	})
+
+	// The trace of the caller is continued
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/shelf/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	spans := byName(recorder)
+	require.Len(t, spans, 3)
+
+	server, usecase, query := spans["GET /shelf/:id"], spans["ShelfUsecase.Get"], spans["gorm.query"]
+	require.NotNil(t, server)
+	require.NotNil(t, usecase)
+	require.NotNil(t, query)
+
+	require.Equal(t, traceID, server.SpanContext().TraceID().String())
+	require.Equal(t, server.SpanContext().SpanID(), usecase.Parent().SpanID())
+	require.Equal(t, usecase.SpanContext().SpanID(), query.Parent().SpanID())
+
+	// Not found is recorded, but fails neither the request nor the query
+	require.Equal(t, codes.Unset, server.Status().Code)
+	require.Equal(t, codes.Unset, usecase.Status().Code)
+	require.Len(t, usecase.Events(), 1)
+	require.Equal(t, codes.Unset, query.Status().Code)
+	require.Contains(t, query.Attributes(), semconv.DBSystemSqlite)
+
+	// Server errors fail the span
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/broken", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, codes.Error, byName(recorder)["GET /broken"].Status().Code)
+
+	// Failed queries fail their span
+	require.Error(t, db.WithContext(context.Background()).Table("missing").Create(map[string]interface{}{"name": "x"}).Error)
+	require.Equal(t, codes.Error, byName(recorder)["gorm.create"].Status().Code)
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: "zipkin"}, nil)
+	require.ErrorIs(t, err, tracing.ErrUnknownExporter)
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone}, nil)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	var out bytes.Buffer
+
+	shutdown, err = tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterStdout, ServiceName: "library", SampleRatio: 1}, &out)
+	require.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "BookUsecase.Get")
+	tracing.End(span, nil)
+
+	require.NoError(t, shutdown(context.Background()))
+	require.Contains(t, out.String(), `"Name":"BookUsecase.Get"`)
+	require.Contains(t, out.String(), `"Value":"library"`)
+}
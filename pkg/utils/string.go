@@ -1,13 +1,18 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"log"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -34,7 +39,7 @@ func ConvertString(v interface{}) string {
 		if err == nil {
 			result = strings.Trim(string(resultJSON), "\"")
 		} else {
-			log.Println("Error on lib/converter ConvertString() ", err)
+			logger.Default().Warn("Could not convert to a string", zap.Error(err))
 		}
 	}
 
@@ -87,30 +92,53 @@ func GetLocalTime() time.Time {
 	return time.Now().Local()
 }
 
-func GetFourDigitsNumber(number string) string {
-	var numberStr string
-
-	switch len(number) {
-	case 1:
-		numberStr = fmt.Sprintf("000%s", number)
-	case 2:
-		numberStr = fmt.Sprintf("00%s", number)
-	case 3:
-		numberStr = fmt.Sprintf("0%s", number)
-	case 4:
-		numberStr = number
-	}
-	return numberStr
+var passwordCost atomic.Int32
+
+func init() {
+	passwordCost.Store(int32(bcrypt.DefaultCost))
+}
+
+// SetPasswordCost sets the bcrypt cost of new password hashes.
+func SetPasswordCost(cost int) {
+	passwordCost.Store(int32(cost))
 }
 
 func HashPassword(password string) string {
-	bytes, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
+	bytes, _ := bcrypt.GenerateFromPassword([]byte(password), int(passwordCost.Load()))
 
 	return string(bytes)
 }
 
+// PasswordNeedsRehash reports whether hash was made with another bcrypt cost
+// than new hashes are.
+func PasswordNeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err == nil && cost != int(passwordCost.Load())
+}
+
 func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 
 	return err == nil
 }
+
+// GenerateToken returns a random, URL safe token of size bytes.
+func GenerateToken(size int) (string, error) {
+	bytes := make([]byte, size)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// HashToken returns the SHA-256 hex digest of token. Tokens are random and
+// long enough that a fast hash is enough to keep them from being usable if
+// the database leaks.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -15,3 +15,17 @@ func Test_CheckPasswordHash(t *testing.T) {
 	assert.Equal(t, CheckPasswordHash("test", hash), true)
 	assert.Equal(t, CheckPasswordHash("test2", hash), false)
 }
+
+func Test_GenerateToken(t *testing.T) {
+	first, err := GenerateToken(32)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(first), 43)
+
+	second, _ := GenerateToken(32)
+	assert.NotEqual(t, first, second)
+}
+
+func Test_HashToken(t *testing.T) {
+	assert.Equal(t, HashToken("test"), "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	assert.NotEqual(t, HashToken("test"), HashToken("test2"))
+}
@@ -3,12 +3,15 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/Zeroaril7/perpustakaan-go/pkg/logger"
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 )
 
 type Result struct {
@@ -31,20 +34,30 @@ type PaginationResponse struct {
 }
 
 type BaseWrapperModel struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data"`
-	Message string      `json:"message"`
-	Code    int         `json:"code"`
-	Meta    interface{} `json:"meta,omitempty"`
+	Success   bool               `json:"success"`
+	Data      interface{}        `json:"data"`
+	Message   string             `json:"message"`
+	Code      int                `json:"code"`
+	ErrorCode string             `json:"error_code,omitempty"`
+	Details   []httperror.Detail `json:"details,omitempty"`
+	Meta      interface{}        `json:"meta,omitempty"`
 }
 
-type Meta struct {
-	Method        string    `json:"method"`
-	Url           string    `json:"url"`
-	Code          string    `json:"code"`
-	ContentLength int64     `json:"content_length"`
-	Date          time.Time `json:"date"`
-	Ip            string    `json:"ip"`
+// MIMEProblemJSON is the media type of RFC 7807 problem details. Clients
+// that accept it get errors as a Problem instead of a BaseWrapperModel.
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an error in the RFC 7807 format. Code and Details are
+// extension members with the same meaning as in BaseWrapperModel.
+type Problem struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	Code     string             `json:"code"`
+	Details  []httperror.Detail `json:"details,omitempty"`
+	Reasons  []httperror.Reason `json:"reasons,omitempty"`
 }
 
 func (q *PaginationRequest) GetOffset() int64 {
@@ -84,16 +97,6 @@ func (q *PaginationRequest) GetPaginationRequest() PaginationRequest {
 
 func Response(data interface{}, message string, code int, c echo.Context) error {
 	success := false
-	meta := Meta{
-		Date:          time.Now(),
-		Url:           c.Path(),
-		Method:        c.Request().Method,
-		Code:          fmt.Sprintf("%v", http.StatusOK),
-		ContentLength: c.Request().ContentLength,
-		Ip:            c.RealIP(),
-	}
-	byteMeta, _ := json.Marshal(meta)
-	LogDefault(string(byteMeta))
 
 	if code < http.StatusBadRequest {
 		success = true
@@ -111,16 +114,6 @@ func Response(data interface{}, message string, code int, c echo.Context) error
 
 func ResponseWithPagination(data interface{}, message string, code int, total int64, pagination PaginationRequest, c echo.Context) error {
 	success := false
-	meta := Meta{
-		Date:          time.Now(),
-		Url:           c.Path(),
-		Method:        c.Request().Method,
-		Code:          fmt.Sprintf("%v", http.StatusOK),
-		ContentLength: c.Request().ContentLength,
-		Ip:            c.RealIP(),
-	}
-	byteMeta, _ := json.Marshal(meta)
-	LogDefault(string(byteMeta))
 
 	if code < http.StatusBadRequest {
 		success = true
@@ -148,72 +141,138 @@ func ResponseError(err interface{}, c echo.Context) error {
 
 	errObj := getErrStatusCode(err)
 
-	meta := Meta{
-		Date:          time.Now(),
-		Url:           c.Path(),
-		Method:        c.Request().Method,
-		Code:          fmt.Sprintf("%v", errObj),
-		Ip:            c.RealIP(),
-		ContentLength: c.Request().ContentLength,
+	// The audit log has the status, the cause of server errors is only here
+	if errObj.ResponseCode >= http.StatusInternalServerError {
+		logger.FromContext(c.Request().Context()).Error("Request failed", zap.String("error", errObj.Message))
 	}
 
-	result := BaseWrapperModel{
-		Success: false,
-		Message: errObj.Message,
-		Code:    errObj.Code,
+	if errObj.RetryAfter > 0 {
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(errObj.RetryAfter.Seconds())), 10))
+	}
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), MIMEProblemJSON) {
+		problem := Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(errObj.ResponseCode),
+			Status:   errObj.ResponseCode,
+			Detail:   errObj.Message,
+			Instance: c.Request().URL.Path,
+			Code:     string(errObj.ErrorCode),
+			Details:  errObj.Details,
+			Reasons:  errObj.Reasons,
+		}
+
+		body, err := json.Marshal(problem)
+
+		if err != nil {
+			return err
+		}
+
+		return c.Blob(errObj.ResponseCode, MIMEProblemJSON, body)
 	}
 
-	byteMeta, _ := json.Marshal(meta)
+	result := BaseWrapperModel{
+		Success:   false,
+		Message:   errObj.Message,
+		Code:      errObj.Code,
+		ErrorCode: string(errObj.ErrorCode),
+		Details:   errObj.Details,
+	}
 
-	LogError(string(byteMeta))
+	if len(errObj.Reasons) > 0 {
+		result.Data = errObj.Reasons
+	}
 
 	return c.JSON(errObj.ResponseCode, result)
 }
 
-func LogDefault(meta string) {
-	log.Default().Println("service-info", "Logging service...", "audit-log", meta)
-}
+// HTTPErrorHandler answers errors returned by handlers and middlewares,
+// e.g. of the JWT middleware or for unknown routes, like ResponseError.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
 
-func LogError(meta string) {
-	log.Default().Println("service-error", "Logging service...", "audit-log", meta)
+	if err = ResponseError(err, c); err != nil {
+		logger.FromContext(c.Request().Context()).Error("Could not answer the error", zap.Error(err))
+	}
 }
 
 func getErrStatusCode(err interface{}) httperror.CommonErrorData {
 	errData := httperror.CommonErrorData{}
 
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		errData.ResponseCode = httpErr.Code
+		errData.Code = httpErr.Code
+		errData.Message = fmt.Sprint(httpErr.Message)
+		errData.ErrorCode = httperror.CodeOf(errData.Message, httpErr.Code)
+		return errData
+	}
+
+	// Errors of the database are mapped to their status, other errors are
+	// internal
+	if e, ok := err.(error); ok {
+		err = httperror.FromError(e)
+	}
+
 	switch obj := err.(type) {
 	case httperror.BadRequestData:
 		errData.ResponseCode = http.StatusBadRequest
 		errData.Code = obj.Code()
 		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
+		errData.Details = obj.Details()
 		return errData
 	case httperror.UnauthorizedData:
 		errData.ResponseCode = http.StatusUnauthorized
 		errData.Code = obj.Code()
 		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
 		return errData
 	case httperror.ForbiddenErrorData:
 		errData.ResponseCode = http.StatusForbidden
 		errData.Code = obj.Code()
 		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
 		return errData
 	case httperror.NotFoundData:
 		errData.ResponseCode = http.StatusNotFound
 		errData.Code = obj.Code()
 		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
 		return errData
 	case httperror.ConflictData:
 		errData.ResponseCode = http.StatusConflict
 		errData.Code = obj.Code()
 		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
+		errData.Reasons = obj.Reasons()
+		return errData
+	case httperror.LockedData:
+		errData.ResponseCode = http.StatusLocked
+		errData.Code = obj.Code()
+		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
+		errData.RetryAfter = obj.RetryAfter()
+		return errData
+	case httperror.TooManyRequestsData:
+		errData.ResponseCode = http.StatusTooManyRequests
+		errData.Code = obj.Code()
+		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
+		errData.RetryAfter = obj.RetryAfter()
 		return errData
 	case httperror.InternalServerErrorData:
 		errData.ResponseCode = http.StatusInternalServerError
 		errData.Code = obj.Code()
 		errData.Message = obj.Message()
+		errData.ErrorCode = obj.ErrorCode()
 		return errData
 	default:
-		errData.Code = http.StatusConflict
+		errData.ResponseCode = http.StatusInternalServerError
+		errData.Code = http.StatusInternalServerError
+		errData.Message = http.StatusText(http.StatusInternalServerError)
+		errData.ErrorCode = httperror.CodeInternal
 		return errData
 	}
 }
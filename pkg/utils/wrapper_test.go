@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func respond(t *testing.T, err interface{}, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/book", nil)
+	req.Header.Set(echo.HeaderAccept, accept)
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, ResponseError(err, echo.New().NewContext(req, rec)))
+
+	return rec
+}
+
+func TestResponseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    interface{}
+		status int
+		code   string
+	}{
+		{name: "not found", err: gorm.ErrRecordNotFound, status: http.StatusNotFound, code: "NOT_FOUND"},
+		{name: "duplicate", err: gorm.ErrDuplicatedKey, status: http.StatusConflict, code: "DUPLICATE"},
+		{name: "echo", err: echo.ErrMethodNotAllowed, status: http.StatusMethodNotAllowed, code: "BAD_REQUEST"},
+		{name: "unknown", err: "boom", status: http.StatusInternalServerError, code: "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		rec := respond(t, tt.err, echo.MIMEApplicationJSON)
+		require.Equal(t, tt.status, rec.Code, tt.name)
+
+		var body BaseWrapperModel
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), tt.name)
+		require.Equal(t, tt.status, body.Code, tt.name)
+		require.Equal(t, tt.code, body.ErrorCode, tt.name)
+	}
+}
+
+func TestResponseErrorProblem(t *testing.T) {
+	details := []httperror.Detail{{Field: "title", Tag: "required", Message: "title is required"}}
+	rec := respond(t, httperror.Validation(details), MIMEProblemJSON+", application/json")
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, MIMEProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, Problem{
+		Type:     "about:blank",
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   httperror.ValidationFailedMsg,
+		Instance: "/book",
+		Code:     "VALIDATION_FAILED",
+		Details:  details,
+	}, problem)
+}
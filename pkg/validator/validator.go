@@ -2,7 +2,10 @@ package validator
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/Zeroaril7/perpustakaan-go/pkg/httperror"
 	"github.com/go-playground/validator"
@@ -12,21 +15,102 @@ type CustomValidator struct {
 	validator *validator.Validate
 }
 
+// Validate returns a validation error detailing every field of i that
+// failed, named as in the JSON body.
 func (cv *CustomValidator) Validate(i interface{}) error {
-	var errorMsg string
-	if cv.validator.Struct(i) != nil {
-		errs := cv.validator.Struct(i).(validator.ValidationErrors)
-		for _, err := range errs {
-			errorMsg += fmt.Sprintf("\"%s\": %s \n ", strings.ToLower(err.Field()), err.Tag())
-		}
+	err := cv.validator.Struct(i)
+
+	if err == nil {
+		return nil
+	}
+
+	errs, ok := err.(validator.ValidationErrors)
 
-		return httperror.Conflict(errorMsg)
+	if !ok {
+		return httperror.BadRequest(err.Error())
 	}
 
-	return nil
+	details := make([]httperror.Detail, 0, len(errs))
+
+	for _, fieldErr := range errs {
+		details = append(details, toDetail(fieldErr))
+	}
+
+	return httperror.Validation(details)
 }
 
 func NewCustomValidator() *CustomValidator {
 	cv := &CustomValidator{validator: validator.New()}
+	cv.validator.RegisterTagNameFunc(jsonName)
+	cv.validator.RegisterValidation("oneof", isOneOf)
+	cv.validator.RegisterValidation("datetime", isDatetime)
+
 	return cv
 }
+
+// oneOfValue matches a value of a oneof tag, which is quoted when it has
+// spaces, e.g. oneof=AVAILABLE 'ON LOAN'.
+var oneOfValue = regexp.MustCompile(`'[^']*'|\S+`)
+
+// isOneOf replaces the oneof check of the validator, which cannot list
+// values with spaces.
+func isOneOf(fl validator.FieldLevel) bool {
+	value := fmt.Sprint(fl.Field().Interface())
+
+	for _, allowed := range oneOfValue.FindAllString(fl.Param(), -1) {
+		if value == strings.Trim(allowed, "'") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jsonName names a field by its json tag, falling back to the lower case
+// Go name.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+	if name == "-" || name == "" {
+		return strings.ToLower(field.Name)
+	}
+
+	return name
+}
+
+// isDatetime checks that a string is a time in the layout of the tag, e.g.
+// datetime=2006-01-02.
+func isDatetime(fl validator.FieldLevel) bool {
+	_, err := time.Parse(fl.Param(), fl.Field().String())
+	return err == nil
+}
+
+func toDetail(err validator.FieldError) httperror.Detail {
+	// The namespace starts with the name of the validated struct
+	field := err.Field()
+
+	if _, path, ok := strings.Cut(err.Namespace(), "."); ok {
+		field = path
+	}
+
+	detail := httperror.Detail{Field: field, Tag: err.Tag(), Param: err.Param()}
+
+	switch err.Tag() {
+	case "required":
+		detail.Message = fmt.Sprintf("%s is required", field)
+	case "required_without":
+		detail.Message = fmt.Sprintf("%s is required without %s", field, err.Param())
+	case "min", "gte":
+		detail.Message = fmt.Sprintf("%s must be at least %s", field, err.Param())
+	case "max", "lte":
+		detail.Message = fmt.Sprintf("%s must be at most %s", field, err.Param())
+	case "oneof":
+		detail.Message = fmt.Sprintf("%s must be one of %s", field, err.Param())
+	case "datetime":
+		detail.Message = fmt.Sprintf("%s must be a date like %s", field, err.Param())
+	default:
+		detail.Message = fmt.Sprintf("%s failed the %s check", field, err.Tag())
+	}
+
+	return detail
+}